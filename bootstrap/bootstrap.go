@@ -2,10 +2,13 @@ package bootstrap
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
 	"syscall"
 	"time"
@@ -19,6 +22,8 @@ import (
 	"github.com/wuwuseo/cmf/config"
 	"github.com/wuwuseo/cmf/filesystem"
 	"github.com/wuwuseo/cmf/log"
+	"github.com/wuwuseo/cmf/orm"
+	"github.com/wuwuseo/cmf/redis"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +47,8 @@ type Bootstrap struct {
 	initFuncs       []InitFunc
 	middlewareFuncs []MiddlewareFunc
 	services        sync.Map // 使用sync.Map保证并发安全
+	serviceMu       sync.Mutex
+	serviceOrder    []string // 服务名称按首次注册的顺序排列，决定启动与关闭顺序
 }
 
 func NewBootstrap() *Bootstrap {
@@ -56,6 +63,11 @@ func NewBootstrap() *Bootstrap {
 	configService, _ := b.GetService("config")
 	cfg := configService.(*config.Config)
 
+	// 初始化 Redis 客户端管理器，连接在首次使用时建立，应用关闭时统一释放
+	// 需要先于缓存设置为默认管理器，Redis 缓存驱动创建时即通过默认管理器获取客户端
+	redisManager := redis.NewManager(cfg)
+	redis.SetDefaultManager(redisManager)
+	b.RegisterService("redis", redisManager)
 	// 初始化缓存服务
 	b.RegisterService("cache", cache.NewCache(b.ctx, cfg))
 	// 初始化文件系统服务
@@ -88,8 +100,13 @@ func (b *Bootstrap) RegisterMiddleware(f MiddlewareFunc) {
 }
 
 // RegisterService 注册服务实例到容器中（单例模式）
+// 重复注册同名服务时替换实例，保留首次注册的顺序
 func (b *Bootstrap) RegisterService(name string, service any) {
-	b.services.Store(name, service)
+	b.serviceMu.Lock()
+	defer b.serviceMu.Unlock()
+	if _, loaded := b.services.Swap(name, service); !loaded {
+		b.serviceOrder = append(b.serviceOrder, name)
+	}
 }
 
 // GetService 从容器中获取服务实例
@@ -151,7 +168,10 @@ func (b *Bootstrap) HasService(name string) bool {
 // RemoveService 从容器中移除服务（谨慎使用）
 // 注意：单例模式下通常不建议移除服务，但在某些特殊场景可能有用
 func (b *Bootstrap) RemoveService(name string) {
+	b.serviceMu.Lock()
+	defer b.serviceMu.Unlock()
 	b.services.Delete(name)
+	b.serviceOrder = slices.DeleteFunc(b.serviceOrder, func(n string) bool { return n == name })
 }
 
 func (b *Bootstrap) Run() error {
@@ -172,6 +192,8 @@ func (b *Bootstrap) Run() error {
 	app := fiber.New(fiber.Config{
 		IdleTimeout: time.Duration(Config.App.IdleTimeout) * time.Second,
 		BodyLimit:   Config.App.BodyLimit,
+		// 将 Locals 中的请求 ID 等数据传入 c.Context()，查询日志经由 c.Context() 派生的上下文也能读取请求 ID
+		PassLocalsToContext: true,
 		ErrorHandler: func(ctx fiber.Ctx, err error) error {
 			// Log the error before handling it
 			log.Error("请求处理错误",
@@ -279,20 +301,75 @@ func (b *Bootstrap) init() {
 }
 
 // buildServiceList 构建 Fiber v3 Service 列表，注册需要生命周期管理的服务
+// Fiber 关闭服务时遍历的是 map，顺序不确定，因此所有服务合并为一个 serviceGroup，
+// 由其按注册顺序启动、按相反顺序关闭；Redis 管理器与数据库连接排在最前，最后关闭
 func (b *Bootstrap) buildServiceList(cfg *config.Config) []fiber.Service {
-	var services []fiber.Service
+	b.serviceMu.Lock()
+	defer b.serviceMu.Unlock()
+
+	group := &serviceGroup{}
+	for _, name := range b.serviceOrder {
+		if value, ok := b.services.Load(name); ok {
+			group.services = append(group.services, &cmfServiceWrapper{
+				name:    name,
+				service: value,
+			})
+		}
+	}
+	slices.SortStableFunc(group.services, func(a, b *cmfServiceWrapper) int {
+		switch ia, ib := isInfrastructure(a.service), isInfrastructure(b.service); {
+		case ia && !ib:
+			return -1
+		case !ia && ib:
+			return 1
+		}
+		return 0
+	})
 
-	// 从 sync.Map 中提取所有注册的服务，包装为 fiber.Service
-	b.services.Range(func(key, value any) bool {
-		name := key.(string)
-		services = append(services, &cmfServiceWrapper{
-			name:    name,
-			service: value,
-		})
+	return []fiber.Service{group}
+}
+
+// isInfrastructure 判断服务是否为其他服务依赖的连接管理器，这类服务最先启动、最后关闭
+func isInfrastructure(service any) bool {
+	switch service.(type) {
+	case *redis.Manager, *orm.DBManager, *orm.Connection, *sql.DB:
 		return true
-	})
+	}
+	return false
+}
 
-	return services
+// serviceGroup 按顺序启动、按相反顺序终止一组服务
+type serviceGroup struct {
+	services []*cmfServiceWrapper
+}
+
+// Start 按顺序启动服务，遇到错误时停止
+func (g *serviceGroup) Start(ctx context.Context) error {
+	for _, service := range g.services {
+		if err := service.Start(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *serviceGroup) String() string {
+	return "cmf"
+}
+
+func (g *serviceGroup) State(ctx context.Context) (string, error) {
+	return "running", nil
+}
+
+// Terminate 按启动的相反顺序终止服务，单个服务关闭失败不影响其余服务
+func (g *serviceGroup) Terminate(ctx context.Context) error {
+	var errs []error
+	for _, service := range slices.Backward(g.services) {
+		if err := service.Terminate(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // cmfServiceWrapper 将 CMF 服务包装为 fiber.Service 接口
@@ -302,8 +379,14 @@ type cmfServiceWrapper struct {
 	service any
 }
 
+// Start 启动服务，实现了 Start(ctx) error 的服务（如 Stream 消费者）会被启动
 func (w *cmfServiceWrapper) Start(ctx context.Context) error {
 	log.Info("服务启动: " + w.name)
+	if starter, ok := w.service.(interface{ Start(context.Context) error }); ok {
+		if err := starter.Start(ctx); err != nil {
+			return fmt.Errorf("服务 '%s' 启动失败: %w", w.name, err)
+		}
+	}
 	return nil
 }
 
@@ -315,8 +398,14 @@ func (w *cmfServiceWrapper) State(ctx context.Context) (string, error) {
 	return "running", nil
 }
 
+// Terminate 终止服务，实现了 io.Closer 的服务会被关闭
 func (w *cmfServiceWrapper) Terminate(ctx context.Context) error {
 	log.Info("服务终止: " + w.name)
+	if closer, ok := w.service.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("服务 '%s' 关闭失败: %w", w.name, err)
+		}
+	}
 	return nil
 }
 
@@ -353,6 +442,12 @@ func (b *Bootstrap) setupRoutes(app *fiber.App) {
 	for _, routeRegister := range b.routeRegisters {
 		routeRegister(app, Config)
 	}
+	// 注册本地磁盘签名链接的路由
+	if fs, ok := GetServiceTyped[*filesystem.Filesystem](b, "filesystem"); ok {
+		if err := fs.RegisterRoutes(app); err != nil {
+			log.Error("注册文件签名链接路由失败", zap.Error(err))
+		}
+	}
 	// 注册默认路由
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.SendString("Hello world! cmf!")
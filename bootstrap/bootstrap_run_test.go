@@ -1,15 +1,20 @@
 package bootstrap_test
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
 	"github.com/wuwuseo/cmf/bootstrap"
 	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/redis"
 )
 
 // sendInterrupt 跨平台发送中断信号
@@ -321,3 +326,149 @@ func TestBootstrap_Run_Middleware(t *testing.T) {
 	case <-time.After(5 * time.Second):
 	}
 }
+
+func TestBootstrap_Run_ClosesRedisCacheClient(t *testing.T) {
+	oldConf := config.Conf
+	defer func() { config.Conf = oldConf }()
+	oldManager := redis.DefaultManager()
+	defer redis.SetDefaultManager(oldManager)
+
+	server := miniredis.RunT(t)
+	testPort := 19993
+	cfg := makeTestConfig(testPort)
+	cfg.Redis.Default = "default"
+	cfg.Redis.Connections = map[string]config.Redis{"default": {Addr: server.Addr()}}
+	cfg.Cache.Default = "redis"
+	cfg.Cache.Stores["redis"] = struct {
+		Driver     string `mapstructure:"driver"`
+		DefaultTTL int    `mapstructure:"default_ttl"`
+		Options    any    `mapstructure:"options"`
+	}{Driver: "redis", DefaultTTL: 60}
+	config.Conf = cfg
+
+	b := bootstrap.NewBootstrap()
+	manager := bootstrap.MustGetServiceTyped[*redis.Manager](b, "redis")
+	if redis.DefaultManager() != manager {
+		t.Fatal("默认管理器应为注册的 redis 服务")
+	}
+	// 默认缓存的客户端应由注册的管理器持有
+	if stats := manager.Stats(); len(stats) != 1 || !stats[0].Connected {
+		t.Fatalf("默认缓存的 Redis 客户端应由 redis 服务管理: %+v", stats)
+	}
+	if server.CurrentConnectionCount() == 0 {
+		t.Fatal("默认缓存应已建立 Redis 连接")
+	}
+
+	done := runBootstrapAndWait(t, b, testPort)
+	sendInterrupt()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Skip("Run 未退出（Windows 信号限制）")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for server.CurrentConnectionCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := server.CurrentConnectionCount(); n != 0 {
+		t.Fatalf("关闭后默认缓存的 Redis 连接应已释放，剩余 %d", n)
+	}
+}
+
+func TestBootstrap_Run_RequestIDInContext(t *testing.T) {
+	oldConf := config.Conf
+	defer func() { config.Conf = oldConf }()
+
+	testPort := 19991
+	config.Conf = makeTestConfig(testPort)
+
+	b := bootstrap.NewBootstrap()
+	b.RegisterRoute(func(app *fiber.App, cfg *config.Config) {
+		app.Get("/api/rid", func(c fiber.Ctx) error {
+			return c.SendString(requestid.FromContext(c.Context()))
+		})
+	})
+
+	done := runBootstrapAndWait(t, b, testPort)
+	defer func() {
+		sendInterrupt()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/rid", testPort))
+	if err != nil {
+		t.Skipf("无法连接到测试服务器: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) == 0 || string(body) != resp.Header.Get(fiber.HeaderXRequestID) {
+		t.Errorf("c.Context() 应携带请求 ID，响应 %q，请求头 %q", body, resp.Header.Get(fiber.HeaderXRequestID))
+	}
+}
+
+// orderCloser 关闭时记录名称以及 Redis 连接是否仍可用
+type orderCloser struct {
+	name   string
+	closed *[]string
+	alive  func() bool
+}
+
+func (c *orderCloser) Close() error {
+	*c.closed = append(*c.closed, fmt.Sprintf("%s:%v", c.name, c.alive()))
+	return nil
+}
+
+func TestBootstrap_Run_TerminatesInReverseOrder(t *testing.T) {
+	oldConf := config.Conf
+	defer func() { config.Conf = oldConf }()
+	oldManager := redis.DefaultManager()
+	defer redis.SetDefaultManager(oldManager)
+
+	server := miniredis.RunT(t)
+	testPort := 19992
+	cfg := makeTestConfig(testPort)
+	cfg.Redis.Default = "default"
+	cfg.Redis.Connections = map[string]config.Redis{"default": {Addr: server.Addr()}}
+	config.Conf = cfg
+
+	b := bootstrap.NewBootstrap()
+	manager := bootstrap.MustGetServiceTyped[*redis.Manager](b, "redis")
+	if _, err := manager.Client(context.Background()); err != nil {
+		t.Fatalf("连接 Redis 失败: %v", err)
+	}
+	alive := func() bool { return server.CurrentConnectionCount() > 0 }
+
+	var closed []string
+	for _, name := range []string{"a", "b", "c"} {
+		b.RegisterService(name, &orderCloser{name: name, closed: &closed, alive: alive})
+	}
+	// 重复注册保留原有位置
+	b.RegisterService("b", &orderCloser{name: "b2", closed: &closed, alive: alive})
+	// Redis 管理器即使在其他服务之后注册也最后关闭
+	b.RemoveService("redis")
+	b.RegisterService("redis", manager)
+
+	done := runBootstrapAndWait(t, b, testPort)
+	sendInterrupt()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Skip("Run 未退出（Windows 信号限制）")
+	}
+
+	want := []string{"c:true", "b2:true", "a:true"}
+	if fmt.Sprint(closed) != fmt.Sprint(want) {
+		t.Fatalf("服务应按注册的相反顺序关闭且 Redis 最后关闭，期望 %v，实际 %v", want, closed)
+	}
+	deadline := time.Now().Add(time.Second)
+	for alive() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := server.CurrentConnectionCount(); n != 0 {
+		t.Fatalf("Redis 管理器应已关闭，剩余连接 %d", n)
+	}
+}
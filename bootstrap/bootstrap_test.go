@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/wuwuseo/cmf/bootstrap"
 	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/redis"
 )
 
 // safeNewBootstrap 安全地创建 Bootstrap 实例
@@ -81,6 +82,18 @@ func TestNewBootstrap_HasFilesystemService(t *testing.T) {
 	}
 }
 
+// TestNewBootstrap_HasRedisService 测试创建后已注册 redis 管理器服务
+func TestNewBootstrap_HasRedisService(t *testing.T) {
+	b := safeNewBootstrap(t)
+	manager, ok := bootstrap.GetServiceTyped[*redis.Manager](b, "redis")
+	if !ok || manager == nil {
+		t.Fatal("NewBootstrap 后应已注册 redis 管理器服务")
+	}
+	if redis.DefaultManager() != manager {
+		t.Error("redis 服务应设置为默认管理器")
+	}
+}
+
 // =============================================================================
 // RegisterService 测试
 // =============================================================================
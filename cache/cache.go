@@ -20,41 +20,33 @@ var ProviderSet = wire.NewSet(NewCache)
 type Cache[T any] struct {
 	ctx context.Context
 	*cache.Cache[T]
-	cfg      *config.Config
-	stores   *sync.Map // 用于存储不同存储类型的缓存实例
-	storeKey string    // 当前存储的键
+	cfg         *config.Config
+	stores      *sync.Map // 用于存储不同存储类型的缓存实例
+	instruments *sync.Map // 存储名称到统计存储的映射，供 Stats 使用
+	storeKey    string    // 当前存储的键
 }
 
 // NewCache 创建一个缓存实例，默认存储[]byte类型的数据
 func NewCache(ctx context.Context, cfg *config.Config) *Cache[[]byte] {
 	// 获取默认缓存存储配置
 	defaultStoreName := cfg.Cache.Default
-	storeConfig, exists := cfg.Cache.Stores[defaultStoreName]
-	if !exists {
-		panic("cache driver not found")
-	}
-
-	var store gostore.StoreInterface
-	switch storeConfig.Driver {
-	case "redis":
-		store = driver.NewRedisCache(ctx, cfg)
-
-	case "memory":
-		store = driver.NewBigCache(ctx, cfg)
-	default:
+	store, err := newStore(ctx, cfg, defaultStoreName)
+	if err != nil {
 		panic("cache driver not found")
 	}
 
 	cacheInstance := &Cache[[]byte]{
-		ctx:      ctx,
-		Cache:    cache.New[[]byte](store),
-		cfg:      cfg,
-		stores:   &sync.Map{},
-		storeKey: defaultStoreName,
+		ctx:         ctx,
+		Cache:       cache.New[[]byte](store),
+		cfg:         cfg,
+		stores:      &sync.Map{},
+		instruments: &sync.Map{},
+		storeKey:    defaultStoreName,
 	}
 
 	// 将默认存储实例存储到sync.Map中
 	cacheInstance.stores.Store(defaultStoreName, cacheInstance)
+	cacheInstance.instruments.Store(defaultStoreName, store)
 
 	return cacheInstance
 }
@@ -66,8 +58,36 @@ func (c *Cache[T]) Store(storeName string) (*Cache[T], error) {
 		return store.(*Cache[T]), nil
 	}
 
+	store, err := newStore(c.ctx, c.cfg, storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheInstance := &Cache[T]{
+		ctx:         c.ctx,
+		Cache:       cache.New[T](store),
+		cfg:         c.cfg,
+		stores:      c.stores, // 共享同一个sync.Map
+		instruments: c.instruments,
+		storeKey:    storeName,
+	}
+
+	// 将新创建的存储实例存储到sync.Map中
+	actual, loaded := c.stores.LoadOrStore(storeName, cacheInstance)
+	if loaded {
+		// 并发创建时使用先写入的实例，关闭本次创建的存储（如 bigcache 的清理协程）
+		store.Close()
+		return actual.(*Cache[T]), nil
+	}
+	c.instruments.Store(storeName, store)
+
+	return cacheInstance, nil
+}
+
+// newStore 根据存储配置创建带统计功能的底层存储
+func newStore(ctx context.Context, cfg *config.Config, storeName string) (*instrumentedStore, error) {
 	// 获取指定的缓存存储配置
-	storeConfig, exists := c.cfg.Cache.Stores[storeName]
+	storeConfig, exists := cfg.Cache.Stores[storeName]
 	if !exists {
 		return nil, fmt.Errorf("cache store '%s' not found", storeName)
 	}
@@ -75,26 +95,16 @@ func (c *Cache[T]) Store(storeName string) (*Cache[T], error) {
 	var store gostore.StoreInterface
 	switch storeConfig.Driver {
 	case "redis":
-		store = driver.NewRedisCache(c.ctx, c.cfg)
+		store = driver.NewRedisCache(ctx, cfg, storeName)
 
 	case "memory":
-		store = driver.NewBigCache(c.ctx, c.cfg)
+		store = driver.NewBigCache(ctx, cfg, storeName)
 	default:
 		return nil, fmt.Errorf("unsupported cache driver: %s", storeConfig.Driver)
 	}
 
-	cacheInstance := &Cache[T]{
-		ctx:      c.ctx,
-		Cache:    cache.New[T](store),
-		cfg:      c.cfg,
-		stores:   c.stores, // 共享同一个sync.Map
-		storeKey: storeName,
-	}
-
-	// 将新创建的存储实例存储到sync.Map中
-	c.stores.Store(storeName, cacheInstance)
-
-	return cacheInstance, nil
+	ttl := time.Duration(storeConfig.DefaultTTL) * time.Second
+	return newInstrumentedStore(store, storeName, storeConfig.Driver, ttl), nil
 }
 
 // TypedCache 提供类型安全的缓存操作
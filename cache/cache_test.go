@@ -2,6 +2,8 @@ package cache_test
 
 import (
 	"context"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -347,3 +349,33 @@ func TestCache_Store_Singleton(t *testing.T) {
 		t.Fatal("同一存储 Store() 两次应该返回同一实例")
 	}
 }
+
+func TestCache_Store_ConcurrentClosesLoser(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Cache.Stores["another"] = cfg.Cache.Stores["memory"]
+	c := cache.NewCache(ctx, cfg)
+	before := runtime.NumGoroutine()
+
+	// 并发切换到同一存储，竞争失败方创建的 bigcache 应被关闭
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Store("another"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 只应保留胜出存储的一个清理协程
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before+1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - before - 1; leaked > 0 {
+		t.Errorf("并发创建存储后多出 %d 个协程，竞争失败的存储未关闭", leaked)
+	}
+}
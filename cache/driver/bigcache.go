@@ -10,11 +10,30 @@ import (
 	"github.com/wuwuseo/cmf/config"
 )
 
-func NewBigCache(ctx context.Context, cfg *config.Config) gostore.StoreInterface {
-	// 获取默认缓存存储配置
-	defaultStore := cfg.Cache.Default
-	storeConfig := cfg.Cache.Stores[defaultStore]
+// BigCacheStore 在 gocache bigcache 存储基础上暴露键数量统计
+type BigCacheStore struct {
+	*bigcachestore.BigcacheStore
+	client *bigcache.BigCache
+}
+
+// KeyCount 返回当前缓存条目数量（包含标签索引条目）
+func (s *BigCacheStore) KeyCount(_ context.Context) (int64, error) {
+	return int64(s.client.Len()), nil
+}
+
+// Close 停止 bigcache 的过期清理协程并释放内存
+func (s *BigCacheStore) Close() error {
+	return s.client.Close()
+}
+
+// NewBigCache 创建内存缓存存储
+// storeName 可选，指定使用的缓存存储配置，默认使用 cache.default
+func NewBigCache(ctx context.Context, cfg *config.Config, storeName ...string) gostore.StoreInterface {
+	storeConfig := getStoreConfig(cfg, storeName...)
 
 	bigcacheClient, _ := bigcache.New(ctx, bigcache.DefaultConfig(time.Duration(storeConfig.DefaultTTL)*time.Second))
-	return bigcachestore.NewBigcache(bigcacheClient)
+	return &BigCacheStore{
+		BigcacheStore: bigcachestore.NewBigcache(bigcacheClient),
+		client:        bigcacheClient,
+	}
 }
@@ -79,3 +79,25 @@ func TestNewBigCache_GetNonExistentKey(t *testing.T) {
 		t.Error("不存在的 key 应该返回错误")
 	}
 }
+
+func TestNewBigCache_KeyCount(t *testing.T) {
+	cfg := newTestConfig()
+	ctx := context.Background()
+	store := driver.NewBigCache(ctx, cfg)
+
+	_ = store.Set(ctx, "count_key", []byte("value"))
+
+	counter, ok := store.(interface {
+		KeyCount(ctx context.Context) (int64, error)
+	})
+	if !ok {
+		t.Fatal("BigCache 存储应支持 KeyCount")
+	}
+	count, err := counter.KeyCount(ctx)
+	if err != nil {
+		t.Fatalf("KeyCount 失败: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("期望 1 个键，实际 %d", count)
+	}
+}
@@ -0,0 +1,44 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/wuwuseo/cmf/config"
+)
+
+// storeConfig 单个缓存存储的配置
+type storeConfig struct {
+	Driver     string
+	DefaultTTL int
+	Options    map[string]any
+}
+
+// getStoreConfig 获取指定名称的缓存存储配置，未指定名称时使用默认存储
+func getStoreConfig(cfg *config.Config, storeName ...string) storeConfig {
+	name := cfg.Cache.Default
+	if len(storeName) > 0 && storeName[0] != "" {
+		name = storeName[0]
+	}
+	store := cfg.Cache.Stores[name]
+	options, ok := store.Options.(map[string]any)
+	if !ok {
+		options = map[string]any{}
+	}
+	return storeConfig{
+		Driver:     store.Driver,
+		DefaultTTL: store.DefaultTTL,
+		Options:    options,
+	}
+}
+
+// optionString 读取字符串类型的驱动选项
+func (s storeConfig) optionString(key string) string {
+	value, ok := s.Options[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return fmt.Sprint(value)
+}
@@ -2,23 +2,117 @@ package driver
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	gostore "github.com/eko/gocache/lib/v4/store"
 	redisstore "github.com/eko/gocache/store/redis/v4"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/wuwuseo/cmf/config"
 	"github.com/wuwuseo/cmf/redis"
 )
 
-func NewRedisCache(ctx context.Context, cfg *config.Config) gostore.StoreInterface {
-	client, err := redis.NewClientFromConfig(ctx, cfg)
+// RedisStore 在 gocache redis 存储基础上增加键前缀隔离与键数量统计
+// 读取结果统一转换为 []byte，与内存驱动保持一致
+type RedisStore struct {
+	*redisstore.RedisStore
+	client goredis.UniversalClient
+	prefix string
+}
+
+// NewRedisCache 创建 Redis 缓存存储
+// storeName 可选，指定使用的缓存存储配置，默认使用 cache.default；
+// 存储选项 connection 指定 Redis 连接名称，prefix 指定键前缀
+func NewRedisCache(ctx context.Context, cfg *config.Config, storeName ...string) gostore.StoreInterface {
+	storeConfig := getStoreConfig(cfg, storeName...)
+
+	var connection []string
+	if name := storeConfig.optionString("connection"); name != "" {
+		connection = append(connection, name)
+	}
+	client, err := redis.NewClientFromConfig(ctx, cfg, connection...)
 	if err != nil {
 		panic(err)
 	}
 
-	// 获取默认缓存存储配置
-	defaultStore := cfg.Cache.Default
-	storeConfig := cfg.Cache.Stores[defaultStore]
+	return &RedisStore{
+		RedisStore: redisstore.NewRedis(client, gostore.WithExpiration(time.Duration(storeConfig.DefaultTTL)*time.Second)),
+		client:     client,
+		prefix:     storeConfig.optionString("prefix"),
+	}
+}
+
+// Get 读取缓存值
+func (s *RedisStore) Get(ctx context.Context, key any) (any, error) {
+	value, err := s.RedisStore.Get(ctx, s.scopedKey(key))
+	return toBytes(value), err
+}
+
+// GetWithTTL 读取缓存值及剩余过期时间
+func (s *RedisStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	value, ttl, err := s.RedisStore.GetWithTTL(ctx, s.scopedKey(key))
+	return toBytes(value), ttl, err
+}
+
+// Set 写入缓存值
+func (s *RedisStore) Set(ctx context.Context, key any, value any, options ...gostore.Option) error {
+	return s.RedisStore.Set(ctx, s.scopedKey(key), value, options...)
+}
+
+// Delete 删除缓存值
+func (s *RedisStore) Delete(ctx context.Context, key any) error {
+	return s.RedisStore.Delete(ctx, s.scopedKey(key))
+}
+
+// KeyCount 返回当前存储的键数量
+// 配置了前缀时通过 SCAN 统计前缀下的键，否则返回 DBSIZE；Cluster 模式下汇总所有主节点
+func (s *RedisStore) KeyCount(ctx context.Context) (int64, error) {
+	if s.prefix == "" {
+		return s.client.DBSize(ctx).Result()
+	}
+
+	cluster, ok := s.client.(*goredis.ClusterClient)
+	if !ok {
+		return scanCount(ctx, s.client, s.prefix+"*")
+	}
+	var total atomic.Int64
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+		count, err := scanCount(ctx, node, s.prefix+"*")
+		total.Add(count)
+		return err
+	})
+	return total.Load(), err
+}
 
-	return redisstore.NewRedis(client, gostore.WithExpiration(time.Duration(storeConfig.DefaultTTL)*time.Second))
+// scanCount 通过 SCAN 统计匹配 pattern 的键数量
+func scanCount(ctx context.Context, client goredis.Cmdable, pattern string) (int64, error) {
+	var count int64
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+		count += int64(len(keys))
+		if next == 0 {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+// scopedKey 为键添加存储前缀
+func (s *RedisStore) scopedKey(key any) any {
+	if str, ok := key.(string); ok && s.prefix != "" {
+		return s.prefix + str
+	}
+	return key
+}
+
+// toBytes 将 Redis 返回的字符串转换为 []byte
+func toBytes(value any) any {
+	if str, ok := value.(string); ok {
+		return []byte(str)
+	}
+	return value
 }
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gostore "github.com/eko/gocache/lib/v4/store"
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	defaultMiddlewareExpiration = 5 * time.Minute
+	defaultMiddlewareKeyPrefix  = "httpcache"
+	defaultMiddlewareHeader     = "X-Cache"
+
+	cacheStatusHit    = "HIT"
+	cacheStatusMiss   = "MISS"
+	cacheStatusBypass = "BYPASS"
+)
+
+// skippedResponseHeaders 不随缓存条目保存/回放的响应头
+// 这些头要么由 fasthttp 重新计算，要么与单次请求相关
+var skippedResponseHeaders = map[string]struct{}{
+	fiber.HeaderContentLength:    {},
+	fiber.HeaderDate:             {},
+	fiber.HeaderConnection:       {},
+	fiber.HeaderTransferEncoding: {},
+	fiber.HeaderSetCookie:        {},
+	fiber.HeaderAge:              {},
+	fiber.HeaderXRequestID:       {},
+}
+
+// MiddlewareConfig HTTP 响应缓存中间件配置
+type MiddlewareConfig struct {
+	// Cache 缓存实例（必填）
+	Cache *Cache[[]byte]
+	// Store 可选：使用的命名缓存存储，为空时使用 Cache 当前存储
+	Store string
+	// Next 返回 true 时跳过中间件
+	Next func(c fiber.Ctx) bool
+	// Expiration 默认缓存时间，响应未声明 max-age 时使用，默认 5 分钟
+	Expiration time.Duration
+	// KeyPrefix 缓存键前缀，默认 httpcache
+	KeyPrefix string
+	// QueryParams 参与缓存键计算的查询参数，为空时使用全部查询参数
+	QueryParams []string
+	// VaryBy 可选：返回用户或租户标识，用于按身份隔离缓存
+	// 未设置时携带 Authorization 或 Cookie 的请求不读写缓存，避免不同用户共享响应
+	VaryBy func(c fiber.Ctx) string
+	// Tags 可选：返回缓存条目的标签，用于 PurgeTags 按标签清除
+	Tags func(c fiber.Ctx) []string
+	// Methods 允许缓存的请求方法，默认 GET、HEAD
+	Methods []string
+	// StatusCodes 允许缓存的响应状态码，默认 200
+	StatusCodes []int
+	// CacheHeader 标识缓存命中状态的响应头，默认 X-Cache
+	CacheHeader string
+	// DisableETag 为 true 时不自动生成 ETag，也不处理 If-None-Match
+	DisableETag bool
+}
+
+// cachedResponse 缓存中保存的完整响应
+type cachedResponse struct {
+	Status    int         `json:"status"`
+	Headers   [][2]string `json:"headers"`
+	Body      []byte      `json:"body"`
+	ETag      string      `json:"etag"`
+	StoredAt  int64       `json:"stored_at"`
+	ExpiresAt int64       `json:"expires_at"`
+}
+
+// NewMiddleware 创建 HTTP 响应缓存中间件
+// 缓存键由请求方法、路径、选定的查询参数以及 VaryBy 返回的身份标识组成，
+// 遵循请求与响应的 Cache-Control 指令，支持 ETag/If-None-Match 协商，
+// 并跳过 SSE 等流式响应；未设置 VaryBy 时跳过携带凭据的请求
+func NewMiddleware(config MiddlewareConfig) fiber.Handler {
+	if config.Cache == nil {
+		panic("cache middleware requires a cache instance")
+	}
+	store := config.Cache
+	if config.Store != "" {
+		named, err := config.Cache.Store(config.Store)
+		if err != nil {
+			panic(err)
+		}
+		store = named
+	}
+	if config.Expiration <= 0 {
+		config.Expiration = defaultMiddlewareExpiration
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultMiddlewareKeyPrefix
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{fiber.MethodGet, fiber.MethodHead}
+	}
+	if len(config.StatusCodes) == 0 {
+		config.StatusCodes = []int{fiber.StatusOK}
+	}
+	if config.CacheHeader == "" {
+		config.CacheHeader = defaultMiddlewareHeader
+	}
+
+	return func(c fiber.Ctx) error {
+		if config.Next != nil && config.Next(c) {
+			return c.Next()
+		}
+		if !slices.Contains(config.Methods, c.Method()) || isEventStreamRequest(c) {
+			return c.Next()
+		}
+
+		if config.VaryBy == nil && hasCredentials(c) {
+			c.Set(config.CacheHeader, cacheStatusBypass)
+			return c.Next()
+		}
+
+		reqDirectives := parseCacheControl(c.Get(fiber.HeaderCacheControl))
+		if _, ok := reqDirectives["no-store"]; ok {
+			c.Set(config.CacheHeader, cacheStatusBypass)
+			return c.Next()
+		}
+		_, noCache := reqDirectives["no-cache"]
+		if strings.Contains(strings.ToLower(c.Get(fiber.HeaderPragma)), "no-cache") {
+			noCache = true
+		}
+
+		ctx := c.Context()
+		key := buildResponseCacheKey(c, &config)
+
+		// 命中缓存直接回放
+		if !noCache {
+			if entry, ok := loadCachedResponse(ctx, store, key); ok {
+				return replayCachedResponse(c, &config, entry)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		c.Set(config.CacheHeader, cacheStatusMiss)
+		ttl, cacheable := responseTTL(c, &config)
+		if !cacheable {
+			return nil
+		}
+
+		entry := captureResponse(c, &config, ttl)
+		if data, err := json.Marshal(entry); err == nil {
+			var opts []gostore.Option
+			opts = append(opts, gostore.WithExpiration(ttl))
+			if config.Tags != nil {
+				if tags := config.Tags(c); len(tags) > 0 {
+					opts = append(opts, gostore.WithTags(tags))
+				}
+			}
+			// 写缓存失败不影响本次响应
+			_ = store.Set(ctx, key, data, opts...)
+		}
+
+		if !config.DisableETag && etagMatches(c.Get(fiber.HeaderIfNoneMatch), entry.ETag) {
+			c.Response().ResetBody()
+			c.Status(fiber.StatusNotModified)
+		}
+		return nil
+	}
+}
+
+// PurgeTags 清除带有指定标签的所有缓存响应
+func PurgeTags(ctx context.Context, c *Cache[[]byte], tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return c.Invalidate(ctx, gostore.WithInvalidateTags(tags))
+}
+
+// hasCredentials 判断请求是否携带 Authorization 或 Cookie，此类响应通常因用户而异
+func hasCredentials(c fiber.Ctx) bool {
+	return c.Get(fiber.HeaderAuthorization) != "" || c.Get(fiber.HeaderCookie) != ""
+}
+
+// isEventStreamRequest 判断是否为 SSE 请求
+func isEventStreamRequest(c fiber.Ctx) bool {
+	return strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+}
+
+// buildResponseCacheKey 根据请求方法、路径、查询参数和身份标识生成缓存键
+// 原始键经 SHA-256 摘要，避免特殊字符影响底层存储（如 bigcache 标签索引）
+func buildResponseCacheKey(c fiber.Ctx, config *MiddlewareConfig) string {
+	var b strings.Builder
+	b.WriteString(c.Method())
+	b.WriteByte('\n')
+	b.WriteString(c.Path())
+	b.WriteByte('\n')
+
+	args := c.Request().URI().QueryArgs()
+	var pairs []string
+	if len(config.QueryParams) > 0 {
+		for _, name := range config.QueryParams {
+			for _, value := range args.PeekMulti(name) {
+				pairs = append(pairs, name+"="+string(value))
+			}
+		}
+	} else {
+		for k, v := range args.All() {
+			pairs = append(pairs, string(k)+"="+string(v))
+		}
+	}
+	sort.Strings(pairs)
+	b.WriteString(strings.Join(pairs, "&"))
+	b.WriteByte('\n')
+
+	if config.VaryBy != nil {
+		b.WriteString(config.VaryBy(c))
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return config.KeyPrefix + ":" + hex.EncodeToString(sum[:])
+}
+
+// loadCachedResponse 读取并校验缓存条目，过期条目视为未命中
+// 部分驱动（如 bigcache）不支持单条目过期时间，因此在此再次校验
+func loadCachedResponse(ctx context.Context, store *Cache[[]byte], key string) (*cachedResponse, bool) {
+	data, err := store.Get(ctx, key)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if entry.ExpiresAt > 0 && time.Now().Unix() >= entry.ExpiresAt {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// replayCachedResponse 将缓存条目写回响应
+func replayCachedResponse(c fiber.Ctx, config *MiddlewareConfig, entry *cachedResponse) error {
+	c.Set(config.CacheHeader, cacheStatusHit)
+	c.Set(fiber.HeaderAge, strconv.FormatInt(max(time.Now().Unix()-entry.StoredAt, 0), 10))
+
+	if !config.DisableETag && etagMatches(c.Get(fiber.HeaderIfNoneMatch), entry.ETag) {
+		c.Set(fiber.HeaderETag, entry.ETag)
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	for _, header := range entry.Headers {
+		c.Set(header[0], header[1])
+	}
+	c.Status(entry.Status)
+	return c.Send(entry.Body)
+}
+
+// responseTTL 根据响应状态和 Cache-Control 计算缓存时间
+// 返回 false 表示该响应不可缓存
+func responseTTL(c fiber.Ctx, config *MiddlewareConfig) (time.Duration, bool) {
+	resp := c.Response()
+	if !slices.Contains(config.StatusCodes, resp.StatusCode()) {
+		return 0, false
+	}
+	if resp.IsBodyStream() || strings.HasPrefix(string(resp.Header.ContentType()), "text/event-stream") {
+		return 0, false
+	}
+	if len(resp.Header.Peek(fiber.HeaderSetCookie)) > 0 {
+		return 0, false
+	}
+
+	directives := parseCacheControl(string(resp.Header.Peek(fiber.HeaderCacheControl)))
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return 0, false
+		}
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return config.Expiration, true
+}
+
+// captureResponse 复制当前响应为缓存条目，必要时生成 ETag
+func captureResponse(c fiber.Ctx, config *MiddlewareConfig, ttl time.Duration) *cachedResponse {
+	resp := c.Response()
+	body := append([]byte(nil), resp.Body()...)
+
+	etag := string(resp.Header.Peek(fiber.HeaderETag))
+	if etag == "" && !config.DisableETag {
+		sum := sha256.Sum256(body)
+		etag = fmt.Sprintf("%q", hex.EncodeToString(sum[:16]))
+		c.Set(fiber.HeaderETag, etag)
+	}
+
+	var headers [][2]string
+	for k, v := range resp.Header.All() {
+		name := string(k)
+		if _, skip := skippedResponseHeaders[name]; skip || strings.EqualFold(name, config.CacheHeader) {
+			continue
+		}
+		headers = append(headers, [2]string{name, string(v)})
+	}
+
+	now := time.Now()
+	return &cachedResponse{
+		Status:    resp.StatusCode(),
+		Headers:   headers,
+		Body:      body,
+		ETag:      etag,
+		StoredAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+}
+
+// parseCacheControl 解析 Cache-Control 指令，键统一为小写
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// etagMatches 判断 If-None-Match 是否匹配给定 ETag（弱比较）
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == target {
+			return true
+		}
+	}
+	return false
+}
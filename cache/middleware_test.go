@@ -0,0 +1,229 @@
+package cache_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/wuwuseo/cmf/cache"
+)
+
+// newMiddlewareApp 创建挂载了响应缓存中间件的测试应用，返回处理函数调用计数
+func newMiddlewareApp(t *testing.T, config cache.MiddlewareConfig, handler fiber.Handler) (*fiber.App, *int32) {
+	t.Helper()
+	if config.Cache == nil {
+		config.Cache = cache.NewCache(context.Background(), newTestConfig())
+	}
+	var calls int32
+	app := fiber.New()
+	app.Use(cache.NewMiddleware(config))
+	app.Get("/*", func(c fiber.Ctx) error {
+		atomic.AddInt32(&calls, 1)
+		return handler(c)
+	})
+	return app, &calls
+}
+
+// doRequest 发送测试请求并返回响应体
+func doRequest(t *testing.T, app *fiber.App, target string, headers map[string]string) (int, string, map[string]string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	out := map[string]string{
+		"X-Cache":       resp.Header.Get("X-Cache"),
+		"ETag":          resp.Header.Get("ETag"),
+		"Content-Type":  resp.Header.Get("Content-Type"),
+		"Cache-Control": resp.Header.Get("Cache-Control"),
+	}
+	return resp.StatusCode, string(body), out
+}
+
+func TestMiddleware_CachesResponse(t *testing.T) {
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{}, func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"n": 1})
+	})
+
+	status, body, headers := doRequest(t, app, "/articles/cache-hit", nil)
+	if status != fiber.StatusOK || headers["X-Cache"] != "MISS" {
+		t.Fatalf("首次请求应为 MISS，得到 status=%d X-Cache=%s", status, headers["X-Cache"])
+	}
+
+	status, body2, headers2 := doRequest(t, app, "/articles/cache-hit", nil)
+	if status != fiber.StatusOK || headers2["X-Cache"] != "HIT" {
+		t.Fatalf("第二次请求应为 HIT，得到 status=%d X-Cache=%s", status, headers2["X-Cache"])
+	}
+	if body != body2 {
+		t.Errorf("缓存响应体不一致: %q != %q", body, body2)
+	}
+	if headers2["Content-Type"] != headers["Content-Type"] {
+		t.Errorf("缓存响应头不一致: %q != %q", headers2["Content-Type"], headers["Content-Type"])
+	}
+	if atomic.LoadInt32(calls) != 1 {
+		t.Errorf("处理函数应只执行一次，实际 %d 次", *calls)
+	}
+}
+
+func TestMiddleware_QueryParamsInKey(t *testing.T) {
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{QueryParams: []string{"page"}}, func(c fiber.Ctx) error {
+		return c.SendString("page " + c.Query("page"))
+	})
+
+	doRequest(t, app, "/list/query?page=1&utm=a", nil)
+	_, _, headers := doRequest(t, app, "/list/query?page=1&utm=b", nil)
+	if headers["X-Cache"] != "HIT" {
+		t.Errorf("未选中的查询参数不应影响缓存键")
+	}
+	_, body, headers := doRequest(t, app, "/list/query?page=2", nil)
+	if headers["X-Cache"] != "MISS" || body != "page 2" {
+		t.Errorf("选中的查询参数应区分缓存键，得到 %s %q", headers["X-Cache"], body)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("处理函数应执行两次，实际 %d 次", *calls)
+	}
+}
+
+func TestMiddleware_VaryBy(t *testing.T) {
+	app, _ := newMiddlewareApp(t, cache.MiddlewareConfig{
+		VaryBy: func(c fiber.Ctx) string { return c.Get("X-Tenant") },
+	}, func(c fiber.Ctx) error {
+		return c.SendString("tenant " + c.Get("X-Tenant"))
+	})
+
+	doRequest(t, app, "/vary", map[string]string{"X-Tenant": "a"})
+	_, body, headers := doRequest(t, app, "/vary", map[string]string{"X-Tenant": "b"})
+	if headers["X-Cache"] != "MISS" || body != "tenant b" {
+		t.Errorf("不同租户不应共享缓存，得到 %s %q", headers["X-Cache"], body)
+	}
+}
+
+func TestMiddleware_SkipsCredentialedRequests(t *testing.T) {
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{}, func(c fiber.Ctx) error {
+		return c.SendString("user " + c.Get("Authorization") + c.Cookies("session"))
+	})
+
+	for _, headers := range []map[string]string{
+		{"Authorization": "Bearer alice"},
+		{"Cookie": "session=alice"},
+	} {
+		doRequest(t, app, "/me", headers)
+		_, _, out := doRequest(t, app, "/me", headers)
+		if out["X-Cache"] != "BYPASS" {
+			t.Errorf("未设置 VaryBy 时携带凭据的请求不应使用缓存，得到 %s", out["X-Cache"])
+		}
+	}
+	// 匿名请求不应读到带凭据请求的响应
+	_, body, out := doRequest(t, app, "/me", nil)
+	if out["X-Cache"] != "MISS" || body != "user " {
+		t.Errorf("匿名请求应重新执行处理函数，得到 %s %q", out["X-Cache"], body)
+	}
+	if atomic.LoadInt32(calls) != 5 {
+		t.Errorf("处理函数应执行 5 次，实际 %d 次", *calls)
+	}
+
+	// 设置 VaryBy 后按身份隔离缓存
+	app, _ = newMiddlewareApp(t, cache.MiddlewareConfig{
+		VaryBy: func(c fiber.Ctx) string { return c.Get("Authorization") },
+	}, func(c fiber.Ctx) error {
+		return c.SendString("user " + c.Get("Authorization"))
+	})
+	doRequest(t, app, "/me", map[string]string{"Authorization": "Bearer bob"})
+	_, body, out = doRequest(t, app, "/me", map[string]string{"Authorization": "Bearer bob"})
+	if out["X-Cache"] != "HIT" || body != "user Bearer bob" {
+		t.Errorf("设置 VaryBy 后应缓存携带凭据的请求，得到 %s %q", out["X-Cache"], body)
+	}
+}
+
+func TestMiddleware_ETagRevalidation(t *testing.T) {
+	app, _ := newMiddlewareApp(t, cache.MiddlewareConfig{}, func(c fiber.Ctx) error {
+		return c.SendString("etag body")
+	})
+
+	_, _, headers := doRequest(t, app, "/etag", nil)
+	etag := headers["ETag"]
+	if etag == "" {
+		t.Fatal("应自动生成 ETag")
+	}
+
+	status, body, _ := doRequest(t, app, "/etag", map[string]string{"If-None-Match": etag})
+	if status != fiber.StatusNotModified {
+		t.Errorf("If-None-Match 匹配时应返回 304，得到 %d", status)
+	}
+	if body != "" {
+		t.Errorf("304 响应不应包含响应体，得到 %q", body)
+	}
+}
+
+func TestMiddleware_RespectsCacheControl(t *testing.T) {
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{}, func(c fiber.Ctx) error {
+		if c.Path() == "/private" {
+			c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+		}
+		return c.SendString("ok")
+	})
+
+	doRequest(t, app, "/private", nil)
+	_, _, headers := doRequest(t, app, "/private", nil)
+	if headers["X-Cache"] != "MISS" {
+		t.Errorf("private 响应不应被缓存")
+	}
+
+	doRequest(t, app, "/public", nil)
+	_, _, headers = doRequest(t, app, "/public", map[string]string{"Cache-Control": "no-cache"})
+	if headers["X-Cache"] != "MISS" {
+		t.Errorf("请求 no-cache 应绕过缓存读取")
+	}
+	_, _, headers = doRequest(t, app, "/public", map[string]string{"Cache-Control": "no-store"})
+	if headers["X-Cache"] != "BYPASS" {
+		t.Errorf("请求 no-store 应完全绕过缓存，得到 %s", headers["X-Cache"])
+	}
+	if atomic.LoadInt32(calls) != 5 {
+		t.Errorf("处理函数调用次数不正确: %d", *calls)
+	}
+}
+
+func TestMiddleware_SkipsEventStream(t *testing.T) {
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{}, func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		return c.SendString("data: {}\n\n")
+	})
+
+	doRequest(t, app, "/sse/stream", nil)
+	doRequest(t, app, "/sse/stream", nil)
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("SSE 响应不应被缓存，处理函数调用 %d 次", *calls)
+	}
+}
+
+func TestPurgeTags(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewCache(ctx, newTestConfig())
+	app, calls := newMiddlewareApp(t, cache.MiddlewareConfig{
+		Cache: c,
+		Tags:  func(c fiber.Ctx) []string { return []string{"articles"} },
+	}, func(c fiber.Ctx) error {
+		return c.SendString("tagged")
+	})
+
+	doRequest(t, app, "/tagged", nil)
+	if err := cache.PurgeTags(ctx, c, "articles"); err != nil {
+		t.Fatalf("PurgeTags 失败: %v", err)
+	}
+	_, _, headers := doRequest(t, app, "/tagged", nil)
+	if headers["X-Cache"] != "MISS" {
+		t.Errorf("按标签清除后应重新生成响应")
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("处理函数应执行两次，实际 %d 次", *calls)
+	}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	gostore "github.com/eko/gocache/lib/v4/store"
+	"github.com/gofiber/fiber/v3"
+)
+
+// StoreStats 单个缓存存储的统计信息
+type StoreStats struct {
+	Name       string        `json:"name"`        // 存储名称
+	Driver     string        `json:"driver"`      // 驱动类型
+	DefaultTTL time.Duration `json:"default_ttl"` // 默认过期时间
+	Hits       uint64        `json:"hits"`        // 命中次数
+	Misses     uint64        `json:"misses"`      // 未命中次数
+	Sets       uint64        `json:"sets"`        // 写入次数
+	Deletes    uint64        `json:"deletes"`     // 删除次数（含按标签失效与清空）
+	Errors     uint64        `json:"errors"`      // 错误次数（不含未命中）
+	HitRatio   float64       `json:"hit_ratio"`   // 命中率
+	AvgLatency time.Duration `json:"avg_latency"` // 平均耗时
+	MaxLatency time.Duration `json:"max_latency"` // 最大耗时
+	Keys       int64         `json:"keys"`        // 键数量，驱动不支持时为 -1
+}
+
+// KeyCounter 可统计键数量的存储驱动实现该接口
+type KeyCounter interface {
+	KeyCount(ctx context.Context) (int64, error)
+}
+
+// instrumentedStore 为底层存储记录命中、未命中、写入、删除、错误次数及耗时
+type instrumentedStore struct {
+	gostore.StoreInterface
+	name       string
+	driver     string
+	defaultTTL time.Duration
+
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	sets         atomic.Uint64
+	deletes      atomic.Uint64
+	errors       atomic.Uint64
+	operations   atomic.Uint64
+	totalLatency atomic.Int64
+	maxLatency   atomic.Int64
+}
+
+func newInstrumentedStore(store gostore.StoreInterface, name string, driver string, defaultTTL time.Duration) *instrumentedStore {
+	return &instrumentedStore{
+		StoreInterface: store,
+		name:           name,
+		driver:         driver,
+		defaultTTL:     defaultTTL,
+	}
+}
+
+// Close 关闭持有独立资源的底层存储；Redis 存储的客户端由 Redis 管理器持有，不在此关闭
+func (s *instrumentedStore) Close() error {
+	if closer, ok := s.StoreInterface.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *instrumentedStore) Get(ctx context.Context, key any) (any, error) {
+	start := time.Now()
+	value, err := s.StoreInterface.Get(ctx, key)
+	s.observeRead(start, err)
+	return value, err
+}
+
+func (s *instrumentedStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	start := time.Now()
+	value, ttl, err := s.StoreInterface.GetWithTTL(ctx, key)
+	s.observeRead(start, err)
+	return value, ttl, err
+}
+
+func (s *instrumentedStore) Set(ctx context.Context, key any, value any, options ...gostore.Option) error {
+	start := time.Now()
+	err := s.StoreInterface.Set(ctx, key, value, options...)
+	s.observeWrite(start, &s.sets, err)
+	return err
+}
+
+func (s *instrumentedStore) Delete(ctx context.Context, key any) error {
+	start := time.Now()
+	err := s.StoreInterface.Delete(ctx, key)
+	s.observeWrite(start, &s.deletes, err)
+	return err
+}
+
+func (s *instrumentedStore) Invalidate(ctx context.Context, options ...gostore.InvalidateOption) error {
+	start := time.Now()
+	err := s.StoreInterface.Invalidate(ctx, options...)
+	s.observeWrite(start, &s.deletes, err)
+	return err
+}
+
+func (s *instrumentedStore) Clear(ctx context.Context) error {
+	start := time.Now()
+	err := s.StoreInterface.Clear(ctx)
+	s.observeWrite(start, &s.deletes, err)
+	return err
+}
+
+// observeRead 记录读操作，未找到的键计为未命中而非错误
+func (s *instrumentedStore) observeRead(start time.Time, err error) {
+	s.observeLatency(time.Since(start))
+	switch {
+	case err == nil:
+		s.hits.Add(1)
+	case isNotFound(err):
+		s.misses.Add(1)
+	default:
+		s.errors.Add(1)
+	}
+}
+
+// observeWrite 记录写操作
+func (s *instrumentedStore) observeWrite(start time.Time, counter *atomic.Uint64, err error) {
+	s.observeLatency(time.Since(start))
+	if err != nil {
+		s.errors.Add(1)
+		return
+	}
+	counter.Add(1)
+}
+
+func (s *instrumentedStore) observeLatency(d time.Duration) {
+	s.operations.Add(1)
+	s.totalLatency.Add(int64(d))
+	for {
+		current := s.maxLatency.Load()
+		if int64(d) <= current || s.maxLatency.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+// snapshot 生成统计快照，支持时附带键数量
+func (s *instrumentedStore) snapshot(ctx context.Context) StoreStats {
+	stats := StoreStats{
+		Name:       s.name,
+		Driver:     s.driver,
+		DefaultTTL: s.defaultTTL,
+		Hits:       s.hits.Load(),
+		Misses:     s.misses.Load(),
+		Sets:       s.sets.Load(),
+		Deletes:    s.deletes.Load(),
+		Errors:     s.errors.Load(),
+		MaxLatency: time.Duration(s.maxLatency.Load()),
+		Keys:       -1,
+	}
+	if reads := stats.Hits + stats.Misses; reads > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(reads)
+	}
+	if ops := s.operations.Load(); ops > 0 {
+		stats.AvgLatency = time.Duration(s.totalLatency.Load() / int64(ops))
+	}
+	if counter, ok := s.StoreInterface.(KeyCounter); ok {
+		if keys, err := counter.KeyCount(ctx); err == nil {
+			stats.Keys = keys
+		}
+	}
+	return stats
+}
+
+// isNotFound 判断错误是否表示键不存在
+func isNotFound(err error) bool {
+	return errors.Is(err, gostore.NotFound{}) || errors.Is(err, bigcache.ErrEntryNotFound)
+}
+
+// Stats 返回所有已创建存储的统计信息，按存储名称排序
+func (c *Cache[T]) Stats() []StoreStats {
+	var stats []StoreStats
+	c.instruments.Range(func(_, value any) bool {
+		stats = append(stats, value.(*instrumentedStore).snapshot(c.ctx))
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// StatsHandler 返回列出缓存存储统计信息的 Fiber 处理函数，供管理端路由挂载
+//
+//	admin.Get("/cache/stats", cache.StatsHandler(c))
+func StatsHandler[T any](c *Cache[T]) fiber.Handler {
+	return func(ctx fiber.Ctx) error {
+		return ctx.JSON(fiber.Map{"stores": c.Stats()})
+	}
+}
@@ -0,0 +1,87 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/wuwuseo/cmf/cache"
+)
+
+func TestCache_Stats(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewCache(ctx, newTestConfig())
+
+	_ = c.Set(ctx, "stats_key", []byte("value"))
+	_, _ = c.Get(ctx, "stats_key")
+	_, _ = c.Get(ctx, "stats_missing")
+	_ = c.Delete(ctx, "stats_key")
+
+	stats := c.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("期望 1 个存储的统计，得到 %d", len(stats))
+	}
+	s := stats[0]
+	if s.Name != "memory" || s.Driver != "memory" {
+		t.Errorf("存储名称或驱动不正确: %s / %s", s.Name, s.Driver)
+	}
+	if s.Hits != 1 || s.Misses != 1 || s.Sets != 1 || s.Deletes != 1 {
+		t.Errorf("计数不正确: hits=%d misses=%d sets=%d deletes=%d", s.Hits, s.Misses, s.Sets, s.Deletes)
+	}
+	if s.Errors != 0 {
+		t.Errorf("未命中不应计为错误，errors=%d", s.Errors)
+	}
+	if s.HitRatio != 0.5 {
+		t.Errorf("命中率应为 0.5，得到 %v", s.HitRatio)
+	}
+	if s.Keys < 0 {
+		t.Errorf("内存驱动应支持键数量统计，得到 %d", s.Keys)
+	}
+}
+
+func TestCache_StatsIncludesNamedStores(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Cache.Stores["pages"] = cfg.Cache.Stores["memory"]
+	c := cache.NewCache(ctx, cfg)
+
+	pages, err := c.Store("pages")
+	if err != nil {
+		t.Fatalf("切换存储失败: %v", err)
+	}
+	_ = pages.Set(ctx, "k", []byte("v"))
+
+	stats := c.Stats()
+	if len(stats) != 2 || stats[1].Name != "pages" || stats[1].Sets != 1 {
+		t.Fatalf("命名存储统计不正确: %+v", stats)
+	}
+	if stats[0].Sets != 0 {
+		t.Errorf("不同存储的统计应相互独立")
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	c := cache.NewCache(context.Background(), newTestConfig())
+	app := fiber.New()
+	app.Get("/admin/cache", cache.StatsHandler(c))
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/admin/cache", nil))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	var out struct {
+		Stores []cache.StoreStats `json:"stores"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(out.Stores) != 1 || out.Stores[0].Driver != "memory" {
+		t.Errorf("统计接口返回不正确: %s", body)
+	}
+}
@@ -21,6 +21,19 @@ type Redis struct {
 	ConnMaxIdleTime int    `mapstructure:"conn_max_idle_time"` // 连接最大空闲时间（分钟）
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`  // 连接最大生命周期（小时）
 	UseTLS          bool   `mapstructure:"use_tls"`            // 是否使用TLS加密连接
+
+	TLSCAFile             string `mapstructure:"tls_ca_file"`              // CA 证书文件路径，为空时使用系统根证书
+	TLSCertFile           string `mapstructure:"tls_cert_file"`            // 客户端证书文件路径（双向认证）
+	TLSKeyFile            string `mapstructure:"tls_key_file"`             // 客户端私钥文件路径（双向认证）
+	TLSServerName         string `mapstructure:"tls_server_name"`          // 校验服务端证书使用的主机名，为空时使用连接地址
+	TLSInsecureSkipVerify bool   `mapstructure:"tls_insecure_skip_verify"` // 跳过服务端证书校验，仅用于开发环境
+
+	Mode             string   `mapstructure:"mode"`              // 连接模式：standalone、sentinel、cluster，默认 standalone
+	MasterName       string   `mapstructure:"master_name"`       // Sentinel 模式下的主节点名称
+	SentinelAddrs    []string `mapstructure:"sentinel_addrs"`    // Sentinel 节点地址列表
+	SentinelUsername string   `mapstructure:"sentinel_username"` // Sentinel 用户名
+	SentinelPassword string   `mapstructure:"sentinel_password"` // Sentinel 密码
+	ClusterNodes     []string `mapstructure:"cluster_nodes"`     // Cluster 模式下的节点地址列表
 }
 
 type Database struct {
@@ -36,6 +49,20 @@ type Database struct {
 	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
 	ConnMaxIdleTime int    `mapstructure:"conn_max_idle_time"`
+
+	// DSN 完整的连接字符串或 URL，设置后忽略其他连接参数
+	DSN            string            `mapstructure:"dsn"`
+	Charset        string            `mapstructure:"charset"`         // MySQL 字符集，默认 utf8mb4
+	Timezone       string            `mapstructure:"timezone"`        // MySQL 的 loc 或 Postgres 的 timezone，MySQL 默认 Local
+	ConnectTimeout int               `mapstructure:"connect_timeout"` // 连接超时（秒）
+	Socket         string            `mapstructure:"socket"`          // Unix socket 路径，MySQL 为 socket 文件，Postgres 为 socket 所在目录
+	Params         map[string]string `mapstructure:"params"`          // 附加的驱动参数
+
+	TxRetries     int `mapstructure:"tx_retries"`     // 事务遇到死锁或序列化失败时的重试次数，默认不重试
+	SlowThreshold int `mapstructure:"slow_threshold"` // 慢查询阈值（毫秒），默认 200，小于 0 时不记录慢查询
+
+	// Replicas 只读副本，未填写的字段沿用主库配置
+	Replicas []Database `mapstructure:"replicas"`
 }
 
 type Config struct {
@@ -172,6 +199,7 @@ func InitConfig() {
 		v.SetDefault("redis.connections.redis.conn_max_idle_time", 30)
 		v.SetDefault("redis.connections.redis.conn_max_lifetime", 24)
 		v.SetDefault("redis.connections.redis.use_tls", false)
+		v.SetDefault("redis.connections.redis.mode", "standalone")
 		// 日志默认配置
 		v.SetDefault("log.level", "info")
 		v.SetDefault("log.format", "json")
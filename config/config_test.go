@@ -448,6 +448,9 @@ func TestConfigNestedStructs(t *testing.T) {
 	v.Set("redis.connections.cache_redis.conn_max_idle_time", 15)
 	v.Set("redis.connections.cache_redis.conn_max_lifetime", 12)
 	v.Set("redis.connections.cache_redis.use_tls", true)
+	v.Set("redis.connections.cache_redis.mode", "sentinel")
+	v.Set("redis.connections.cache_redis.master_name", "mymaster")
+	v.Set("redis.connections.cache_redis.sentinel_addrs", []string{"sentinel-1:26379", "sentinel-2:26379"})
 
 	// --- Filesystem 嵌套结构体 ---
 	v.Set("filesystem.default", "s3")
@@ -679,6 +682,15 @@ func TestConfigNestedStructs(t *testing.T) {
 		if !r.UseTLS {
 			t.Error("Redis.UseTLS: 期望 true")
 		}
+		if r.Mode != "sentinel" {
+			t.Errorf("Redis.Mode: 期望 %q, 得到 %q", "sentinel", r.Mode)
+		}
+		if r.MasterName != "mymaster" {
+			t.Errorf("Redis.MasterName: 期望 %q, 得到 %q", "mymaster", r.MasterName)
+		}
+		if len(r.SentinelAddrs) != 2 || r.SentinelAddrs[0] != "sentinel-1:26379" {
+			t.Errorf("Redis.SentinelAddrs: 期望 2 个地址, 得到 %v", r.SentinelAddrs)
+		}
 	})
 
 	// ============================
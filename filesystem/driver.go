@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"sync"
+
+	"github.com/gofiber/storage"
+	"github.com/gofiber/storage/s3/v2"
+)
+
+// DriverFactory 根据磁盘配置中的 options 创建存储驱动
+type DriverFactory func(options map[string]any) (storage.Storage, error)
+
+// drivers 驱动名称到 DriverFactory 的映射
+var drivers sync.Map
+
+func init() {
+	RegisterDriver("local", NewLocalStorage)
+	RegisterDriver("s3", NewS3StorageFromOptions)
+}
+
+// RegisterDriver 注册存储驱动，filesystem.disks 中 driver 为 name 的磁盘将使用该驱动创建
+// 用于接入 sftp、内存、加密等自定义驱动，同名驱动会覆盖之前的注册（包括内置的 local 与 s3）
+func RegisterDriver(name string, factory DriverFactory) {
+	drivers.Store(name, factory)
+}
+
+func lookupDriver(name string) (DriverFactory, bool) {
+	factory, ok := drivers.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return factory.(DriverFactory), true
+}
+
+// NewS3StorageFromOptions 根据磁盘 options 创建 S3 存储
+// 支持 access_key、secret_key、region、bucket、endpoint
+func NewS3StorageFromOptions(options map[string]any) (storage.Storage, error) {
+	option := func(key string) string {
+		value, _ := options[key].(string)
+		return value
+	}
+	return NewS3Storage(s3.Config{
+		Credentials: s3.Credentials{
+			AccessKey:       option("access_key"),
+			SecretAccessKey: option("secret_key"),
+		},
+		Region:   option("region"),
+		Bucket:   option("bucket"),
+		Endpoint: option("endpoint"),
+	}), nil
+}
@@ -0,0 +1,235 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/storage"
+	cmfstorage "github.com/wuwuseo/cmf/storage"
+)
+
+// FileInfo 文件信息
+type FileInfo = cmfstorage.FileInfo
+
+var (
+	// ErrNotFound 文件不存在或已过期
+	ErrNotFound = cmfstorage.ErrNotFound
+	// ErrInvalidKey 键不合法，如本地磁盘中越出根目录的路径
+	ErrInvalidKey = cmfstorage.ErrInvalidKey
+)
+
+// ErrListUnsupported 存储驱动不支持列举
+var ErrListUnsupported = fmt.Errorf("%w: storage adapter cannot list files", errors.ErrUnsupported)
+
+func notFound(key string) error {
+	return fmt.Errorf("%w: %s", ErrNotFound, key)
+}
+
+// Exists 判断文件是否存在
+func (f *Filesystem) Exists(key string) (bool, error) {
+	return exists(f.Adapter, key)
+}
+
+// Stat 读取文件信息，文件不存在时返回 ErrNotFound
+func (f *Filesystem) Stat(key string) (*FileInfo, error) {
+	return stat(f.Adapter, key)
+}
+
+// Size 读取文件大小，文件不存在时返回 ErrNotFound
+func (f *Filesystem) Size(key string) (int64, error) {
+	return size(f.Adapter, key)
+}
+
+// List 列举前缀下的文件，recursive 为 false 时只列举当前层级，子目录以 IsDir 返回
+// 键值型的通用适配器无法列举，返回 ErrListUnsupported
+func (f *Filesystem) List(prefix string, recursive bool) ([]FileInfo, error) {
+	return list(f.Adapter, prefix, recursive)
+}
+
+// GetReader 以流的方式读取文件，文件不存在时返回 ErrNotFound，调用方负责关闭
+func (f *Filesystem) GetReader(key string) (io.ReadCloser, error) {
+	return getReader(f.Adapter, key)
+}
+
+// Copy 复制文件，源文件不存在时返回 ErrNotFound
+func (f *Filesystem) Copy(src, dst string) error {
+	return copyFile(f.Adapter, src, dst)
+}
+
+// Move 移动文件，源文件不存在时返回 ErrNotFound
+func (f *Filesystem) Move(src, dst string) error {
+	return move(f.Adapter, src, dst)
+}
+
+// 以下函数优先调用驱动的原生实现，未实现时基于 Get/Set 回退
+
+func exists(adapter storage.Storage, key string) (bool, error) {
+	if a, ok := adapter.(cmfstorage.Existser); ok {
+		return a.Exists(key)
+	}
+	if a, ok := adapter.(cmfstorage.Statter); ok {
+		_, err := a.Stat(key)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	data, err := adapter.Get(key)
+	return data != nil, err
+}
+
+func stat(adapter storage.Storage, key string) (*FileInfo, error) {
+	if a, ok := adapter.(cmfstorage.Statter); ok {
+		return a.Stat(key)
+	}
+	data, err := adapter.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, notFound(key)
+	}
+	sum := md5.Sum(data)
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return &FileInfo{
+		Key:         key,
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		Checksum:    hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func size(adapter storage.Storage, key string) (int64, error) {
+	if a, ok := adapter.(cmfstorage.Sizer); ok {
+		return a.Size(key)
+	}
+	info, err := stat(adapter, key)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+func list(adapter storage.Storage, prefix string, recursive bool) ([]FileInfo, error) {
+	if a, ok := adapter.(cmfstorage.Lister); ok {
+		return a.List(prefix, recursive)
+	}
+	return nil, ErrListUnsupported
+}
+
+func getReader(adapter storage.Storage, key string) (io.ReadCloser, error) {
+	if a, ok := adapter.(cmfstorage.ReaderGetter); ok {
+		return a.GetReader(key)
+	}
+	data, err := adapter.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, notFound(key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// copyFile 回退实现不保留源文件的过期时间
+func copyFile(adapter storage.Storage, src, dst string) error {
+	if a, ok := adapter.(cmfstorage.Copier); ok {
+		return a.Copy(src, dst)
+	}
+	if src == dst {
+		_, err := stat(adapter, src)
+		return err
+	}
+	reader, err := getReader(adapter, src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return setReader(adapter, dst, reader, 0)
+}
+
+func move(adapter storage.Storage, src, dst string) error {
+	if a, ok := adapter.(cmfstorage.Mover); ok {
+		return a.Move(src, dst)
+	}
+	if src == dst {
+		_, err := stat(adapter, src)
+		return err
+	}
+	if err := copyFile(adapter, src, dst); err != nil {
+		return err
+	}
+	return adapter.Delete(src)
+}
+
+// setReader 驱动支持流式写入时直接写入，否则读取全部数据后调用 Set
+func setReader(adapter storage.Storage, key string, reader io.Reader, expiration time.Duration) error {
+	if a, ok := adapter.(interface {
+		SetReader(string, io.Reader, time.Duration) error
+	}); ok {
+		return a.SetReader(key, reader, expiration)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return adapter.Set(key, data, expiration)
+}
+
+// Exists 判断主存储中的文件是否存在
+func (d *DualStorage) Exists(key string) (bool, error) {
+	return exists(d.Primary, key)
+}
+
+// Stat 读取主存储中的文件信息
+func (d *DualStorage) Stat(key string) (*FileInfo, error) {
+	return stat(d.Primary, key)
+}
+
+// Size 读取主存储中的文件大小
+func (d *DualStorage) Size(key string) (int64, error) {
+	return size(d.Primary, key)
+}
+
+// List 列举主存储中的文件
+func (d *DualStorage) List(prefix string, recursive bool) ([]FileInfo, error) {
+	return list(d.Primary, prefix, recursive)
+}
+
+// GetReader 从主存储流式读取文件
+func (d *DualStorage) GetReader(key string) (io.ReadCloser, error) {
+	return getReader(d.Primary, key)
+}
+
+// Copy 在主存储和本地存储中分别复制，本地缺少源文件时忽略
+func (d *DualStorage) Copy(src, dst string) error {
+	if err := copyFile(d.Primary, src, dst); err != nil {
+		return err
+	}
+	if err := copyFile(d.Local, src, dst); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Move 在主存储和本地存储中分别移动，本地缺少源文件时忽略
+func (d *DualStorage) Move(src, dst string) error {
+	if err := move(d.Primary, src, dst); err != nil {
+		return err
+	}
+	if err := move(d.Local, src, dst); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
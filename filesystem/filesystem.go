@@ -2,13 +2,13 @@ package filesystem
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
 	"time"
 
 	"github.com/gofiber/storage"
-	"github.com/gofiber/storage/s3/v2"
 	"github.com/wuwuseo/cmf/config"
 	local "github.com/wuwuseo/cmf/storage/local"
 )
@@ -22,9 +22,9 @@ type DualStorage struct {
 	Local   storage.Storage // 本地存储
 }
 
-// Close implements storage.Storage.
+// Close 关闭主存储和本地存储
 func (d *DualStorage) Close() error {
-	return d.Primary.Close()
+	return errors.Join(d.Primary.Close(), d.Local.Close())
 }
 
 // Reset implements storage.Storage.
@@ -81,9 +81,24 @@ func (d *DualStorage) SetReader(key string, reader io.Reader, exp time.Duration)
 	return d.Local.Set(key, data, exp)
 }
 
+// ErrClosed 文件系统已关闭
+var ErrClosed = errors.New("filesystem closed")
+
+// Filesystem 文件系统，对应 filesystem.disks 中的一个磁盘
 type Filesystem struct {
 	Config  config.Config
 	Adapter storage.Storage
+
+	name     string
+	disks    *diskSet
+	initOnce sync.Once
+}
+
+// diskSet 同一配置下按名称共享的磁盘实例
+type diskSet struct {
+	mu     sync.Mutex
+	disks  map[string]*Filesystem
+	closed bool
 }
 
 func (f *Filesystem) Get(key string) ([]byte, error) {
@@ -102,21 +117,71 @@ func (f *Filesystem) Delete(key string) error {
 // 如果底层适配器支持流式写入（SetReader方法），则直接使用流式写入
 // 否则回退到读取所有数据到内存后调用Set方法
 func (f *Filesystem) SetReader(key string, reader io.Reader, expiration time.Duration) error {
-	if adapter, ok := f.Adapter.(interface {
-		SetReader(string, io.Reader, time.Duration) error
-	}); ok {
-		return adapter.SetReader(key, reader, expiration)
+	return setReader(f.Adapter, key, reader, expiration)
+}
+
+// Name 返回磁盘名称
+func (f *Filesystem) Name() string {
+	f.diskSet()
+	return f.name
+}
+
+// diskSet 返回共享的磁盘集合，直接构造的 Filesystem 在首次使用时创建
+func (f *Filesystem) diskSet() *diskSet {
+	f.initOnce.Do(func() {
+		if f.name == "" {
+			f.name = defaultDiskName(&f.Config)
+		}
+		if f.disks == nil {
+			f.disks = &diskSet{disks: map[string]*Filesystem{f.name: f}}
+		}
+	})
+	return f.disks
+}
+
+// Disk 切换到指定名称的磁盘，与 cache.Cache.Store 一致，同名磁盘只创建一次并在各实例间共享
+func (f *Filesystem) Disk(name string) (*Filesystem, error) {
+	set := f.diskSet()
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.closed {
+		return nil, ErrClosed
+	}
+	if disk, ok := set.disks[name]; ok {
+		return disk, nil
 	}
 
-	data, err := io.ReadAll(reader)
+	adapter, err := openDisk(&f.Config, name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	disk := &Filesystem{Config: f.Config, Adapter: adapter, name: name, disks: set}
+	set.disks[name] = disk
+	return disk, nil
+}
+
+// Close 关闭当前文件系统以及通过 Disk 打开的全部磁盘，重复调用不会报错
+// 注册为 filesystem 服务后会在应用关闭时自动调用
+func (f *Filesystem) Close() error {
+	set := f.diskSet()
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.closed {
+		return nil
+	}
+	set.closed = true
+
+	var errs []error
+	for name, disk := range set.disks {
+		if err := disk.Adapter.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("关闭磁盘 '%s' 失败: %w", name, err))
+		}
 	}
-	return f.Adapter.Set(key, data, expiration)
+	return errors.Join(errs...)
 }
 
 // NewFilesystem 创建一个新的文件系统实例
-// 此方法保持向后兼容性，直接使用提供的适配器
+// 此方法保持向后兼容性，直接使用提供的适配器作为默认磁盘
 func NewFilesystem(adapter storage.Storage, config config.Config) *Filesystem {
 	return &Filesystem{
 		Config:  config,
@@ -126,6 +191,7 @@ func NewFilesystem(adapter storage.Storage, config config.Config) *Filesystem {
 
 // NewStorageDriver 根据配置创建存储驱动
 // 使用sync.Map确保每个磁盘配置只创建一个实例（单例模式）
+// 由调用方管理生命周期，需要随应用关闭的磁盘请使用 Filesystem.Disk
 func NewStorageDriver(cfg *config.Config, diskName string) (storage.Storage, error) {
 	// 构建磁盘配置的唯一标识符
 	diskKey := fmt.Sprintf("%s_%s", cfg.App.Name, diskName)
@@ -135,6 +201,21 @@ func NewStorageDriver(cfg *config.Config, diskName string) (storage.Storage, err
 		return instance.(storage.Storage), nil
 	}
 
+	adapter, err := openDisk(cfg, diskName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 将新创建的实例存储到sync.Map中，并发创建时保留先存入的实例
+	actual, loaded := driverInstances.LoadOrStore(diskKey, adapter)
+	if loaded {
+		adapter.Close()
+	}
+	return actual.(storage.Storage), nil
+}
+
+// openDisk 根据磁盘配置创建新的存储驱动实例
+func openDisk(cfg *config.Config, diskName string) (storage.Storage, error) {
 	// 获取磁盘配置
 	disk, exists := cfg.Filesystem.Disks[diskName]
 	if !exists {
@@ -147,39 +228,11 @@ func NewStorageDriver(cfg *config.Config, diskName string) (storage.Storage, err
 		options = make(map[string]any)
 	}
 
-	var adapter storage.Storage
-	var err error
-
-	switch disk.Driver {
-	case "local":
-		// 获取本地存储的配置选项
-		// 获取根路径配置，默认为./data/storage
-		adapter, err = NewLocalStorage(options)
-
-	case "s3":
-		// 实现S3存储驱动
-		adapter, err = s3.New(s3.Config{
-			Credentials: s3.Credentials{
-				AccessKey:       options["access_key"].(string),
-				SecretAccessKey: options["secret_key"].(string),
-			},
-			Region:   options["region"].(string),
-			Bucket:   options["bucket"].(string),
-			Endpoint: options["endpoint"].(string),
-		}), nil
-
-	default:
+	factory, ok := lookupDriver(disk.Driver)
+	if !ok {
 		return nil, fmt.Errorf("不支持的存储驱动类型: %s", disk.Driver)
 	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	// 将新创建的实例存储到sync.Map中
-	driverInstances.Store(diskKey, adapter)
-
-	return adapter, nil
+	return factory(options)
 }
 
 func NewLocalStorage(options map[string]any) (storage.Storage, error) {
@@ -188,20 +241,36 @@ func NewLocalStorage(options map[string]any) (storage.Storage, error) {
 		rootPath = "./data/storage"
 	}
 
+	// gc_interval 为后台清理过期文件的间隔，如 10m，未配置时不启动
+	var gcInterval time.Duration
+	if value, _ := options["gc_interval"].(string); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("本地磁盘的 gc_interval 无效: %w", err)
+		}
+		gcInterval = interval
+	}
+
 	// 创建本地存储实例
-	return local.New(local.Config{BasePath: rootPath}), nil
+	return local.New(local.Config{BasePath: rootPath, GCInterval: gcInterval}), nil
+}
+
+// defaultDiskName 返回默认磁盘名称，未配置时使用 local
+func defaultDiskName(cfg *config.Config) string {
+	if cfg.Filesystem.Default == "" {
+		return "local"
+	}
+	return cfg.Filesystem.Default
 }
 
 // NewFilesystemFromConfig 根据配置创建文件系统实例
+// 磁盘由返回的实例管理，Close 时一并关闭
 func NewFilesystemFromConfig(cfg *config.Config) (*Filesystem, error) {
 	// 获取默认磁盘名称
-	defaultDisk := cfg.Filesystem.Default
-	if defaultDisk == "" {
-		defaultDisk = "local" // 默认使用本地存储
-	}
+	defaultDisk := defaultDiskName(cfg)
 
 	// 创建存储驱动
-	adapter, err := NewStorageDriver(cfg, defaultDisk)
+	adapter, err := openDisk(cfg, defaultDisk)
 	if err != nil {
 		return nil, fmt.Errorf("创建存储驱动失败: %w", err)
 	}
@@ -209,8 +278,9 @@ func NewFilesystemFromConfig(cfg *config.Config) (*Filesystem, error) {
 	// 如果启用了IsAndLocal且默认磁盘不是local，则创建复合存储适配器
 	if cfg.Filesystem.IsAndLocal && defaultDisk != "local" {
 		// 创建本地存储驱动
-		localAdapter, err := NewStorageDriver(cfg, "local")
+		localAdapter, err := openDisk(cfg, "local")
 		if err != nil {
+			adapter.Close()
 			return nil, fmt.Errorf("创建本地存储驱动失败: %w", err)
 		}
 
@@ -225,5 +295,6 @@ func NewFilesystemFromConfig(cfg *config.Config) (*Filesystem, error) {
 	return &Filesystem{
 		Config:  *cfg,
 		Adapter: adapter,
+		name:    defaultDisk,
 	}, nil
 }
@@ -2,12 +2,21 @@ package filesystem_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/storage"
+	"github.com/gofiber/storage/s3/v2"
 	"github.com/wuwuseo/cmf/config"
 	"github.com/wuwuseo/cmf/filesystem"
 	local "github.com/wuwuseo/cmf/storage/local"
@@ -352,6 +361,18 @@ func TestNewLocalStorage_WithoutRootOption(t *testing.T) {
 	}
 }
 
+func TestNewLocalStorage_GCInterval(t *testing.T) {
+	store, err := filesystem.NewLocalStorage(map[string]any{"root": t.TempDir(), "gc_interval": "10m"})
+	if err != nil {
+		t.Fatalf("NewLocalStorage 失败: %v", err)
+	}
+	store.Close()
+
+	if _, err := filesystem.NewLocalStorage(map[string]any{"root": t.TempDir(), "gc_interval": "soon"}); err == nil {
+		t.Fatal("无效的 gc_interval 应返回错误")
+	}
+}
+
 // =============================================================================
 // NewStorageDriver 测试
 // =============================================================================
@@ -614,3 +635,431 @@ func TestFilesystem_SetReader_NoSetReaderAdapter(t *testing.T) {
 }
 
 var _ io.Reader = (*bytes.Reader)(nil)
+
+// =============================================================================
+// Exists / Stat / List / Copy / Move / GetReader 测试
+// =============================================================================
+
+func TestFilesystem_FileOperations_Local(t *testing.T) {
+	fs := filesystem.NewFilesystem(newLocalStorage(t), config.Config{})
+	data := []byte("local file")
+	if err := fs.Set("a/b.txt", data, 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	if ok, err := fs.Exists("a/b.txt"); err != nil || !ok {
+		t.Fatalf("文件应存在: %v, %v", ok, err)
+	}
+	info, err := fs.Stat("a/b.txt")
+	if err != nil || info.Size != int64(len(data)) || info.Checksum == "" {
+		t.Fatalf("Stat 结果不正确: %+v, %v", info, err)
+	}
+	if err := fs.Copy("a/b.txt", "a/c.txt"); err != nil {
+		t.Fatalf("Copy 失败: %v", err)
+	}
+	if err := fs.Move("a/c.txt", "d.txt"); err != nil {
+		t.Fatalf("Move 失败: %v", err)
+	}
+	files, err := fs.List("", true)
+	if err != nil || len(files) != 2 || files[0].Key != "a/b.txt" || files[1].Key != "d.txt" {
+		t.Fatalf("List 结果不正确: %+v, %v", files, err)
+	}
+	if size, err := fs.Size("d.txt"); err != nil || size != int64(len(data)) {
+		t.Fatalf("Size 不正确: %d, %v", size, err)
+	}
+}
+
+func TestFilesystem_FileOperations_Fallback(t *testing.T) {
+	mock := &mockStorageNoSetReader{data: make(map[string][]byte)}
+	fs := filesystem.NewFilesystem(mock, config.Config{})
+	data := []byte(`{"ok":true}`)
+	if err := fs.Set("data.json", data, 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	if ok, _ := fs.Exists("data.json"); !ok {
+		t.Fatal("文件应存在")
+	}
+	if ok, _ := fs.Exists("missing"); ok {
+		t.Fatal("文件不应存在")
+	}
+	info, err := fs.Stat("data.json")
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	if info.Size != int64(len(data)) || info.ContentType != "application/json" || len(info.Checksum) != 32 {
+		t.Fatalf("回退的 Stat 结果不正确: %+v", info)
+	}
+	if _, err := fs.Stat("missing"); !errors.Is(err, filesystem.ErrNotFound) {
+		t.Fatalf("不存在的文件应返回 ErrNotFound: %v", err)
+	}
+
+	reader, err := fs.GetReader("data.json")
+	if err != nil {
+		t.Fatalf("GetReader 失败: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, data) {
+		t.Fatalf("读取的内容不正确: %q", got)
+	}
+
+	if err := fs.Move("data.json", "moved.json"); err != nil {
+		t.Fatalf("Move 失败: %v", err)
+	}
+	if _, ok := mock.data["data.json"]; ok {
+		t.Fatal("移动后源文件不应存在")
+	}
+	if !bytes.Equal(mock.data["moved.json"], data) {
+		t.Fatalf("移动后的内容不正确: %q", mock.data["moved.json"])
+	}
+	if err := fs.Copy("missing", "x"); !errors.Is(err, filesystem.ErrNotFound) {
+		t.Fatalf("复制不存在的文件应返回 ErrNotFound: %v", err)
+	}
+	if _, err := fs.List("", true); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("通用适配器列举应返回 ErrUnsupported: %v", err)
+	}
+}
+
+func TestDualStorage_CopyMove(t *testing.T) {
+	primary := newLocalStorage(t)
+	localStore := newLocalStorage(t)
+	dual := &filesystem.DualStorage{Primary: primary, Local: localStore}
+	if err := dual.Set("f.txt", []byte("dual"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	// 只存在于主存储的文件，本地缺失时忽略
+	if err := primary.Set("only.txt", []byte("primary"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	if err := dual.Copy("f.txt", "g.txt"); err != nil {
+		t.Fatalf("Copy 失败: %v", err)
+	}
+	if err := dual.Move("only.txt", "moved.txt"); err != nil {
+		t.Fatalf("本地缺少源文件时 Move 不应失败: %v", err)
+	}
+	for _, store := range []*local.Storage{primary, localStore} {
+		if ok, _ := store.Exists("g.txt"); !ok {
+			t.Fatalf("%s 中应存在复制的文件", store.BasePath)
+		}
+	}
+	if ok, _ := dual.Exists("moved.txt"); !ok {
+		t.Fatal("主存储中应存在移动后的文件")
+	}
+}
+
+func TestS3Storage_Stat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/media/img/a.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "42")
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("ETag", `"abc123"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+	}))
+	defer server.Close()
+
+	store := filesystem.NewS3Storage(s3.Config{
+		Bucket:      "media",
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Credentials: s3.Credentials{AccessKey: "key", SecretAccessKey: "secret"},
+		MaxAttempts: 1,
+	})
+	info, err := store.Stat("img/a.png")
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	if info.Size != 42 || info.ContentType != "image/png" || info.Checksum != "abc123" || info.ModTime.Year() != 2006 {
+		t.Fatalf("Stat 结果不正确: %+v", info)
+	}
+	if ok, err := store.Exists("missing.png"); err != nil || ok {
+		t.Fatalf("不存在的对象应返回 false: %v, %v", ok, err)
+	}
+}
+
+// =============================================================================
+// 多磁盘与驱动注册测试
+// =============================================================================
+
+// closingStorage 记录 Close 调用的内存存储
+type closingStorage struct {
+	mockStorageNoSetReader
+	closed int
+}
+
+func (c *closingStorage) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestFilesystem_Disk(t *testing.T) {
+	var created []*closingStorage
+	filesystem.RegisterDriver("memory_test", func(options map[string]any) (storage.Storage, error) {
+		if options["fail"] == true {
+			return nil, errors.New("open failed")
+		}
+		store := &closingStorage{mockStorageNoSetReader: mockStorageNoSetReader{data: make(map[string][]byte)}}
+		created = append(created, store)
+		return store, nil
+	})
+
+	cfg := newTestFilesystemConfig(t, t.TempDir())
+	cfg.Filesystem.Disks["memory"] = struct {
+		Driver  string `mapstructure:"driver"`
+		Options any    `mapstructure:"options"`
+	}{Driver: "memory_test"}
+	cfg.Filesystem.Disks["broken"] = struct {
+		Driver  string `mapstructure:"driver"`
+		Options any    `mapstructure:"options"`
+	}{Driver: "memory_test", Options: map[string]any{"fail": true}}
+
+	fs, err := filesystem.NewFilesystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFilesystemFromConfig 失败: %v", err)
+	}
+	if fs.Name() != "local" {
+		t.Fatalf("默认磁盘名称不正确: %s", fs.Name())
+	}
+	if disk, _ := fs.Disk("local"); disk != fs {
+		t.Fatal("Disk 默认磁盘名称应返回自身")
+	}
+
+	memory, err := fs.Disk("memory")
+	if err != nil {
+		t.Fatalf("Disk 失败: %v", err)
+	}
+	if err := memory.Set("k", []byte("v"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	if ok, _ := fs.Exists("k"); ok {
+		t.Fatal("不同磁盘之间的数据不应共享")
+	}
+	// 从任一磁盘切换都返回同一实例
+	again, _ := memory.Disk("memory")
+	if again != memory || len(created) != 1 {
+		t.Fatal("同名磁盘应只创建一次")
+	}
+	if back, _ := memory.Disk("local"); back != fs {
+		t.Fatal("应能从其他磁盘切换回默认磁盘")
+	}
+
+	if _, err := fs.Disk("broken"); err == nil {
+		t.Fatal("驱动创建失败时应返回错误")
+	}
+	if _, err := fs.Disk("missing"); err == nil {
+		t.Fatal("不存在的磁盘应返回错误")
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+	if created[0].closed != 1 {
+		t.Fatalf("Close 应关闭通过 Disk 打开的磁盘，实际关闭 %d 次", created[0].closed)
+	}
+	if err := fs.Close(); err != nil || created[0].closed != 1 {
+		t.Fatal("重复 Close 不应再次关闭磁盘")
+	}
+	if _, err := fs.Disk("memory"); !errors.Is(err, filesystem.ErrClosed) {
+		t.Fatalf("关闭后 Disk 应返回 ErrClosed: %v", err)
+	}
+}
+
+func TestNewFilesystem_Disk(t *testing.T) {
+	cfg := newTestFilesystemConfig(t, t.TempDir())
+	adapter := newLocalStorage(t)
+	fs := filesystem.NewFilesystem(adapter, *cfg)
+
+	// 直接构造的实例以提供的适配器作为默认磁盘
+	if disk, err := fs.Disk("local"); err != nil || disk != fs {
+		t.Fatalf("默认磁盘应返回自身: %v", err)
+	}
+	if fs.Name() != "local" {
+		t.Fatalf("默认磁盘名称不正确: %s", fs.Name())
+	}
+}
+
+// =============================================================================
+// 临时链接测试
+// =============================================================================
+
+func newSignedFilesystem(t *testing.T) *filesystem.Filesystem {
+	t.Helper()
+	cfg := newTestFilesystemConfig(t, t.TempDir())
+	cfg.App.Secret = "test-secret"
+	cfg.Filesystem.Disks["local"].Options.(map[string]any)["url"] = "https://example.com/files/"
+	fs, err := filesystem.NewFilesystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFilesystemFromConfig 失败: %v", err)
+	}
+	return fs
+}
+
+func TestFilesystem_TemporaryURL_Local(t *testing.T) {
+	fs := newSignedFilesystem(t)
+	data := []byte("private report")
+	if err := fs.Set("private/报告 1.pdf", data, 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	app := fiber.New()
+	if err := fs.RegisterRoutes(app); err != nil {
+		t.Fatalf("RegisterRoutes 失败: %v", err)
+	}
+	get := func(rawURL string) *http.Response {
+		t.Helper()
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("链接无效: %v", err)
+		}
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		return resp
+	}
+
+	link, err := fs.TemporaryURL("private/报告 1.pdf", time.Minute, filesystem.URLOptions{Filename: "report.pdf"})
+	if err != nil {
+		t.Fatalf("TemporaryURL 失败: %v", err)
+	}
+	if !strings.HasPrefix(link, "https://example.com/files/private/") {
+		t.Fatalf("链接前缀不正确: %s", link)
+	}
+	resp := get(link)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("期望返回文件内容，实际 %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Type") != "application/pdf" || resp.Header.Get("Content-Disposition") != `attachment; filename=report.pdf` {
+		t.Fatalf("响应头不正确: %v", resp.Header)
+	}
+
+	// 篡改下载参数或签名
+	tampered := strings.Replace(link, "filename=report.pdf", "filename=other.pdf", 1)
+	if resp := get(tampered); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("篡改参数后应返回 403，实际 %d", resp.StatusCode)
+	}
+	if resp := get(link[:len(link)-4] + "0000"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("篡改签名后应返回 403，实际 %d", resp.StatusCode)
+	}
+	// 其他文件不能复用签名
+	if err := fs.Set("private/other.pdf", data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if resp := get(strings.Replace(link, "%E6%8A%A5%E5%91%8A%201.pdf", "other.pdf", 1)); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("其他文件复用签名应返回 403，实际 %d", resp.StatusCode)
+	}
+
+	// 已签名但文件不存在
+	missing, _ := fs.TemporaryURL("private/missing.pdf", time.Minute, filesystem.URLOptions{})
+	if resp := get(missing); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("文件不存在时应返回 404，实际 %d", resp.StatusCode)
+	}
+}
+
+func TestFilesystem_TemporaryURL_FieldBoundaries(t *testing.T) {
+	fs := newSignedFilesystem(t)
+	if err := fs.Set("a.txt", []byte("a"), 0); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Get("/files/*", fs.TemporaryURLHandler())
+
+	// 文件名中换行之后的部分不能被挪到 content_type 字段复用签名
+	link, err := fs.TemporaryURL("a.txt", time.Minute, filesystem.URLOptions{Filename: "x\ny", ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(link)
+	query := u.Query()
+	query.Set("filename", "x")
+	query.Set("content_type", "y\ntext/plain")
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, u.Path+"?"+query.Encode(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("字段边界改变后签名应无效，实际 %d", resp.StatusCode)
+	}
+}
+
+func TestFilesystem_TemporaryURL_Expired(t *testing.T) {
+	fs := newSignedFilesystem(t)
+	if err := fs.Set("a.txt", []byte("a"), 0); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Get("/files/*", fs.TemporaryURLHandler())
+
+	link, _ := fs.TemporaryURL("a.txt", time.Minute, filesystem.URLOptions{})
+	u, _ := url.Parse(link)
+	query := u.Query()
+	query.Set("expires", strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, u.Path+"?"+query.Encode(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("过期链接应返回 403，实际 %d", resp.StatusCode)
+	}
+
+	if _, err := fs.TemporaryURL("a.txt", 0, filesystem.URLOptions{}); err == nil {
+		t.Fatal("有效期为 0 时应返回错误")
+	}
+}
+
+func TestFilesystem_RegisterRoutes_RootURL(t *testing.T) {
+	cfg := newTestFilesystemConfig(t, t.TempDir())
+	cfg.App.Secret = "test-secret"
+	cfg.Filesystem.Disks["local"].Options.(map[string]any)["url"] = "https://cdn.example.com/"
+	fs, err := filesystem.NewFilesystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewFilesystemFromConfig 失败: %v", err)
+	}
+
+	app := fiber.New()
+	if err := fs.RegisterRoutes(app); err == nil {
+		t.Fatal("url 没有路径部分时应返回错误")
+	}
+	app.Get("/", func(c fiber.Ctx) error { return c.SendString("home") })
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("不应注册覆盖所有请求的路由，实际 %d", resp.StatusCode)
+	}
+}
+
+func TestFilesystem_TemporaryURL_Unsupported(t *testing.T) {
+	fs := filesystem.NewFilesystem(newLocalStorage(t), *newTestFilesystemConfig(t, t.TempDir()))
+	if _, err := fs.TemporaryURL("a.txt", time.Minute, filesystem.URLOptions{}); !errors.Is(err, filesystem.ErrTemporaryURLUnsupported) {
+		t.Fatalf("未配置 url 的本地磁盘应返回 ErrTemporaryURLUnsupported: %v", err)
+	}
+}
+
+func TestS3Storage_TemporaryURL(t *testing.T) {
+	store := filesystem.NewS3Storage(s3.Config{
+		Bucket:      "media",
+		Endpoint:    "https://s3.example.com",
+		Region:      "us-east-1",
+		Credentials: s3.Credentials{AccessKey: "key", SecretAccessKey: "secret"},
+	})
+	fs := filesystem.NewFilesystem(store, config.Config{})
+	link, err := fs.TemporaryURL("img/a.png", 5*time.Minute, filesystem.URLOptions{Filename: "a.png", ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("TemporaryURL 失败: %v", err)
+	}
+	u, _ := url.Parse(link)
+	query := u.Query()
+	if u.Path != "/media/img/a.png" || query.Get("X-Amz-Expires") != "300" || query.Get("X-Amz-Signature") == "" {
+		t.Fatalf("预签名链接不正确: %s", link)
+	}
+	if query.Get("response-content-disposition") != "attachment; filename=a.png" || query.Get("response-content-type") != "image/png" {
+		t.Fatalf("预签名链接缺少响应头参数: %s", link)
+	}
+}
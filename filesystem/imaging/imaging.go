@@ -0,0 +1,295 @@
+// Package imaging 为文件系统中的图片生成缩略图等尺寸变体
+//
+// 使用标准库解码 JPEG、PNG、GIF（取第一帧），按预设的尺寸与缩放方式生成变体，
+// 变体与原图存放在同一磁盘的同一目录下，如 2024/05/01/photo.jpg 的 thumb 变体为 2024/05/01/photo@thumb.jpg。
+// 变体可以在上传时生成，也可以在首次请求时由 Handler 按需生成。
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/wuwuseo/cmf/filesystem"
+	"github.com/wuwuseo/cmf/filesystem/upload"
+)
+
+const (
+	defaultQuality     = 85
+	defaultMaxPixels   = 40_000_000
+	defaultCacheMaxAge = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrUnknownVariant 未配置的变体名称
+	ErrUnknownVariant = errors.New("unknown image variant")
+	// ErrUnsupportedImage 无法解码的图片格式
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	// ErrImageTooLarge 图片像素数超过限制
+	ErrImageTooLarge = errors.New("image dimensions too large")
+	// ErrVariantSource 键本身是变体，不能再生成变体
+	ErrVariantSource = errors.New("key is already an image variant")
+)
+
+// Preset 变体预设
+type Preset struct {
+	Width   int  // 目标宽度，Fit 方式下为 0 表示不限制
+	Height  int  // 目标高度，Fit 方式下为 0 表示不限制
+	Mode    Mode // 缩放方式，默认 Fit
+	Quality int  // JPEG 质量 1-100，默认 85
+}
+
+// Config 图片处理配置
+type Config struct {
+	// Presets 变体名称到预设的映射，例如 {"thumb": {200, 200, Crop, 0}, "medium": {Width: 800}}
+	Presets map[string]Preset
+	// MaxPixels 允许处理的最大像素数，防止解压炸弹，默认 4000 万
+	MaxPixels int
+	// CacheMaxAge Handler 响应的缓存时间，默认 30 天
+	CacheMaxAge time.Duration
+	// Public 为 true 时 Handler 允许匿名访问磁盘上的任意图片，响应使用 Cache-Control: public
+	// 私有磁盘不要开启，应配置 Authorize
+	Public bool
+	// Authorize Handler 在生成与输出变体前调用，返回错误时直接作为响应错误，如 fiber.ErrForbidden
+	// 配置后响应使用 Cache-Control: private；Public 为 false 且未配置 Authorize 时 Handler 拒绝所有请求
+	Authorize func(c fiber.Ctx, key string) error
+}
+
+// Processor 图片变体处理器
+type Processor struct {
+	fs     *filesystem.Filesystem
+	config Config
+}
+
+// New 创建处理器，变体写入 fs 对应的磁盘
+func New(fs *filesystem.Filesystem, config Config) *Processor {
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = defaultMaxPixels
+	}
+	if config.CacheMaxAge <= 0 {
+		config.CacheMaxAge = defaultCacheMaxAge
+	}
+	return &Processor{fs: fs, config: config}
+}
+
+// Variants 返回已配置的变体名称，按名称排序
+func (p *Processor) Variants() []string {
+	names := make([]string, 0, len(p.config.Presets))
+	for name := range p.config.Presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// VariantKey 返回变体的存储路径，GIF 原图的变体以 PNG 保存
+func (p *Processor) VariantKey(key, name string) string {
+	ext := path.Ext(key)
+	stem := strings.TrimSuffix(key, ext)
+	if strings.EqualFold(ext, ".gif") {
+		ext = ".png"
+	}
+	return stem + "@" + name + ext
+}
+
+// IsVariant 判断键是否为已配置预设的变体，即去掉扩展名后以 @<预设名> 结尾
+func (p *Processor) IsVariant(key string) bool {
+	stem := strings.TrimSuffix(key, path.Ext(key))
+	for name := range p.config.Presets {
+		if strings.HasSuffix(stem, "@"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate 为原图生成变体，names 为空时生成全部变体，已存在的变体会被覆盖
+// 返回变体名称到存储路径的映射；key 本身是变体时返回 ErrVariantSource
+func (p *Processor) Generate(key string, names ...string) (map[string]string, error) {
+	if p.IsVariant(key) {
+		return nil, fmt.Errorf("%w: %s", ErrVariantSource, key)
+	}
+	if len(names) == 0 {
+		names = p.Variants()
+	}
+	for _, name := range names {
+		if _, ok := p.config.Presets[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVariant, name)
+		}
+	}
+
+	src, err := p.decode(key)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string, len(names))
+	for _, name := range names {
+		preset := p.config.Presets[name]
+		variantKey := p.VariantKey(key, name)
+		data, err := encode(Resize(src, preset.Width, preset.Height, preset.Mode), variantKey, preset.Quality)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.fs.SetReader(variantKey, bytes.NewReader(data), 0); err != nil {
+			return nil, err
+		}
+		keys[name] = variantKey
+	}
+	return keys, nil
+}
+
+// Variant 返回变体的存储路径，变体不存在时按需生成
+func (p *Processor) Variant(key, name string) (string, error) {
+	if _, ok := p.config.Presets[name]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownVariant, name)
+	}
+	if p.IsVariant(key) {
+		return "", fmt.Errorf("%w: %s", ErrVariantSource, key)
+	}
+	variantKey := p.VariantKey(key, name)
+	exists, err := p.fs.Exists(variantKey)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return variantKey, nil
+	}
+	if _, err := p.Generate(key, name); err != nil {
+		return "", err
+	}
+	return variantKey, nil
+}
+
+// Delete 删除原图及其全部变体
+func (p *Processor) Delete(key string) error {
+	var errs []error
+	for _, name := range p.Variants() {
+		if err := p.fs.Delete(p.VariantKey(key, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := p.fs.Delete(key); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// OnUpload 用作 upload.Config.OnSave，在图片上传后生成全部变体，非图片文件直接跳过
+func (p *Processor) OnUpload(file *upload.File) error {
+	switch file.ContentType {
+	case "image/jpeg", "image/png", "image/gif":
+		_, err := p.Generate(file.Key)
+		return err
+	}
+	return nil
+}
+
+// decode 读取并解码原图，解码前检查像素数
+func (p *Processor) decode(key string) (image.Image, error) {
+	data, err := p.fs.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: %s", filesystem.ErrNotFound, key)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, key)
+	}
+	if cfg.Width*cfg.Height > p.config.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	return img, nil
+}
+
+// encode 按变体路径的扩展名编码，JPEG 之外一律使用 PNG
+func encode(img image.Image, key string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg":
+		if quality <= 0 || quality > 100 {
+			quality = defaultQuality
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+	default:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Handler 返回按需生成并输出变体的 Fiber 处理函数，路由需要包含 :variant 参数并以 /* 结尾
+// 需要开启 Public 或配置 Authorize，否则所有请求返回 403；请求变体的变体返回 404
+//
+//	app.Get("/images/:variant/*", processor.Handler())
+func (p *Processor) Handler() fiber.Handler {
+	cacheControl := "private, max-age="
+	if p.config.Public {
+		cacheControl = "public, max-age="
+	}
+	cacheControl += strconv.Itoa(int(p.config.CacheMaxAge.Seconds()))
+
+	return func(c fiber.Ctx) error {
+		key := c.Params("*")
+		if !c.App().Config().UnescapePath {
+			var err error
+			if key, err = url.PathUnescape(key); err != nil {
+				return fiber.ErrBadRequest
+			}
+		}
+		if p.config.Authorize != nil {
+			if err := p.config.Authorize(c, key); err != nil {
+				return err
+			}
+		} else if !p.config.Public {
+			return fiber.ErrForbidden
+		}
+
+		variantKey, err := p.Variant(key, c.Params("variant"))
+		switch {
+		case errors.Is(err, ErrUnknownVariant), errors.Is(err, ErrVariantSource),
+			errors.Is(err, filesystem.ErrNotFound), errors.Is(err, filesystem.ErrInvalidKey):
+			return fiber.ErrNotFound
+		case errors.Is(err, ErrUnsupportedImage):
+			return fiber.ErrUnsupportedMediaType
+		case errors.Is(err, ErrImageTooLarge):
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		case err != nil:
+			return err
+		}
+
+		size, err := p.fs.Size(variantKey)
+		if err != nil {
+			return err
+		}
+		reader, err := p.fs.GetReader(variantKey)
+		if err != nil {
+			return err
+		}
+		if contentType := mime.TypeByExtension(path.Ext(variantKey)); contentType != "" {
+			c.Set(fiber.HeaderContentType, contentType)
+		}
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		return c.SendStream(reader, int(size))
+	}
+}
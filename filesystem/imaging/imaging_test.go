@@ -0,0 +1,312 @@
+package imaging_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/filesystem"
+	"github.com/wuwuseo/cmf/filesystem/imaging"
+	"github.com/wuwuseo/cmf/filesystem/upload"
+	local "github.com/wuwuseo/cmf/storage/local"
+)
+
+var presets = map[string]imaging.Preset{
+	"thumb":  {Width: 20, Height: 20, Mode: imaging.Crop},
+	"medium": {Width: 50},
+}
+
+func newTestFilesystem(t *testing.T) *filesystem.Filesystem {
+	t.Helper()
+	return filesystem.NewFilesystem(local.New(local.Config{BasePath: t.TempDir()}), config.Config{})
+}
+
+// testImage 生成左半红色、右半蓝色的图片
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeSize(t *testing.T, fs *filesystem.Filesystem, key string) (int, int, string) {
+	t.Helper()
+	data, err := fs.Get(key)
+	if err != nil || data == nil {
+		t.Fatalf("读取 %s 失败: %v", key, err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("解码 %s 失败: %v", key, err)
+	}
+	return cfg.Width, cfg.Height, format
+}
+
+func TestResize(t *testing.T) {
+	src := testImage(200, 100)
+	tests := []struct {
+		name          string
+		width, height int
+		mode          imaging.Mode
+		wantW, wantH  int
+	}{
+		{"按宽度等比缩放", 100, 0, imaging.Fit, 100, 50},
+		{"按宽高范围缩放", 100, 20, imaging.Fit, 40, 20},
+		{"不放大", 400, 400, imaging.Fit, 200, 100},
+		{"裁剪为正方形", 30, 30, imaging.Crop, 30, 30},
+		{"裁剪允许放大", 300, 300, imaging.Crop, 300, 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := imaging.Resize(src, tt.width, tt.height, tt.mode).Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Fatalf("期望 %dx%d，实际 %dx%d", tt.wantW, tt.wantH, b.Dx(), b.Dy())
+			}
+		})
+	}
+
+	// 居中裁剪保留左右两种颜色，缩放后左侧仍为红色
+	out := imaging.Resize(src, 10, 10, imaging.Crop)
+	if r, _, b, _ := out.At(0, 5).RGBA(); r>>8 != 255 || b != 0 {
+		t.Fatalf("左侧颜色不正确: %v", out.At(0, 5))
+	}
+	if r, _, b, _ := out.At(9, 5).RGBA(); r != 0 || b>>8 != 255 {
+		t.Fatalf("右侧颜色不正确: %v", out.At(9, 5))
+	}
+}
+
+func TestProcessor_GenerateAndDelete(t *testing.T) {
+	fs := newTestFilesystem(t)
+	p := imaging.New(fs, imaging.Config{Presets: presets})
+	if err := fs.Set("photos/a.png", encodePNG(t, testImage(200, 100)), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := p.Generate("photos/a.png")
+	if err != nil {
+		t.Fatalf("Generate 失败: %v", err)
+	}
+	if keys["thumb"] != "photos/a@thumb.png" || keys["medium"] != "photos/a@medium.png" {
+		t.Fatalf("变体路径不正确: %v", keys)
+	}
+	if w, h, _ := decodeSize(t, fs, keys["thumb"]); w != 20 || h != 20 {
+		t.Fatalf("thumb 尺寸不正确: %dx%d", w, h)
+	}
+	if w, h, _ := decodeSize(t, fs, keys["medium"]); w != 50 || h != 25 {
+		t.Fatalf("medium 尺寸不正确: %dx%d", w, h)
+	}
+
+	if _, err := p.Generate("photos/a.png", "huge"); !errors.Is(err, imaging.ErrUnknownVariant) {
+		t.Fatalf("期望 ErrUnknownVariant，实际: %v", err)
+	}
+
+	if err := p.Delete("photos/a.png"); err != nil {
+		t.Fatalf("Delete 失败: %v", err)
+	}
+	for _, key := range []string{"photos/a.png", keys["thumb"], keys["medium"]} {
+		if exists, _ := fs.Exists(key); exists {
+			t.Fatalf("%s 应已删除", key)
+		}
+	}
+}
+
+func TestProcessor_Variant(t *testing.T) {
+	fs := newTestFilesystem(t)
+	p := imaging.New(fs, imaging.Config{Presets: presets})
+
+	// JPEG 保持 JPEG，GIF 变体输出为 PNG
+	var jpg, gf bytes.Buffer
+	if err := jpeg.Encode(&jpg, testImage(100, 100), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gf, testImage(100, 100), nil); err != nil {
+		t.Fatal(err)
+	}
+	fs.Set("a.jpg", jpg.Bytes(), 0)
+	fs.Set("b.gif", gf.Bytes(), 0)
+
+	key, err := p.Variant("a.jpg", "thumb")
+	if err != nil || key != "a@thumb.jpg" {
+		t.Fatalf("Variant 失败: %s %v", key, err)
+	}
+	if _, _, format := decodeSize(t, fs, key); format != "jpeg" {
+		t.Fatalf("期望 jpeg，实际 %s", format)
+	}
+	key, err = p.Variant("b.gif", "thumb")
+	if err != nil || key != "b@thumb.png" {
+		t.Fatalf("Variant 失败: %s %v", key, err)
+	}
+	if _, _, format := decodeSize(t, fs, key); format != "png" {
+		t.Fatalf("期望 png，实际 %s", format)
+	}
+
+	if _, err := p.Variant("missing.png", "thumb"); !errors.Is(err, filesystem.ErrNotFound) {
+		t.Fatalf("期望 ErrNotFound，实际: %v", err)
+	}
+	fs.Set("text.png", []byte("not an image"), 0)
+	if _, err := p.Variant("text.png", "thumb"); !errors.Is(err, imaging.ErrUnsupportedImage) {
+		t.Fatalf("期望 ErrUnsupportedImage，实际: %v", err)
+	}
+
+	small := imaging.New(fs, imaging.Config{Presets: presets, MaxPixels: 100})
+	if _, err := small.Variant("a.jpg", "medium"); !errors.Is(err, imaging.ErrImageTooLarge) {
+		t.Fatalf("期望 ErrImageTooLarge，实际: %v", err)
+	}
+}
+
+func TestProcessor_Handler(t *testing.T) {
+	fs := newTestFilesystem(t)
+	p := imaging.New(fs, imaging.Config{Presets: presets, Public: true})
+	fs.Set("photos/my photo.png", encodePNG(t, testImage(200, 100)), 0)
+
+	app := fiber.New()
+	app.Get("/images/:variant/*", p.Handler())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/images/thumb/photos/my%20photo.png", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("期望 200，实际 %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != "image/png" {
+		t.Fatalf("Content-Type 不正确: %s", got)
+	}
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "public, max-age=2592000" {
+		t.Fatalf("Cache-Control 不正确: %s", got)
+	}
+	img, err := png.Decode(resp.Body)
+	if err != nil || img.Bounds().Dx() != 20 {
+		t.Fatalf("响应图片不正确: %v", err)
+	}
+	if exists, _ := fs.Exists("photos/my photo@thumb.png"); !exists {
+		t.Fatal("变体应在首次请求时生成")
+	}
+
+	for path, status := range map[string]int{
+		"/images/huge/photos/my%20photo.png": fiber.StatusNotFound,
+		"/images/thumb/photos/missing.png":   fiber.StatusNotFound,
+		// 变体不能再生成变体，防止匿名请求无限写入文件
+		"/images/thumb/photos/my%20photo@thumb.png":  fiber.StatusNotFound,
+		"/images/medium/photos/my%20photo@thumb.png": fiber.StatusNotFound,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Fatalf("%s 期望 %d，实际 %d", path, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestProcessor_OnUpload(t *testing.T) {
+	fs := newTestFilesystem(t)
+	p := imaging.New(fs, imaging.Config{Presets: presets})
+	u := upload.New(fs, upload.Config{Pattern: "{name}{ext}", OnSave: p.OnUpload})
+
+	file, err := u.SaveReader("cover.png", bytes.NewReader(encodePNG(t, testImage(200, 100))))
+	if err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	for _, name := range p.Variants() {
+		if exists, _ := fs.Exists(p.VariantKey(file.Key, name)); !exists {
+			t.Fatalf("上传后应生成变体 %s", name)
+		}
+	}
+
+	// 非图片文件不生成变体
+	if _, err := u.SaveReader("notes.txt", bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	if exists, _ := fs.Exists("notes@thumb.txt"); exists {
+		t.Fatal("非图片文件不应生成变体")
+	}
+}
+
+func TestProcessor_HandlerAuthorization(t *testing.T) {
+	fs := newTestFilesystem(t)
+	fs.Set("private/a.png", encodePNG(t, testImage(100, 100)), 0)
+	fs.Set("shared/b.png", encodePNG(t, testImage(100, 100)), 0)
+
+	request := func(p *imaging.Processor, path string) *http.Response {
+		t.Helper()
+		app := fiber.New()
+		app.Get("/images/:variant/*", p.Handler())
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// 未开启 Public 且未配置 Authorize 时拒绝访问，也不生成变体
+	if resp := request(imaging.New(fs, imaging.Config{Presets: presets}), "/images/thumb/private/a.png"); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("期望 403，实际 %d", resp.StatusCode)
+	}
+	if exists, _ := fs.Exists("private/a@thumb.png"); exists {
+		t.Fatal("拒绝访问时不应生成变体")
+	}
+
+	p := imaging.New(fs, imaging.Config{Presets: presets, Authorize: func(c fiber.Ctx, key string) error {
+		if !strings.HasPrefix(key, "shared/") {
+			return fiber.ErrForbidden
+		}
+		return nil
+	}})
+	if resp := request(p, "/images/thumb/private/a.png"); resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("期望 403，实际 %d", resp.StatusCode)
+	}
+	resp := request(p, "/images/thumb/shared/b.png")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("期望 200，实际 %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "private, max-age=2592000" {
+		t.Fatalf("授权访问的响应应为 private: %s", got)
+	}
+}
+
+func TestProcessor_VariantSource(t *testing.T) {
+	fs := newTestFilesystem(t)
+	p := imaging.New(fs, imaging.Config{Presets: presets})
+	fs.Set("a@thumb.png", encodePNG(t, testImage(100, 100)), 0)
+
+	if !p.IsVariant("dir/a@medium.jpg") || p.IsVariant("me@example.png") {
+		t.Fatal("IsVariant 判断不正确")
+	}
+	if _, err := p.Variant("a@thumb.png", "thumb"); !errors.Is(err, imaging.ErrVariantSource) {
+		t.Fatalf("期望 ErrVariantSource，实际: %v", err)
+	}
+	if _, err := p.Generate("a@thumb.png"); !errors.Is(err, imaging.ErrVariantSource) {
+		t.Fatalf("期望 ErrVariantSource，实际: %v", err)
+	}
+	if exists, _ := fs.Exists("a@thumb@thumb.png"); exists {
+		t.Fatal("不应生成变体的变体")
+	}
+}
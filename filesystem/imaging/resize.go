@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Mode 缩放方式
+type Mode int
+
+const (
+	// Fit 等比缩放到宽高范围内，不放大，Width 或 Height 为 0 时只按另一边限制
+	Fit Mode = iota
+	// Crop 等比缩放后居中裁剪，输出尺寸固定为 Width x Height
+	Crop
+)
+
+// Resize 按方式缩放图片
+func Resize(src image.Image, width, height int, mode Mode) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+	if mode == Crop && width > 0 && height > 0 {
+		// 从源图中取与目标宽高比一致的居中区域
+		rect := b
+		if sw*height > sh*width {
+			cw := sh * width / height
+			rect.Min.X += (sw - cw) / 2
+			rect.Max.X = rect.Min.X + cw
+		} else {
+			ch := sw * height / width
+			rect.Min.Y += (sh - ch) / 2
+			rect.Max.Y = rect.Min.Y + ch
+		}
+		return resample(src, rect, width, height)
+	}
+
+	w, h := fitSize(sw, sh, width, height)
+	if w == sw && h == sh {
+		return src
+	}
+	return resample(src, b, w, h)
+}
+
+// fitSize 计算等比缩放到范围内的尺寸，不放大
+func fitSize(sw, sh, width, height int) (int, int) {
+	scale := 1.0
+	if width > 0 && sw > width {
+		scale = float64(width) / float64(sw)
+	}
+	if height > 0 && sh > height {
+		scale = min(scale, float64(height)/float64(sh))
+	}
+	return max(1, int(float64(sw)*scale+0.5)), max(1, int(float64(sh)*scale+0.5))
+}
+
+// resample 将 src 的 rect 区域缩放为 w x h，缩小时对覆盖的源像素取平均（按透明度加权），放大时取最近像素
+func resample(src image.Image, rect image.Rectangle, w, h int) *image.NRGBA {
+	s := image.NewNRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(s, s.Bounds(), src, rect.Min, draw.Src)
+	sw, sh := rect.Dx(), rect.Dy()
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := s.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					pa := uint64(s.Pix[i+3])
+					r += uint64(s.Pix[i]) * pa
+					g += uint64(s.Pix[i+1]) * pa
+					b += uint64(s.Pix[i+2]) * pa
+					a += pa
+					n++
+					i += 4
+				}
+			}
+			j := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[j] = uint8(r / a)
+				dst.Pix[j+1] = uint8(g / a)
+				dst.Pix[j+2] = uint8(b / a)
+			}
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,207 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/gofiber/storage/s3/v2"
+	cmfstorage "github.com/wuwuseo/cmf/storage"
+)
+
+// S3Storage 在 gofiber S3 存储的基础上实现文件信息、列举、复制、移动、流式读取与预签名链接
+type S3Storage struct {
+	*s3.Storage
+	bucket         string
+	requestTimeout time.Duration
+}
+
+// NewS3Storage 创建 S3 存储
+func NewS3Storage(cfg s3.Config) *S3Storage {
+	return &S3Storage{
+		Storage:        s3.New(cfg),
+		bucket:         cfg.Bucket,
+		requestTimeout: cfg.RequestTimeout,
+	}
+}
+
+// Bucket 返回存储桶名称
+func (s *S3Storage) Bucket() string {
+	return s.bucket
+}
+
+func (s *S3Storage) requestContext() (context.Context, context.CancelFunc) {
+	if s.requestTimeout > 0 {
+		return context.WithTimeout(context.Background(), s.requestTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// isS3NotFound 判断是否为对象不存在的错误，HeadObject 返回 NotFound，GetObject 返回 NoSuchKey
+func isS3NotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey":
+			return true
+		}
+	}
+	return false
+}
+
+func s3NotFound(key string) error {
+	return fmt.Errorf("%w: %s", cmfstorage.ErrNotFound, key)
+}
+
+// Exists 判断对象是否存在
+func (s *S3Storage) Exists(key string) (bool, error) {
+	_, err := s.Stat(key)
+	if errors.Is(err, cmfstorage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Stat 通过 HeadObject 读取对象信息，Checksum 为去掉引号的 ETag
+func (s *S3Storage) Stat(key string) (*cmfstorage.FileInfo, error) {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	out, err := s.Conn().HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, s3NotFound(key)
+		}
+		return nil, err
+	}
+	return &cmfstorage.FileInfo{
+		Key:         key,
+		Size:        aws.ToInt64(out.ContentLength),
+		ModTime:     aws.ToTime(out.LastModified),
+		ContentType: aws.ToString(out.ContentType),
+		Checksum:    strings.Trim(aws.ToString(out.ETag), `"`),
+		Metadata:    out.Metadata,
+	}, nil
+}
+
+// Size 读取对象大小
+func (s *S3Storage) Size(key string) (int64, error) {
+	info, err := s.Stat(key)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// List 列举前缀下的对象，recursive 为 false 时以 / 为分隔符，公共前缀以 IsDir 返回
+// ListObjectsV2 不返回 ContentType，需要时对单个对象调用 Stat
+func (s *S3Storage) List(prefix string, recursive bool) ([]cmfstorage.FileInfo, error) {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	input := &awss3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	if !recursive {
+		input.Delimiter = aws.String("/")
+	}
+	var files []cmfstorage.FileInfo
+	paginator := awss3.NewListObjectsV2Paginator(s.Conn(), input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.CommonPrefixes {
+			files = append(files, cmfstorage.FileInfo{Key: aws.ToString(p.Prefix), IsDir: true})
+		}
+		for _, obj := range page.Contents {
+			files = append(files, cmfstorage.FileInfo{
+				Key:      aws.ToString(obj.Key),
+				Size:     aws.ToInt64(obj.Size),
+				ModTime:  aws.ToTime(obj.LastModified),
+				Checksum: strings.Trim(aws.ToString(obj.ETag), `"`),
+			})
+		}
+	}
+	return files, nil
+}
+
+// Copy 在存储桶内复制对象，不经过本地传输
+func (s *S3Storage) Copy(src, dst string) error {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	// CopySource 需要 URL 编码，保留路径分隔符
+	source := (&url.URL{Path: s.bucket + "/" + src}).EscapedPath()
+	_, err := s.Conn().CopyObject(ctx, &awss3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(source),
+	})
+	if isS3NotFound(err) {
+		return s3NotFound(src)
+	}
+	return err
+}
+
+// Move 复制后删除源对象，S3 没有原生的重命名
+func (s *S3Storage) Move(src, dst string) error {
+	if src == dst {
+		_, err := s.Stat(src)
+		return err
+	}
+	if err := s.Copy(src, dst); err != nil {
+		return err
+	}
+	return s.Delete(src)
+}
+
+// GetReader 以流的方式读取对象，调用方负责关闭
+// 读取过程可能超过请求超时时间，因此不使用 RequestTimeout
+func (s *S3Storage) GetReader(key string) (io.ReadCloser, error) {
+	out, err := s.Conn().GetObject(context.Background(), &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, s3NotFound(key)
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// TemporaryURL 生成预签名的 GET 链接，opts 通过 response-content-* 参数覆盖响应头
+func (s *S3Storage) TemporaryURL(key string, ttl time.Duration, opts cmfstorage.URLOptions) (string, error) {
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	input := &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if opts.Filename != "" {
+		input.ResponseContentDisposition = aws.String(contentDisposition(opts.Filename))
+	}
+	if opts.ContentType != "" {
+		input.ResponseContentType = aws.String(opts.ContentType)
+	}
+	req, err := awss3.NewPresignClient(s.Conn()).PresignGetObject(ctx, input, awss3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
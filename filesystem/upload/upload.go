@@ -0,0 +1,381 @@
+// Package upload 将 multipart 上传的文件流式写入 Filesystem 磁盘
+//
+// 文件类型根据内容头部的魔数判断，不信任扩展名与客户端提供的 Content-Type；
+// 存储路径按模式生成，支持日期目录、UUID 与 SHA-256 内容哈希，基于哈希命名时可以对相同内容去重。
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/wuwuseo/cmf/filesystem"
+)
+
+const (
+	defaultMaxSize    = 10 << 20
+	defaultPattern    = "{date}/{uuid}{ext}"
+	defaultFieldName  = "file"
+	defaultTempPrefix = ".uploads/"
+	sniffLen          = 512
+)
+
+var (
+	// ErrNoFile 请求中没有上传文件
+	ErrNoFile = errors.New("no file uploaded")
+	// ErrTooLarge 文件超过大小限制
+	ErrTooLarge = errors.New("file too large")
+	// ErrTypeNotAllowed 文件类型不在允许列表中
+	ErrTypeNotAllowed = errors.New("file type not allowed")
+)
+
+// Config 上传配置
+type Config struct {
+	// MaxSize 单个文件的最大字节数，默认 10MB，小于 0 时不限制
+	MaxSize int64
+	// AllowedTypes 允许的 MIME 类型，支持 image/* 形式的通配
+	// 为空时允许除 HTML、XML、SVG、JavaScript 等可执行脚本的类型以外的所有类型
+	AllowedTypes []string
+	// Pattern 存储路径模式，默认 {date}/{uuid}{ext}，可用占位符：
+	//	{date} 2006/01/02    {year} {month} {day}
+	//	{uuid} 随机 UUID     {hash} 内容的 SHA-256 十六进制
+	//	{name} 原始文件名（不含扩展名，已过滤特殊字符）
+	//	{ext}  根据内容类型确定的扩展名，包含点号
+	Pattern string
+	// Prefix 存储路径前缀，如 avatars/
+	Prefix string
+	// Dedupe 为 true 且 Pattern 包含 {hash} 时，已存在相同路径的文件直接复用，不再重复写入
+	Dedupe bool
+	// FieldName Handler 读取的表单字段名，默认 file
+	FieldName string
+	// TempPrefix 计算哈希期间暂存文件的路径前缀，默认 .uploads/
+	TempPrefix string
+	// OnSave 文件写入后调用，如生成图片变体；复用已有文件时不调用，返回错误时上传失败但文件保留
+	OnSave func(file *File) error
+}
+
+// File 上传结果
+type File struct {
+	Key          string `json:"key"`           // 存储路径
+	OriginalName string `json:"original_name"` // 客户端提供的文件名
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"` // 根据内容嗅探的 MIME 类型
+	Extension    string `json:"extension"`
+	Hash         string `json:"hash"`         // 内容的 SHA-256 十六进制
+	Deduplicated bool   `json:"deduplicated"` // 是否复用了已存在的文件
+}
+
+// Uploader 文件上传器
+type Uploader struct {
+	fs     *filesystem.Filesystem
+	config Config
+}
+
+// New 创建上传器，文件写入 fs 对应的磁盘
+func New(fs *filesystem.Filesystem, config Config) *Uploader {
+	if config.MaxSize == 0 {
+		config.MaxSize = defaultMaxSize
+	}
+	if config.Pattern == "" {
+		config.Pattern = defaultPattern
+	}
+	if config.FieldName == "" {
+		config.FieldName = defaultFieldName
+	}
+	if config.TempPrefix == "" {
+		config.TempPrefix = defaultTempPrefix
+	}
+	return &Uploader{fs: fs, config: config}
+}
+
+// Save 保存 multipart 表单中的文件
+func (u *Uploader) Save(header *multipart.FileHeader) (*File, error) {
+	if u.config.MaxSize > 0 && header.Size > u.config.MaxSize {
+		return nil, fmt.Errorf("%w: %s", ErrTooLarge, header.Filename)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return u.SaveReader(header.Filename, file)
+}
+
+// SaveReader 从 reader 流式读取并保存文件，name 为原始文件名，仅用于 {name} 与扩展名推断
+func (u *Uploader) SaveReader(name string, reader io.Reader) (*File, error) {
+	// 读取头部用于嗅探类型，之后与剩余内容拼接后写入
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType := detectContentType(head)
+	if !u.allowed(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	result := &File{
+		OriginalName: name,
+		ContentType:  contentType,
+		Extension:    extension(name, contentType),
+	}
+	hash := sha256.New()
+	counter := &limitedCounter{limit: u.config.MaxSize}
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), reader), io.MultiWriter(hash, counter))
+
+	vars := u.vars(result)
+	if !strings.Contains(u.config.Pattern, "{hash}") {
+		result.Key = u.key(vars)
+		if err := u.fs.SetReader(result.Key, body, 0); err != nil {
+			u.fs.Delete(result.Key)
+			return nil, u.wrapErr(err, counter)
+		}
+		result.Size = counter.n
+		result.Hash = hex.EncodeToString(hash.Sum(nil))
+		return result, u.saved(result)
+	}
+
+	// 路径依赖内容哈希，先写入临时路径，写完后移动到最终路径
+	tempKey := u.config.TempPrefix + uuid.NewString()
+	if err := u.fs.SetReader(tempKey, body, 0); err != nil {
+		u.fs.Delete(tempKey)
+		return nil, u.wrapErr(err, counter)
+	}
+	result.Size = counter.n
+	result.Hash = hex.EncodeToString(hash.Sum(nil))
+	vars["{hash}"] = result.Hash
+	result.Key = u.key(vars)
+
+	if u.config.Dedupe {
+		exists, err := u.fs.Exists(result.Key)
+		if err != nil {
+			u.fs.Delete(tempKey)
+			return nil, err
+		}
+		if exists {
+			result.Deduplicated = true
+			return result, u.fs.Delete(tempKey)
+		}
+	}
+	if err := u.fs.Move(tempKey, result.Key); err != nil {
+		u.fs.Delete(tempKey)
+		return nil, err
+	}
+	return result, u.saved(result)
+}
+
+// saved 调用 OnSave 回调
+func (u *Uploader) saved(file *File) error {
+	if u.config.OnSave == nil {
+		return nil
+	}
+	return u.config.OnSave(file)
+}
+
+// wrapErr 超过大小限制导致的写入失败返回 ErrTooLarge
+func (u *Uploader) wrapErr(err error, counter *limitedCounter) error {
+	if counter.exceeded {
+		return ErrTooLarge
+	}
+	return err
+}
+
+// vars 返回除 {hash} 外的占位符取值
+func (u *Uploader) vars(file *File) map[string]string {
+	now := time.Now()
+	base := strings.TrimSuffix(path.Base(strings.ReplaceAll(file.OriginalName, "\\", "/")), path.Ext(file.OriginalName))
+	return map[string]string{
+		"{date}":  now.Format("2006/01/02"),
+		"{year}":  now.Format("2006"),
+		"{month}": now.Format("01"),
+		"{day}":   now.Format("02"),
+		"{uuid}":  uuid.NewString(),
+		"{name}":  sanitizeName(base),
+		"{ext}":   file.Extension,
+	}
+}
+
+func (u *Uploader) key(vars map[string]string) string {
+	key := u.config.Pattern
+	for placeholder, value := range vars {
+		key = strings.ReplaceAll(key, placeholder, value)
+	}
+	return path.Clean(u.config.Prefix + key)
+}
+
+// deniedTypes 未配置 AllowedTypes 时拒绝的类型，浏览器会执行其中的脚本，在公开磁盘上会造成存储型 XSS
+var deniedTypes = []string{
+	"text/html",
+	"text/xml",
+	"application/xml",
+	"application/xhtml+xml",
+	"image/svg+xml",
+	"text/javascript",
+	"application/javascript",
+}
+
+// allowed 判断类型是否在允许列表中，未配置允许列表时拒绝 deniedTypes 中的类型
+func (u *Uploader) allowed(contentType string) bool {
+	if len(u.config.AllowedTypes) == 0 {
+		return !slices.Contains(deniedTypes, contentType)
+	}
+	for _, allowed := range u.config.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler 返回处理上传请求的 Fiber 处理函数，保存 FieldName 字段中的全部文件并返回元数据
+// 直接从请求体中逐个解析 multipart 分段并流式写入磁盘，不经过 MultipartForm
+//
+// 流式上传需要开启 fiber.Config{StreamRequestBody: true}；未开启时 fasthttp 会先把整个请求体读入内存，
+// 并拒绝超过 BodyLimit（默认 4MB，小于默认的 MaxSize）的请求
+//
+//	app := fiber.New(fiber.Config{StreamRequestBody: true})
+//	app.Post("/upload", upload.New(fs, upload.Config{AllowedTypes: []string{"image/*"}}).Handler())
+func (u *Uploader) Handler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		mediaType, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+		if err != nil || mediaType != fiber.MIMEMultipartForm || params["boundary"] == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "request is not multipart/form-data")
+		}
+		body := c.Request().BodyStream()
+		if body == nil {
+			body = bytes.NewReader(c.Request().Body())
+		}
+
+		reader := multipart.NewReader(body, params["boundary"])
+		var files []*File
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+			if part.FormName() != u.config.FieldName || part.FileName() == "" {
+				part.Close()
+				continue
+			}
+			file, err := u.SaveReader(part.FileName(), part)
+			part.Close()
+			if err != nil {
+				return httpError(err)
+			}
+			files = append(files, file)
+		}
+		if len(files) == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, ErrNoFile.Error())
+		}
+		return c.JSON(fiber.Map{"files": files})
+	}
+}
+
+// httpError 将上传错误转换为对应状态码的 Fiber 错误
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, ErrTypeNotAllowed):
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+	}
+	return err
+}
+
+// detectContentType 根据魔数嗅探 MIME 类型，去掉 charset 等参数
+func detectContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// preferredExtensions 常见类型的首选扩展名，mime.ExtensionsByType 的返回顺序不固定
+var preferredExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"image/x-icon":    ".ico",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"text/html":       ".html",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+}
+
+// extension 原始扩展名与嗅探类型一致时保留，否则使用类型对应的扩展名，防止伪装扩展名
+func extension(name, contentType string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext != "" {
+		if typ, _, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil && typ == contentType {
+			return ext
+		}
+	}
+	if ext, ok := preferredExtensions[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		slices.Sort(exts)
+		return exts[0]
+	}
+	return ""
+}
+
+// sanitizeName 只保留字母、数字、下划线、连字符与点号，其余字符替换为下划线
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	result := strings.Trim(b.String(), ".")
+	if result == "" {
+		return "file"
+	}
+	return result
+}
+
+// limitedCounter 统计写入字节数，超过限制时返回错误以中断写入
+type limitedCounter struct {
+	n        int64
+	limit    int64
+	exceeded bool
+}
+
+func (c *limitedCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	if c.limit > 0 && c.n > c.limit {
+		c.exceeded = true
+		return 0, ErrTooLarge
+	}
+	return len(p), nil
+}
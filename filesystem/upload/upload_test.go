@@ -0,0 +1,216 @@
+package upload_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/filesystem"
+	"github.com/wuwuseo/cmf/filesystem/upload"
+	local "github.com/wuwuseo/cmf/storage/local"
+)
+
+// pngData 以 PNG 魔数开头的测试数据
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+func newTestFilesystem(t *testing.T) *filesystem.Filesystem {
+	t.Helper()
+	return filesystem.NewFilesystem(local.New(local.Config{BasePath: t.TempDir()}), config.Config{})
+}
+
+// listAll 列出磁盘中的全部文件
+func listAll(t *testing.T, fs *filesystem.Filesystem) []string {
+	t.Helper()
+	files, err := fs.List("", true)
+	if err != nil {
+		t.Fatalf("List 失败: %v", err)
+	}
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+func TestSaveReader_DefaultPattern(t *testing.T) {
+	fs := newTestFilesystem(t)
+	u := upload.New(fs, upload.Config{})
+
+	// 扩展名伪装为 php，按内容识别为 PNG
+	file, err := u.SaveReader("shell.php", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	if file.ContentType != "image/png" || file.Extension != ".png" {
+		t.Fatalf("类型识别不正确: %+v", file)
+	}
+	prefix := time.Now().Format("2006/01/02") + "/"
+	if !strings.HasPrefix(file.Key, prefix) || !strings.HasSuffix(file.Key, ".png") || len(file.Key) != len(prefix)+36+4 {
+		t.Fatalf("存储路径不正确: %s", file.Key)
+	}
+	if file.Size != int64(len(pngData)) || len(file.Hash) != 64 || file.OriginalName != "shell.php" {
+		t.Fatalf("元数据不正确: %+v", file)
+	}
+	got, _ := fs.Get(file.Key)
+	if !bytes.Equal(got, pngData) {
+		t.Fatal("写入的内容不正确")
+	}
+}
+
+func TestSaveReader_Validation(t *testing.T) {
+	fs := newTestFilesystem(t)
+
+	u := upload.New(fs, upload.Config{AllowedTypes: []string{"image/*", "application/pdf"}})
+	if _, err := u.SaveReader("a.png", strings.NewReader("plain text pretending to be png")); !errors.Is(err, upload.ErrTypeNotAllowed) {
+		t.Fatalf("不允许的类型应返回 ErrTypeNotAllowed: %v", err)
+	}
+	if _, err := u.SaveReader("a.pdf", strings.NewReader("%PDF-1.7 ...")); err != nil {
+		t.Fatalf("允许的类型不应报错: %v", err)
+	}
+
+	// 未配置允许列表时拒绝可执行脚本的类型
+	u = upload.New(fs, upload.Config{})
+	for name, data := range map[string]string{
+		"a.html": "<!DOCTYPE html><script>alert(1)</script>",
+		"a.xml":  `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+	} {
+		if _, err := u.SaveReader(name, strings.NewReader(data)); !errors.Is(err, upload.ErrTypeNotAllowed) {
+			t.Fatalf("%s 应返回 ErrTypeNotAllowed: %v", name, err)
+		}
+	}
+	if file, err := u.SaveReader("a.txt", strings.NewReader("hello")); err != nil || file.Extension != ".txt" {
+		t.Fatalf("纯文本应允许上传: %+v, %v", file, err)
+	}
+
+	u = upload.New(fs, upload.Config{MaxSize: 50, Pattern: "{name}{ext}", Prefix: "big/"})
+	if _, err := u.SaveReader("big.png", bytes.NewReader(pngData)); !errors.Is(err, upload.ErrTooLarge) {
+		t.Fatalf("超过大小限制应返回 ErrTooLarge: %v", err)
+	}
+	for _, key := range listAll(t, fs) {
+		if strings.HasPrefix(key, "big/") {
+			t.Fatalf("超过大小限制的文件不应保留: %s", key)
+		}
+	}
+}
+
+func TestSaveReader_HashDedupe(t *testing.T) {
+	fs := newTestFilesystem(t)
+	u := upload.New(fs, upload.Config{Pattern: "{hash}{ext}", Prefix: "media/", Dedupe: true})
+
+	first, err := u.SaveReader("a.png", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	if first.Key != "media/"+first.Hash+".png" || first.Deduplicated {
+		t.Fatalf("按哈希命名不正确: %+v", first)
+	}
+	second, err := u.SaveReader("b.png", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	if second.Key != first.Key || !second.Deduplicated {
+		t.Fatalf("相同内容应复用已有文件: %+v", second)
+	}
+	if keys := listAll(t, fs); len(keys) != 1 || keys[0] != first.Key {
+		t.Fatalf("不应留下重复或临时文件: %v", keys)
+	}
+}
+
+func TestSaveReader_NamePattern(t *testing.T) {
+	fs := newTestFilesystem(t)
+	u := upload.New(fs, upload.Config{Pattern: "{year}/{month}/{name}{ext}"})
+	file, err := u.SaveReader(`C:\Users\me\我的 照片.PNG`, bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("SaveReader 失败: %v", err)
+	}
+	want := time.Now().Format("2006/01") + "/_____.png"
+	if file.Key != want {
+		t.Fatalf("期望 %s，实际 %s", want, file.Key)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	fs := newTestFilesystem(t)
+	app := fiber.New()
+	app.Post("/upload", upload.New(fs, upload.Config{AllowedTypes: []string{"image/png"}}).Handler())
+
+	send := func(files map[string][]byte) *http.Response {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("title", "ignored")
+		for name, data := range files {
+			part, _ := writer.CreateFormFile("file", name)
+			part.Write(data)
+		}
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		return resp
+	}
+
+	resp := send(map[string][]byte{"a.png": pngData})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("期望 200，实际 %d", resp.StatusCode)
+	}
+	var result struct{ Files []upload.File }
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.Files[0].ContentType != "image/png" || result.Files[0].Key == "" {
+		t.Fatalf("返回的元数据不正确: %+v", result)
+	}
+
+	if resp := send(map[string][]byte{"a.png": []byte("not an image")}); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("不允许的类型应返回 415，实际 %d", resp.StatusCode)
+	}
+	if resp := send(nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("没有文件时应返回 400，实际 %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_StreamRequestBody(t *testing.T) {
+	fs := newTestFilesystem(t)
+	// 请求体超过 BodyLimit，开启 StreamRequestBody 后仍按 MaxSize 流式写入
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 1024})
+	app.Post("/upload", upload.New(fs, upload.Config{MaxSize: 1 << 20}).Handler())
+
+	data := append(append([]byte{}, pngData...), bytes.Repeat([]byte{1}, 64<<10)...)
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "big.png")
+	part.Write(data)
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("期望 200，实际 %d", resp.StatusCode)
+	}
+	var result struct{ Files []upload.File }
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Size != int64(len(data)) {
+		t.Fatalf("返回的元数据不正确: %+v", result)
+	}
+	if got, _ := fs.Get(result.Files[0].Key); !bytes.Equal(got, data) {
+		t.Fatal("写入的内容不正确")
+	}
+}
@@ -0,0 +1,218 @@
+package filesystem
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	cmfstorage "github.com/wuwuseo/cmf/storage"
+)
+
+// URLOptions 临时链接选项
+type URLOptions = cmfstorage.URLOptions
+
+var (
+	// ErrTemporaryURLUnsupported 磁盘不支持临时链接，本地磁盘需要在 options 中配置 url
+	ErrTemporaryURLUnsupported = fmt.Errorf("%w: disk cannot generate temporary urls", errors.ErrUnsupported)
+	// ErrInvalidSignature 签名无效或链接已过期
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+// 签名链接的查询参数
+const (
+	queryExpires     = "expires"
+	querySignature   = "signature"
+	queryFilename    = "filename"
+	queryContentType = "content_type"
+)
+
+// TemporaryURL 生成在 ttl 内有效的文件访问链接
+// 驱动原生支持时（如 S3）返回预签名链接；本地磁盘在 options 中配置 url（可选 secret，默认使用 app.secret）后
+// 返回 HMAC 签名链接，由 RegisterRoutes 注册的路由校验签名并返回文件
+func (f *Filesystem) TemporaryURL(key string, ttl time.Duration, opts URLOptions) (string, error) {
+	if ttl <= 0 {
+		return "", errors.New("临时链接的有效期必须大于 0")
+	}
+	if a, ok := f.Adapter.(cmfstorage.TemporaryURLer); ok {
+		u, err := a.TemporaryURL(key, ttl, opts)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return u, err
+		}
+	}
+	signer, err := f.signer()
+	if err != nil {
+		return "", err
+	}
+	return signer.url(key, time.Now().Add(ttl), opts), nil
+}
+
+// TemporaryURL 使用主存储生成临时链接
+func (d *DualStorage) TemporaryURL(key string, ttl time.Duration, opts URLOptions) (string, error) {
+	if a, ok := d.Primary.(cmfstorage.TemporaryURLer); ok {
+		return a.TemporaryURL(key, ttl, opts)
+	}
+	return "", ErrTemporaryURLUnsupported
+}
+
+// urlSigner 本地磁盘的链接签名器
+type urlSigner struct {
+	disk   string
+	base   string
+	secret []byte
+}
+
+// signer 根据磁盘的 url 与 secret 选项创建签名器
+func (f *Filesystem) signer() (*urlSigner, error) {
+	name := f.Name()
+	options, _ := f.Config.Filesystem.Disks[name].Options.(map[string]any)
+	base, _ := options["url"].(string)
+	if base == "" {
+		return nil, ErrTemporaryURLUnsupported
+	}
+	secret, _ := options["secret"].(string)
+	if secret == "" {
+		secret = f.Config.App.Secret
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("磁盘 '%s' 未配置签名密钥", name)
+	}
+	return &urlSigner{disk: name, base: strings.TrimSuffix(base, "/"), secret: []byte(secret)}, nil
+}
+
+// sign 对磁盘、键、过期时间与响应选项签名，防止链接被用于其他磁盘或篡改下载参数
+// 每个字段带长度前缀，键与文件名中包含换行等分隔符时也不会与其他字段组合产生相同的签名内容
+func (s *urlSigner) sign(key string, expires int64, opts URLOptions) string {
+	mac := hmac.New(sha256.New, s.secret)
+	for _, field := range []string{s.disk, key, strconv.FormatInt(expires, 10), opts.Filename, opts.ContentType} {
+		fmt.Fprintf(mac, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *urlSigner) url(key string, expiresAt time.Time, opts URLOptions) string {
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	query.Set(queryExpires, strconv.FormatInt(expires, 10))
+	if opts.Filename != "" {
+		query.Set(queryFilename, opts.Filename)
+	}
+	if opts.ContentType != "" {
+		query.Set(queryContentType, opts.ContentType)
+	}
+	query.Set(querySignature, s.sign(key, expires, opts))
+	return s.base + (&url.URL{Path: "/" + key}).EscapedPath() + "?" + query.Encode()
+}
+
+// verify 校验签名与过期时间，返回签名中的响应选项
+func (s *urlSigner) verify(key string, query func(string) string) (URLOptions, error) {
+	opts := URLOptions{Filename: query(queryFilename), ContentType: query(queryContentType)}
+	expires, err := strconv.ParseInt(query(queryExpires), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return opts, ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(query(querySignature))
+	if err != nil {
+		return opts, ErrInvalidSignature
+	}
+	expected, _ := hex.DecodeString(s.sign(key, expires, opts))
+	if !hmac.Equal(signature, expected) {
+		return opts, ErrInvalidSignature
+	}
+	return opts, nil
+}
+
+// contentDisposition 生成附件形式的 Content-Disposition，非 ASCII 文件名按 RFC 5987 编码
+func contentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+// TemporaryURLHandler 返回校验签名链接并输出文件的 Fiber 处理函数，路由需要以 /* 结尾
+//
+//	app.Get("/files/*", fs.TemporaryURLHandler())
+func (f *Filesystem) TemporaryURLHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		signer, err := f.signer()
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+		key := c.Params("*")
+		if !c.App().Config().UnescapePath {
+			if key, err = url.PathUnescape(key); err != nil {
+				return fiber.ErrBadRequest
+			}
+		}
+		opts, err := signer.verify(key, func(name string) string { return c.Query(name) })
+		if err != nil {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+
+		size, err := f.Size(key)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey) {
+			return fiber.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		reader, err := f.GetReader(key)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey) {
+			return fiber.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		contentType := opts.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(key))
+		}
+		if contentType != "" {
+			c.Set(fiber.HeaderContentType, contentType)
+		}
+		if opts.Filename != "" {
+			c.Set(fiber.HeaderContentDisposition, contentDisposition(opts.Filename))
+		}
+		c.Set(fiber.HeaderCacheControl, "private, no-store")
+		// fasthttp 在响应写出后关闭实现了 io.Closer 的 reader
+		return c.SendStream(reader, int(size))
+	}
+}
+
+// RegisterRoutes 为 options 中配置了 url 的磁盘注册签名链接路由，路由路径取 url 的路径部分
+// 例如 url 为 https://example.com/files 时注册 GET /files/*
+// url 没有路径部分（如 https://cdn.example.com）时注册的路由会覆盖所有 GET 请求，因此跳过该磁盘并返回错误
+func (f *Filesystem) RegisterRoutes(router fiber.Router) error {
+	var errs []error
+	for name, disk := range f.Config.Filesystem.Disks {
+		options, _ := disk.Options.(map[string]any)
+		base, _ := options["url"].(string)
+		if base == "" {
+			continue
+		}
+		parsed, err := url.Parse(base)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("磁盘 '%s' 的 url 无效: %w", name, err))
+			continue
+		}
+		prefix := strings.TrimSuffix(parsed.Path, "/")
+		if prefix == "" {
+			errs = append(errs, fmt.Errorf("磁盘 '%s' 的 url 缺少路径部分，如 %s/files", name, strings.TrimSuffix(base, "/")))
+			continue
+		}
+		fs, err := f.Disk(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		router.Get(prefix+"/*", fs.TemporaryURLHandler())
+	}
+	return errors.Join(errs...)
+}
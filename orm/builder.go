@@ -0,0 +1,587 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prefixPlaceholder 原始 SQL 片段中的表前缀占位符，与迁移脚本一致
+const prefixPlaceholder = "{prefix}"
+
+var (
+	// ErrNoTable 未指定表名
+	ErrNoTable = errors.New("未指定表名")
+	// ErrNoConnection 构建器未绑定连接，只能生成 SQL，不能执行
+	ErrNoConnection = errors.New("查询构建器未绑定数据库连接")
+)
+
+// Builder SQL 构建器，自动为表名添加连接的表前缀并按方言生成占位符
+//
+// 表名参数（From、Join、Insert、Update、Delete）会自动加前缀与引号，
+// 支持 "users u" 或 "users AS u" 形式的别名；条件、列等原始 SQL 片段中
+// 统一使用 ? 作为占位符，其中的 {prefix} 会替换为表前缀
+type Builder struct {
+	dialect Dialect
+	prefix  string
+	conn    *Connection
+}
+
+// NewBuilder 创建不绑定连接的构建器，只用于生成 SQL
+func NewBuilder(dialect Dialect, prefix string) *Builder {
+	return &Builder{dialect: dialect, prefix: prefix}
+}
+
+// Builder 返回绑定到该连接的构建器，执行时使用 Querier(ctx)，在事务中自动使用事务
+func (c *Connection) Builder() *Builder {
+	return &Builder{dialect: c.Dialect(), prefix: c.config.TablePrefix, conn: c}
+}
+
+// Builder 返回绑定到默认连接的构建器
+func (m *DBManager) Builder() *Builder {
+	conn, err := m.Connection()
+	if err != nil {
+		return NewBuilder(m.Dialect(), m.config.TablePrefix)
+	}
+	return conn.Builder()
+}
+
+// Dialect 返回构建器使用的方言
+func (b *Builder) Dialect() Dialect {
+	return b.dialect
+}
+
+// Table 返回加上前缀与引号的表名，支持 schema.table 形式
+func (b *Builder) Table(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return b.dialect.Quote(name[:i]) + "." + b.dialect.Quote(b.prefix+name[i+1:])
+	}
+	return b.dialect.Quote(b.prefix + name)
+}
+
+// Select 开始构建 SELECT 语句，未指定列时查询全部列
+func (b *Builder) Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{builder: b, columns: columns}
+}
+
+// Insert 开始构建 INSERT 语句
+func (b *Builder) Insert(table string) *InsertBuilder {
+	return &InsertBuilder{builder: b, table: table}
+}
+
+// Update 开始构建 UPDATE 语句
+func (b *Builder) Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{builder: b, table: table}
+}
+
+// Delete 开始构建 DELETE 语句
+func (b *Builder) Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{builder: b, table: table}
+}
+
+// tableRef 表名加前缀与引号，保留别名；以括号开头的子查询原样返回
+func (b *Builder) tableRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "(") {
+		return b.raw(ref)
+	}
+	fields := strings.Fields(ref)
+	if len(fields) == 0 {
+		return ""
+	}
+	table := b.Table(fields[0])
+	if len(fields) > 1 {
+		return table + " " + strings.Join(fields[1:], " ")
+	}
+	return table
+}
+
+// raw 替换原始 SQL 片段中的表前缀占位符
+func (b *Builder) raw(fragment string) string {
+	return strings.ReplaceAll(fragment, prefixPlaceholder, b.prefix)
+}
+
+func (b *Builder) querier(ctx context.Context) (Querier, error) {
+	if b.conn == nil {
+		return nil, ErrNoConnection
+	}
+	return b.conn.Querier(ctx), nil
+}
+
+// condition WHERE 或 HAVING 中的一个条件
+type condition struct {
+	or   bool
+	expr string
+	args []any
+}
+
+// conditions 按顺序以 AND、OR 连接的条件列表
+type conditions []condition
+
+func (c conditions) write(b *Builder, keyword string, sb *strings.Builder, args *[]any) {
+	if len(c) == 0 {
+		return
+	}
+	sb.WriteString(" " + keyword + " ")
+	for i, cond := range c {
+		if i > 0 {
+			if cond.or {
+				sb.WriteString(" OR ")
+			} else {
+				sb.WriteString(" AND ")
+			}
+		}
+		if len(c) > 1 {
+			sb.WriteString("(" + b.raw(cond.expr) + ")")
+		} else {
+			sb.WriteString(b.raw(cond.expr))
+		}
+		*args = append(*args, cond.args...)
+	}
+}
+
+// whereIn 生成 column IN (?, ...)，值为空时生成恒假条件
+func whereIn(column string, values []any, not bool) condition {
+	if len(values) == 0 {
+		if not {
+			return condition{expr: "1 = 1"}
+		}
+		return condition{expr: "1 = 0"}
+	}
+	op := " IN ("
+	if not {
+		op = " NOT IN ("
+	}
+	return condition{
+		expr: column + op + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")",
+		args: values,
+	}
+}
+
+// join JOIN 子句
+type join struct {
+	kind  string
+	table string
+	on    string
+	args  []any
+}
+
+// SelectBuilder SELECT 语句构建器
+type SelectBuilder struct {
+	builder  *Builder
+	distinct bool
+	columns  []string
+	from     string
+	joins    []join
+	where    conditions
+	groupBy  []string
+	having   conditions
+	orderBy  []string
+	limit    int
+	offset   int
+}
+
+// Distinct 去重
+func (s *SelectBuilder) Distinct() *SelectBuilder {
+	s.distinct = true
+	return s
+}
+
+// Columns 追加查询的列
+func (s *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	s.columns = append(s.columns, columns...)
+	return s
+}
+
+// From 设置查询的表
+func (s *SelectBuilder) From(table string) *SelectBuilder {
+	s.from = table
+	return s
+}
+
+// Join 内连接
+func (s *SelectBuilder) Join(table, on string, args ...any) *SelectBuilder {
+	return s.addJoin("JOIN", table, on, args)
+}
+
+// LeftJoin 左连接
+func (s *SelectBuilder) LeftJoin(table, on string, args ...any) *SelectBuilder {
+	return s.addJoin("LEFT JOIN", table, on, args)
+}
+
+// RightJoin 右连接
+func (s *SelectBuilder) RightJoin(table, on string, args ...any) *SelectBuilder {
+	return s.addJoin("RIGHT JOIN", table, on, args)
+}
+
+func (s *SelectBuilder) addJoin(kind, table, on string, args []any) *SelectBuilder {
+	s.joins = append(s.joins, join{kind: kind, table: table, on: on, args: args})
+	return s
+}
+
+// Where 以 AND 追加条件
+func (s *SelectBuilder) Where(expr string, args ...any) *SelectBuilder {
+	s.where = append(s.where, condition{expr: expr, args: args})
+	return s
+}
+
+// OrWhere 以 OR 追加条件
+func (s *SelectBuilder) OrWhere(expr string, args ...any) *SelectBuilder {
+	s.where = append(s.where, condition{or: true, expr: expr, args: args})
+	return s
+}
+
+// WhereIn 以 AND 追加 IN 条件
+func (s *SelectBuilder) WhereIn(column string, values ...any) *SelectBuilder {
+	s.where = append(s.where, whereIn(column, values, false))
+	return s
+}
+
+// WhereNotIn 以 AND 追加 NOT IN 条件
+func (s *SelectBuilder) WhereNotIn(column string, values ...any) *SelectBuilder {
+	s.where = append(s.where, whereIn(column, values, true))
+	return s
+}
+
+// GroupBy 分组
+func (s *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	s.groupBy = append(s.groupBy, columns...)
+	return s
+}
+
+// Having 以 AND 追加分组条件
+func (s *SelectBuilder) Having(expr string, args ...any) *SelectBuilder {
+	s.having = append(s.having, condition{expr: expr, args: args})
+	return s
+}
+
+// OrderBy 排序，如 "id DESC"
+func (s *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	s.orderBy = append(s.orderBy, exprs...)
+	return s
+}
+
+// Limit 限制返回行数，小于等于 0 时不限制
+func (s *SelectBuilder) Limit(n int) *SelectBuilder {
+	s.limit = n
+	return s
+}
+
+// Offset 跳过的行数
+func (s *SelectBuilder) Offset(n int) *SelectBuilder {
+	s.offset = n
+	return s
+}
+
+// ToSQL 生成 SQL 与参数
+func (s *SelectBuilder) ToSQL() (string, []any, error) {
+	b := s.builder
+	if s.from == "" {
+		return "", nil, ErrNoTable
+	}
+	var sb strings.Builder
+	var args []any
+
+	sb.WriteString("SELECT ")
+	if s.distinct {
+		sb.WriteString("DISTINCT ")
+	}
+	if len(s.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(b.raw(strings.Join(s.columns, ", ")))
+	}
+	sb.WriteString(" FROM " + b.tableRef(s.from))
+	for _, j := range s.joins {
+		sb.WriteString(" " + j.kind + " " + b.tableRef(j.table))
+		if j.on != "" {
+			sb.WriteString(" ON " + b.raw(j.on))
+		}
+		args = append(args, j.args...)
+	}
+	s.where.write(b, "WHERE", &sb, &args)
+	if len(s.groupBy) > 0 {
+		sb.WriteString(" GROUP BY " + b.raw(strings.Join(s.groupBy, ", ")))
+	}
+	s.having.write(b, "HAVING", &sb, &args)
+	if len(s.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + b.raw(strings.Join(s.orderBy, ", ")))
+	}
+	if s.limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.Itoa(s.limit))
+	}
+	if s.offset > 0 {
+		if s.limit <= 0 && b.dialect.Driver != DriverPostgres {
+			// MySQL 与 SQLite 不支持单独使用 OFFSET
+			sb.WriteString(" LIMIT " + strconv.FormatInt(1<<63-1, 10))
+		}
+		sb.WriteString(" OFFSET " + strconv.Itoa(s.offset))
+	}
+	return b.dialect.Rebind(sb.String()), args, nil
+}
+
+// Query 执行查询
+func (s *SelectBuilder) Query(ctx context.Context) (*sql.Rows, error) {
+	q, err := s.builder.querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := s.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args...)
+}
+
+// QueryRow 执行单行查询，dest 依次对应查询的列
+func (s *SelectBuilder) QueryRow(ctx context.Context, dest ...any) error {
+	q, err := s.builder.querier(ctx)
+	if err != nil {
+		return err
+	}
+	query, args, err := s.ToSQL()
+	if err != nil {
+		return err
+	}
+	return q.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// InsertBuilder INSERT 语句构建器
+type InsertBuilder struct {
+	builder   *Builder
+	table     string
+	columns   []string
+	rows      [][]any
+	returning []string
+}
+
+// Columns 设置插入的列
+func (i *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	i.columns = columns
+	return i
+}
+
+// Values 追加一行值，顺序与 Columns 一致
+func (i *InsertBuilder) Values(values ...any) *InsertBuilder {
+	i.rows = append(i.rows, values)
+	return i
+}
+
+// SetMap 按列名设置单行值，列按名称排序以保证生成的 SQL 稳定
+func (i *InsertBuilder) SetMap(values map[string]any) *InsertBuilder {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	row := make([]any, len(columns))
+	for n, column := range columns {
+		row[n] = values[column]
+	}
+	i.columns = columns
+	i.rows = [][]any{row}
+	return i
+}
+
+// Returning 返回插入后的列，适用于 Postgres 与 SQLite 3.35+
+func (i *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	i.returning = columns
+	return i
+}
+
+// ToSQL 生成 SQL 与参数
+func (i *InsertBuilder) ToSQL() (string, []any, error) {
+	b := i.builder
+	if i.table == "" {
+		return "", nil, ErrNoTable
+	}
+	if len(i.columns) == 0 || len(i.rows) == 0 {
+		return "", nil, errors.New("INSERT 未指定列或值")
+	}
+	quoted := make([]string, len(i.columns))
+	for n, column := range i.columns {
+		quoted[n] = b.dialect.Quote(column)
+	}
+
+	var sb strings.Builder
+	var args []any
+	sb.WriteString("INSERT INTO " + b.Table(i.table) + " (" + strings.Join(quoted, ", ") + ") VALUES ")
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(i.columns)), ", ") + ")"
+	for n, row := range i.rows {
+		if len(row) != len(i.columns) {
+			return "", nil, fmt.Errorf("INSERT 第 %d 行有 %d 个值，期望 %d 个", n+1, len(row), len(i.columns))
+		}
+		if n > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(placeholders)
+		args = append(args, row...)
+	}
+	if len(i.returning) > 0 {
+		sb.WriteString(" RETURNING " + b.raw(strings.Join(i.returning, ", ")))
+	}
+	return b.dialect.Rebind(sb.String()), args, nil
+}
+
+// Exec 执行插入
+func (i *InsertBuilder) Exec(ctx context.Context) (sql.Result, error) {
+	return execStatement(ctx, i.builder, i.ToSQL)
+}
+
+// QueryRow 执行带 Returning 的插入并读取返回的列
+func (i *InsertBuilder) QueryRow(ctx context.Context, dest ...any) error {
+	q, err := i.builder.querier(ctx)
+	if err != nil {
+		return err
+	}
+	query, args, err := i.ToSQL()
+	if err != nil {
+		return err
+	}
+	return q.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// assignment UPDATE 中的一个赋值
+type assignment struct {
+	column string
+	expr   string
+	args   []any
+}
+
+// UpdateBuilder UPDATE 语句构建器
+type UpdateBuilder struct {
+	builder *Builder
+	table   string
+	sets    []assignment
+	where   conditions
+}
+
+// Set 设置列的值
+func (u *UpdateBuilder) Set(column string, value any) *UpdateBuilder {
+	u.sets = append(u.sets, assignment{column: column, expr: "?", args: []any{value}})
+	return u
+}
+
+// SetExpr 使用表达式设置列，如 SetExpr("hits", "hits + ?", 1)
+func (u *UpdateBuilder) SetExpr(column, expr string, args ...any) *UpdateBuilder {
+	u.sets = append(u.sets, assignment{column: column, expr: expr, args: args})
+	return u
+}
+
+// SetMap 按列名设置多个值，列按名称排序以保证生成的 SQL 稳定
+func (u *UpdateBuilder) SetMap(values map[string]any) *UpdateBuilder {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		u.Set(column, values[column])
+	}
+	return u
+}
+
+// Where 以 AND 追加条件
+func (u *UpdateBuilder) Where(expr string, args ...any) *UpdateBuilder {
+	u.where = append(u.where, condition{expr: expr, args: args})
+	return u
+}
+
+// OrWhere 以 OR 追加条件
+func (u *UpdateBuilder) OrWhere(expr string, args ...any) *UpdateBuilder {
+	u.where = append(u.where, condition{or: true, expr: expr, args: args})
+	return u
+}
+
+// WhereIn 以 AND 追加 IN 条件
+func (u *UpdateBuilder) WhereIn(column string, values ...any) *UpdateBuilder {
+	u.where = append(u.where, whereIn(column, values, false))
+	return u
+}
+
+// ToSQL 生成 SQL 与参数
+func (u *UpdateBuilder) ToSQL() (string, []any, error) {
+	b := u.builder
+	if u.table == "" {
+		return "", nil, ErrNoTable
+	}
+	if len(u.sets) == 0 {
+		return "", nil, errors.New("UPDATE 未设置任何列")
+	}
+	var sb strings.Builder
+	var args []any
+	sb.WriteString("UPDATE " + b.tableRef(u.table) + " SET ")
+	for n, set := range u.sets {
+		if n > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(b.dialect.Quote(set.column) + " = " + b.raw(set.expr))
+		args = append(args, set.args...)
+	}
+	u.where.write(b, "WHERE", &sb, &args)
+	return b.dialect.Rebind(sb.String()), args, nil
+}
+
+// Exec 执行更新
+func (u *UpdateBuilder) Exec(ctx context.Context) (sql.Result, error) {
+	return execStatement(ctx, u.builder, u.ToSQL)
+}
+
+// DeleteBuilder DELETE 语句构建器
+type DeleteBuilder struct {
+	builder *Builder
+	table   string
+	where   conditions
+}
+
+// Where 以 AND 追加条件
+func (d *DeleteBuilder) Where(expr string, args ...any) *DeleteBuilder {
+	d.where = append(d.where, condition{expr: expr, args: args})
+	return d
+}
+
+// OrWhere 以 OR 追加条件
+func (d *DeleteBuilder) OrWhere(expr string, args ...any) *DeleteBuilder {
+	d.where = append(d.where, condition{or: true, expr: expr, args: args})
+	return d
+}
+
+// WhereIn 以 AND 追加 IN 条件
+func (d *DeleteBuilder) WhereIn(column string, values ...any) *DeleteBuilder {
+	d.where = append(d.where, whereIn(column, values, false))
+	return d
+}
+
+// ToSQL 生成 SQL 与参数
+func (d *DeleteBuilder) ToSQL() (string, []any, error) {
+	b := d.builder
+	if d.table == "" {
+		return "", nil, ErrNoTable
+	}
+	var sb strings.Builder
+	var args []any
+	sb.WriteString("DELETE FROM " + b.tableRef(d.table))
+	d.where.write(b, "WHERE", &sb, &args)
+	return b.dialect.Rebind(sb.String()), args, nil
+}
+
+// Exec 执行删除
+func (d *DeleteBuilder) Exec(ctx context.Context) (sql.Result, error) {
+	return execStatement(ctx, d.builder, d.ToSQL)
+}
+
+func execStatement(ctx context.Context, b *Builder, toSQL func() (string, []any, error)) (sql.Result, error) {
+	q, err := b.querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := toSQL()
+	if err != nil {
+		return nil, err
+	}
+	return q.ExecContext(ctx, query, args...)
+}
@@ -0,0 +1,129 @@
+package orm_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	cmform "github.com/wuwuseo/cmf/orm"
+)
+
+func assertSQL(t *testing.T, gotSQL string, gotArgs []any, err error, wantSQL string, wantArgs ...any) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("生成 SQL 失败: %v", err)
+	}
+	if gotSQL != wantSQL {
+		t.Errorf("SQL 不正确:\n得到 %s\n期望 %s", gotSQL, wantSQL)
+	}
+	if len(gotArgs) != 0 || len(wantArgs) != 0 {
+		if !reflect.DeepEqual(gotArgs, wantArgs) {
+			t.Errorf("参数不正确: 得到 %v，期望 %v", gotArgs, wantArgs)
+		}
+	}
+}
+
+// TestBuilder_Select 测试 SELECT 语句的表前缀与占位符
+func TestBuilder_Select(t *testing.T) {
+	build := func(b *cmform.Builder) *cmform.SelectBuilder {
+		return b.Select("u.id", "COUNT(p.id) AS posts").
+			From("users u").
+			LeftJoin("posts AS p", "p.user_id = u.id AND p.status = ?", "published").
+			Where("u.age > ?", 18).
+			OrWhere("u.name = 'who?'").
+			WhereIn("u.role", "admin", "editor").
+			GroupBy("u.id").
+			Having("COUNT(p.id) > ?", 1).
+			OrderBy("u.id DESC").
+			Limit(10).
+			Offset(20)
+	}
+
+	sql, args, err := build(cmform.NewBuilder(cmform.DialectFor("mysql"), "cmf_")).ToSQL()
+	assertSQL(t, sql, args, err,
+		"SELECT u.id, COUNT(p.id) AS posts FROM `cmf_users` u LEFT JOIN `cmf_posts` AS p ON p.user_id = u.id AND p.status = ?"+
+			" WHERE (u.age > ?) OR (u.name = 'who?') AND (u.role IN (?, ?)) GROUP BY u.id HAVING COUNT(p.id) > ? ORDER BY u.id DESC LIMIT 10 OFFSET 20",
+		"published", 18, "admin", "editor", 1)
+
+	sql, args, err = build(cmform.NewBuilder(cmform.DialectFor("postgres"), "cmf_")).ToSQL()
+	assertSQL(t, sql, args, err,
+		`SELECT u.id, COUNT(p.id) AS posts FROM "cmf_users" u LEFT JOIN "cmf_posts" AS p ON p.user_id = u.id AND p.status = $1`+
+			` WHERE (u.age > $2) OR (u.name = 'who?') AND (u.role IN ($3, $4)) GROUP BY u.id HAVING COUNT(p.id) > $5 ORDER BY u.id DESC LIMIT 10 OFFSET 20`,
+		"published", 18, "admin", "editor", 1)
+}
+
+// TestBuilder_Statements 测试 INSERT、UPDATE、DELETE 语句
+func TestBuilder_Statements(t *testing.T) {
+	pg := cmform.NewBuilder(cmform.DialectFor("pgx"), "cmf_")
+
+	sql, args, err := pg.Insert("users").Columns("name", "age").Values("a", 1).Values("b", 2).Returning("id").ToSQL()
+	assertSQL(t, sql, args, err, `INSERT INTO "cmf_users" ("name", "age") VALUES ($1, $2), ($3, $4) RETURNING id`, "a", 1, "b", 2)
+
+	sql, args, err = pg.Update("public.users").SetMap(map[string]any{"name": "c", "age": 3}).
+		SetExpr("hits", "hits + ?", 1).Where("id = ?", 7).ToSQL()
+	assertSQL(t, sql, args, err, `UPDATE "public"."cmf_users" SET "age" = $1, "name" = $2, "hits" = hits + $3 WHERE id = $4`, 3, "c", 1, 7)
+
+	sql, args, err = pg.Delete("users").Where("id IN (SELECT user_id FROM {prefix}bans WHERE until > ?)", 100).ToSQL()
+	assertSQL(t, sql, args, err, `DELETE FROM "cmf_users" WHERE id IN (SELECT user_id FROM cmf_bans WHERE until > $1)`, 100)
+
+	sql, args, err = pg.Select().From("users").WhereIn("id").ToSQL()
+	assertSQL(t, sql, args, err, `SELECT * FROM "cmf_users" WHERE 1 = 0`)
+
+	if _, _, err := pg.Select().ToSQL(); !errors.Is(err, cmform.ErrNoTable) {
+		t.Errorf("未指定表名应返回 ErrNoTable，得到 %v", err)
+	}
+	if _, _, err := pg.Insert("users").Columns("a", "b").Values(1).ToSQL(); err == nil {
+		t.Error("值数量与列不一致时应返回错误")
+	}
+	if _, _, err := pg.Update("users").ToSQL(); err == nil {
+		t.Error("UPDATE 未设置列时应返回错误")
+	}
+	if _, err := pg.Delete("users").Exec(context.Background()); !errors.Is(err, cmform.ErrNoConnection) {
+		t.Errorf("未绑定连接时执行应返回 ErrNoConnection，得到 %v", err)
+	}
+}
+
+// TestBuilder_ExecuteOnConnection 测试在连接与事务中执行构建的语句
+func TestBuilder_ExecuteOnConnection(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "builder.db"), TablePrefix: "t_"},
+	}
+	manager, err := cmform.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+	if _, err := manager.GetDB().Exec("CREATE TABLE t_users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	b := manager.Builder()
+	err = manager.WithTx(ctx, nil, func(ctx context.Context) error {
+		if _, err := b.Insert("users").Columns("name").Values("a").Values("b").Exec(ctx); err != nil {
+			return err
+		}
+		_, err := b.Update("users").Set("name", "c").Where("name = ?", "b").Exec(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("事务中执行失败: %v", err)
+	}
+
+	var name string
+	if err := b.Select("name").From("users").OrderBy("id DESC").Limit(1).QueryRow(ctx, &name); err != nil || name != "c" {
+		t.Errorf("查询结果不正确: %q %v", name, err)
+	}
+	if _, err := b.Delete("users").Where("name = ?", "a").Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	b.Select("COUNT(*)").From("users").QueryRow(ctx, &count)
+	if count != 1 {
+		t.Errorf("删除后应剩 1 行，实际 %d 行", count)
+	}
+}
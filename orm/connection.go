@@ -0,0 +1,284 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wuwuseo/cmf/config"
+)
+
+var (
+	// replicaHealthInterval 副本健康检查间隔
+	replicaHealthInterval = 10 * time.Second
+	// replicaPingTimeout 单次健康检查超时时间
+	replicaPingTimeout = 3 * time.Second
+)
+
+// Connection 命名数据库连接，包含主库与可选的只读副本
+// 写操作与事务使用主库；读操作轮询可用副本，副本不可用时被摘除并回退主库，
+// 后台健康检查恢复后重新加入
+type Connection struct {
+	name     string
+	config   config.Database
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// replica 只读副本
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// openConnection 打开主库与全部副本，并启动副本健康检查
+func openConnection(name string, dbConfig config.Database) (*Connection, error) {
+	primary, err := openPool(name, dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库连接 %s 失败: %w", name, err)
+	}
+	conn := &Connection{
+		name:    name,
+		config:  dbConfig,
+		primary: primary,
+		stop:    make(chan struct{}),
+	}
+	for i, replicaConfig := range dbConfig.Replicas {
+		db, err := openPool(fmt.Sprintf("%s.replica%d", name, i), mergeReplicaConfig(dbConfig, replicaConfig))
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("打开数据库连接 %s 的副本 %d 失败: %w", name, i, err)
+		}
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		conn.replicas = append(conn.replicas, r)
+	}
+	if len(conn.replicas) > 0 {
+		conn.wg.Add(1)
+		go conn.healthLoop()
+	}
+	return conn, nil
+}
+
+// openPool 打开连接池并配置连接池参数，name 用于慢查询日志与统计
+func openPool(name string, dbConfig config.Database) (*sql.DB, error) {
+	slowThreshold := defaultSlowThreshold
+	if dbConfig.SlowThreshold != 0 {
+		slowThreshold = time.Duration(dbConfig.SlowThreshold) * time.Millisecond
+	}
+	dsn, err := BuildDSN(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	db, err := GetSqlDb(dbConfig.Driver, dsn, WithConnectionName(name), WithSlowThreshold(slowThreshold))
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	}
+	if dbConfig.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	}
+	if dbConfig.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(dbConfig.ConnMaxLifetime) * time.Second)
+	}
+	if dbConfig.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(time.Duration(dbConfig.ConnMaxIdleTime) * time.Second)
+	}
+	return db, nil
+}
+
+// mergeReplicaConfig 副本未填写的字段沿用主库配置
+func mergeReplicaConfig(primary config.Database, replica config.Database) config.Database {
+	merged := primary
+	merged.Replicas = nil
+	// 完整的 DSN 与 Unix socket 只属于主库，副本需要单独配置
+	merged.DSN = replica.DSN
+	if replica.Host != "" {
+		merged.Host = replica.Host
+		merged.Socket = replica.Socket
+	}
+	if replica.Socket != "" {
+		merged.Socket = replica.Socket
+	}
+	if replica.Port != 0 {
+		merged.Port = replica.Port
+	}
+	if replica.User != "" {
+		merged.User = replica.User
+	}
+	if replica.Password != "" {
+		merged.Password = replica.Password
+	}
+	if replica.Name != "" {
+		merged.Name = replica.Name
+	}
+	if replica.SSLMode != "" {
+		merged.SSLMode = replica.SSLMode
+	}
+	if replica.MaxOpenConns != 0 {
+		merged.MaxOpenConns = replica.MaxOpenConns
+	}
+	if replica.MaxIdleConns != 0 {
+		merged.MaxIdleConns = replica.MaxIdleConns
+	}
+	if replica.ConnMaxLifetime != 0 {
+		merged.ConnMaxLifetime = replica.ConnMaxLifetime
+	}
+	if replica.ConnMaxIdleTime != 0 {
+		merged.ConnMaxIdleTime = replica.ConnMaxIdleTime
+	}
+	if replica.SlowThreshold != 0 {
+		merged.SlowThreshold = replica.SlowThreshold
+	}
+	return merged
+}
+
+// Name 返回连接名称
+func (c *Connection) Name() string {
+	return c.name
+}
+
+// Config 返回连接配置
+func (c *Connection) Config() config.Database {
+	return c.config
+}
+
+// Dialect 返回连接的 SQL 方言
+func (c *Connection) Dialect() Dialect {
+	return DialectFor(c.config.Driver)
+}
+
+// Primary 返回主库连接池
+func (c *Connection) Primary() *sql.DB {
+	return c.primary
+}
+
+// Writer 返回写操作使用的连接池，即主库
+func (c *Connection) Writer() *sql.DB {
+	return c.primary
+}
+
+// Reader 返回读操作使用的连接池，轮询可用副本，无可用副本时返回主库
+func (c *Connection) Reader() *sql.DB {
+	if r := c.pickReplica(); r != nil {
+		return r.db
+	}
+	return c.primary
+}
+
+func (c *Connection) pickReplica() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := c.next.Add(1)
+	for i := range n {
+		r := c.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// QueryContext 在副本上执行查询
+// 查询失败且副本 Ping 不通时摘除该副本，并在主库上重试
+func (c *Connection) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	r := c.pickReplica()
+	if r == nil {
+		return c.primary.QueryContext(ctx, query, args...)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err == nil || ctx.Err() != nil || !c.eject(ctx, r) {
+		return rows, err
+	}
+	return c.primary.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext 在副本上执行单行查询，错误在 Scan 时返回
+func (c *Connection) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.Reader().QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext 在主库上执行写操作
+func (c *Connection) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+// BeginTx 在主库上开启事务
+func (c *Connection) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return c.primary.BeginTx(ctx, opts)
+}
+
+// Ping 检查主库连接
+func (c *Connection) Ping(ctx context.Context) error {
+	return c.primary.PingContext(ctx)
+}
+
+// HealthyReplicas 返回当前可用的副本数量
+func (c *Connection) HealthyReplicas() int {
+	n := 0
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// eject 副本 Ping 不通时将其摘除，返回是否已摘除
+func (c *Connection) eject(ctx context.Context, r *replica) bool {
+	pingCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), replicaPingTimeout)
+	defer cancel()
+	if err := r.db.PingContext(pingCtx); err == nil {
+		return false
+	}
+	r.healthy.Store(false)
+	return true
+}
+
+// healthLoop 定期检查副本，Ping 失败时摘除，恢复后重新加入
+func (c *Connection) healthLoop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(replicaHealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		for _, r := range c.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+			r.healthy.Store(r.db.PingContext(ctx) == nil)
+			cancel()
+		}
+	}
+}
+
+// Close 停止健康检查并关闭主库与全部副本
+func (c *Connection) Close() error {
+	var errs []error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.wg.Wait()
+		if c.primary != nil {
+			errs = append(errs, c.primary.Close())
+		}
+		for _, r := range c.replicas {
+			errs = append(errs, r.db.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
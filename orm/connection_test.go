@@ -0,0 +1,158 @@
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	cmform "github.com/wuwuseo/cmf/orm"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteFile 创建带有标记表的 SQLite 数据库文件，用于区分主库与副本
+func newSQLiteFile(t *testing.T, dir string, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE node (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO node (name) VALUES (?)", name); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func queryNode(t *testing.T, conn *cmform.Connection) string {
+	t.Helper()
+	rows, err := conn.QueryContext(context.Background(), "SELECT name FROM node")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	defer rows.Close()
+	var name string
+	if rows.Next() {
+		rows.Scan(&name)
+	}
+	return name
+}
+
+func newReplicaConfig(t *testing.T, replicas ...string) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	primary := config.Database{Driver: "sqlite", Host: newSQLiteFile(t, dir, "primary")}
+	for _, name := range replicas {
+		host := filepath.Join(dir, "missing", name+".db")
+		if name != "broken" {
+			host = newSQLiteFile(t, dir, name)
+		}
+		primary.Replicas = append(primary.Replicas, config.Database{Host: host})
+	}
+	cfg := &config.Config{}
+	cfg.Database.Default = "main"
+	cfg.Database.Connections = map[string]config.Database{
+		"main":      primary,
+		"analytics": {Driver: "sqlite", Host: newSQLiteFile(t, dir, "analytics")},
+	}
+	return cfg
+}
+
+// TestDBManager_NamedConnections 测试命名连接按需打开并复用
+func TestDBManager_NamedConnections(t *testing.T) {
+	manager, err := cmform.NewDBManager(newReplicaConfig(t))
+	if err != nil {
+		t.Fatalf("NewDBManager 失败: %v", err)
+	}
+	defer manager.Close()
+
+	main, err := manager.Connection()
+	if err != nil || main.Primary() != manager.GetDB() {
+		t.Fatalf("默认连接应与 GetDB 一致: %v", err)
+	}
+	analytics, err := manager.Connection("analytics")
+	if err != nil {
+		t.Fatalf("获取命名连接失败: %v", err)
+	}
+	if again, _ := manager.Connection("analytics"); again != analytics {
+		t.Error("相同名称应返回同一连接")
+	}
+	if got := queryNode(t, analytics); got != "analytics" {
+		t.Errorf("命名连接应使用自身配置，查询到 %q", got)
+	}
+	if _, err := manager.Connection("missing"); err == nil {
+		t.Error("不存在的连接应返回错误")
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+	if err := analytics.Ping(context.Background()); err == nil {
+		t.Error("Close 后命名连接应被关闭")
+	}
+	if _, err := manager.Connection("analytics"); err == nil {
+		t.Error("Close 后获取连接应返回错误")
+	}
+}
+
+// TestConnection_ReadWriteSplit 测试读操作轮询副本，写操作使用主库
+func TestConnection_ReadWriteSplit(t *testing.T) {
+	manager, err := cmform.NewDBManager(newReplicaConfig(t, "replica1", "replica2"))
+	if err != nil {
+		t.Fatalf("NewDBManager 失败: %v", err)
+	}
+	defer manager.Close()
+	conn, _ := manager.Connection()
+
+	seen := map[string]int{}
+	for range 4 {
+		seen[queryNode(t, conn)]++
+	}
+	if seen["replica1"] != 2 || seen["replica2"] != 2 {
+		t.Errorf("读操作应在副本间轮询，得到 %v", seen)
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "UPDATE node SET name = 'written'"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	conn.Writer().QueryRow("SELECT name FROM node").Scan(&name)
+	if name != "written" {
+		t.Error("写操作应在主库执行")
+	}
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.QueryRow("SELECT name FROM node").Scan(&name)
+	tx.Rollback()
+	if name != "written" {
+		t.Error("事务应在主库执行")
+	}
+}
+
+// TestConnection_EjectsUnhealthyReplica 测试不可用的副本被摘除并回退主库
+func TestConnection_EjectsUnhealthyReplica(t *testing.T) {
+	manager, err := cmform.NewDBManager(newReplicaConfig(t, "broken"))
+	if err != nil {
+		t.Fatalf("NewDBManager 失败: %v", err)
+	}
+	defer manager.Close()
+	conn, _ := manager.Connection()
+
+	if got := queryNode(t, conn); got != "primary" {
+		t.Errorf("副本不可用时应回退主库，查询到 %q", got)
+	}
+	if conn.HealthyReplicas() != 0 {
+		t.Error("不可用的副本应被摘除")
+	}
+	if conn.Reader() != conn.Primary() {
+		t.Error("无可用副本时 Reader 应返回主库")
+	}
+}
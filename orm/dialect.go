@@ -0,0 +1,94 @@
+package orm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 支持的数据库驱动名称
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+)
+
+// NormalizeDriver 将驱动别名统一为 DriverMySQL、DriverPostgres 或 DriverSQLite
+// 例如 pgx、postgresql 视为 postgres，sqlite（modernc.org/sqlite）视为 sqlite3，
+// 无法识别的驱动原样返回
+func NormalizeDriver(driver string) string {
+	switch strings.ToLower(driver) {
+	case "mysql":
+		return DriverMySQL
+	case "postgres", "postgresql", "pgx", "pq":
+		return DriverPostgres
+	case "sqlite", "sqlite3":
+		return DriverSQLite
+	default:
+		return driver
+	}
+}
+
+// Dialect 不同数据库之间的 SQL 方言差异
+type Dialect struct {
+	Driver string // 规范化后的驱动名称
+}
+
+// DialectFor 返回驱动对应的方言
+func DialectFor(driver string) Dialect {
+	return Dialect{Driver: NormalizeDriver(driver)}
+}
+
+// Placeholder 返回第 n 个（从 1 开始）参数的占位符，Postgres 为 $n，其他为 ?
+func (d Dialect) Placeholder(n int) string {
+	if d.Driver == DriverPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Quote 为标识符加引号，支持 schema.table 形式，MySQL 使用反引号，其他使用双引号
+func (d Dialect) Quote(ident string) string {
+	quote := `"`
+	if d.Driver == DriverMySQL {
+		quote = "`"
+	}
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// Rebind 将查询中的 ? 占位符转换为方言的占位符，忽略引号内的 ?
+// 非 Postgres 方言原样返回
+func (d Dialect) Rebind(query string) string {
+	if d.Driver != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '\'', '"', '`':
+			end := i + 1
+			for end < len(query) && query[end] != c {
+				end++
+			}
+			if end < len(query) {
+				end++
+			}
+			b.WriteString(query[i:end])
+			i = end - 1
+		case '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package orm_test
+
+import (
+	"testing"
+
+	cmform "github.com/wuwuseo/cmf/orm"
+)
+
+// TestNormalizeDriver 测试驱动别名规范化
+func TestNormalizeDriver(t *testing.T) {
+	cases := map[string]string{
+		"mysql":      cmform.DriverMySQL,
+		"pgx":        cmform.DriverPostgres,
+		"postgresql": cmform.DriverPostgres,
+		"sqlite":     cmform.DriverSQLite,
+		"sqlite3":    cmform.DriverSQLite,
+		"oracle":     "oracle",
+	}
+	for driver, want := range cases {
+		if got := cmform.NormalizeDriver(driver); got != want {
+			t.Errorf("NormalizeDriver(%q) = %q, 期望 %q", driver, got, want)
+		}
+	}
+}
+
+// TestDialect_PlaceholderAndQuote 测试占位符与标识符引号
+func TestDialect_PlaceholderAndQuote(t *testing.T) {
+	mysql := cmform.DialectFor("mysql")
+	postgres := cmform.DialectFor("pgx")
+	sqlite := cmform.DialectFor("sqlite")
+
+	if mysql.Placeholder(2) != "?" || postgres.Placeholder(2) != "$2" || sqlite.Placeholder(2) != "?" {
+		t.Error("占位符不正确")
+	}
+	if got := mysql.Quote("db.users"); got != "`db`.`users`" {
+		t.Errorf("MySQL 标识符引号不正确: %s", got)
+	}
+	if got := postgres.Quote(`public.us"ers`); got != `"public"."us""ers"` {
+		t.Errorf("Postgres 标识符引号不正确: %s", got)
+	}
+	if got := sqlite.Quote("users.*"); got != `"users".*` {
+		t.Errorf("通配符不应加引号: %s", got)
+	}
+}
+
+// TestDialect_Rebind 测试占位符转换忽略引号内的问号
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b = 'it''s ?' AND c = ?"
+	if got := cmform.DialectFor("mysql").Rebind(query); got != query {
+		t.Errorf("MySQL 不应转换: %s", got)
+	}
+	want := "SELECT * FROM t WHERE a = $1 AND b = 'it''s ?' AND c = $2"
+	if got := cmform.DialectFor("postgres").Rebind(query); got != want {
+		t.Errorf("Postgres 转换不正确: %s", got)
+	}
+}
@@ -0,0 +1,184 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wuwuseo/cmf/config"
+)
+
+// ErrUnknownDriver 无法为该驱动生成连接字符串
+var ErrUnknownDriver = errors.New("不支持的数据库驱动")
+
+// BuildDSN 根据连接配置生成驱动的连接字符串
+// 配置了 DSN 时原样返回；否则按驱动生成，Params 中的参数覆盖同名的默认参数
+func BuildDSN(dbConfig config.Database) (string, error) {
+	if dbConfig.DSN != "" {
+		return dbConfig.DSN, nil
+	}
+	switch NormalizeDriver(dbConfig.Driver) {
+	case DriverMySQL:
+		return buildMySQLDSN(dbConfig), nil
+	case DriverPostgres:
+		return buildPostgresDSN(dbConfig), nil
+	case DriverSQLite:
+		return buildSQLiteDSN(dbConfig), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownDriver, dbConfig.Driver)
+	}
+}
+
+// buildMySQLDSN 生成 go-sql-driver/mysql 格式的连接字符串
+// 驱动按最后一个 @ 与第一个冒号切分账号密码，密码中的特殊字符无需转义；参数值按 URL 编码
+func buildMySQLDSN(dbConfig config.Database) string {
+	address := fmt.Sprintf("tcp(%s:%d)", dbConfig.Host, dbConfig.Port)
+	if dbConfig.Socket != "" {
+		address = "unix(" + dbConfig.Socket + ")"
+	}
+
+	charset := dbConfig.Charset
+	if charset == "" {
+		charset = "utf8mb4"
+	}
+	loc := dbConfig.Timezone
+	if loc == "" {
+		loc = "Local"
+	}
+	params := []param{{"charset", charset}, {"parseTime", "True"}, {"loc", loc}}
+	if dbConfig.ConnectTimeout > 0 {
+		params = append(params, param{"timeout", strconv.Itoa(dbConfig.ConnectTimeout) + "s"})
+	}
+	if tls := mysqlTLS(dbConfig.SSLMode); tls != "" {
+		params = append(params, param{"tls", tls})
+	}
+	params = mergeParams(params, dbConfig.Params)
+
+	query := make([]string, len(params))
+	for i, p := range params {
+		query[i] = p.key + "=" + url.QueryEscape(p.value)
+	}
+	return fmt.Sprintf("%s:%s@%s/%s?%s", dbConfig.User, dbConfig.Password, address, dbConfig.Name, strings.Join(query, "&"))
+}
+
+// mysqlTLS 将 ssl_mode 转换为 go-sql-driver/mysql 的 tls 参数，未启用时返回空字符串
+func mysqlTLS(sslMode string) string {
+	switch strings.ToLower(sslMode) {
+	case "", "false", "disable", "disabled":
+		return ""
+	case "true", "require", "required", "verify-full", "verify_identity":
+		return "true"
+	default:
+		// skip-verify、preferred 或通过 mysql.RegisterTLSConfig 注册的名称
+		return sslMode
+	}
+}
+
+// buildPostgresDSN 生成 libpq 关键字格式的连接字符串，pgx 与 lib/pq 均支持
+func buildPostgresDSN(dbConfig config.Database) string {
+	host := dbConfig.Host
+	if dbConfig.Socket != "" {
+		host = dbConfig.Socket
+	}
+	params := []param{
+		{"user", dbConfig.User},
+		{"password", dbConfig.Password},
+		{"host", host},
+	}
+	// 未配置端口时（如 Unix socket）由 libpq 使用默认端口
+	if dbConfig.Port > 0 {
+		params = append(params, param{"port", strconv.Itoa(dbConfig.Port)})
+	}
+	params = append(params, param{"dbname", dbConfig.Name})
+	if sslMode := postgresSSLMode(dbConfig.SSLMode); sslMode != "" {
+		params = append(params, param{"sslmode", sslMode})
+	}
+	if dbConfig.Timezone != "" {
+		params = append(params, param{"timezone", dbConfig.Timezone})
+	}
+	if dbConfig.ConnectTimeout > 0 {
+		params = append(params, param{"connect_timeout", strconv.Itoa(dbConfig.ConnectTimeout)})
+	}
+	params = mergeParams(params, dbConfig.Params)
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.key + "=" + quotePostgresValue(p.value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// postgresSSLMode 将布尔形式的 ssl_mode 转换为 libpq 的取值
+func postgresSSLMode(sslMode string) string {
+	switch strings.ToLower(sslMode) {
+	case "false":
+		return "disable"
+	case "true":
+		return "require"
+	default:
+		return sslMode
+	}
+}
+
+// quotePostgresValue 值为空或包含空白、单引号、反斜杠时加单引号并转义
+func quotePostgresValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\f\v'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// buildSQLiteDSN 使用 Host 作为数据库文件路径（兼容旧配置），未设置 Host 时使用 Name
+// 配置默认的 Name 为 cmf，因此不能优先使用 Name，否则只配置 Host 的旧配置会打开新的空文件
+func buildSQLiteDSN(dbConfig config.Database) string {
+	path := dbConfig.Host
+	if path == "" {
+		path = dbConfig.Name
+	}
+	if len(dbConfig.Params) == 0 {
+		return path
+	}
+	params := mergeParams(nil, dbConfig.Params)
+	query := make([]string, len(params))
+	for i, p := range params {
+		query[i] = url.QueryEscape(p.key) + "=" + url.QueryEscape(p.value)
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(query, "&")
+}
+
+// param 保持顺序的连接参数
+type param struct {
+	key   string
+	value string
+}
+
+// mergeParams 用 extra 覆盖同名参数，其余参数按名称排序追加在末尾
+func mergeParams(params []param, extra map[string]string) []param {
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		replaced := false
+		for i := range params {
+			if params[i].key == key {
+				params[i].value = extra[key]
+				replaced = true
+			}
+		}
+		if !replaced {
+			params = append(params, param{key, extra[key]})
+		}
+	}
+	return params
+}
@@ -0,0 +1,89 @@
+package orm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	cmform "github.com/wuwuseo/cmf/orm"
+)
+
+// TestBuildDSN 测试各驱动的连接字符串
+func TestBuildDSN(t *testing.T) {
+	cases := []struct {
+		name   string
+		config config.Database
+		want   string
+	}{
+		{
+			name: "MySQL 时区、超时、TLS 与附加参数",
+			config: config.Database{
+				Driver: "mysql", Host: "db", Port: 3306, User: "root", Password: "p@ss:w/rd", Name: "cmf",
+				Charset: "utf8", Timezone: "Asia/Shanghai", ConnectTimeout: 5, SSLMode: "true",
+				Params: map[string]string{"parseTime": "false", "readTimeout": "3s"},
+			},
+			want: "root:p@ss:w/rd@tcp(db:3306)/cmf?charset=utf8&parseTime=false&loc=Asia%2FShanghai&timeout=5s&tls=true&readTimeout=3s",
+		},
+		{
+			name:   "MySQL Unix socket",
+			config: config.Database{Driver: "mysql", User: "root", Name: "cmf", Socket: "/tmp/mysql.sock", SSLMode: "false"},
+			want:   "root:@unix(/tmp/mysql.sock)/cmf?charset=utf8mb4&parseTime=True&loc=Local",
+		},
+		{
+			name: "Postgres sslmode、时区与密码转义",
+			config: config.Database{
+				Driver: "pgx", Host: "pg", Port: 5432, User: "app", Password: `it's a \secret`, Name: "cmf",
+				SSLMode: "false", Timezone: "UTC", ConnectTimeout: 10, Params: map[string]string{"application_name": "cmf"},
+			},
+			want: `user=app password='it\'s a \\secret' host=pg port=5432 dbname=cmf sslmode=disable timezone=UTC connect_timeout=10 application_name=cmf`,
+		},
+		{
+			name:   "Postgres Unix socket",
+			config: config.Database{Driver: "postgres", Port: 5432, User: "app", Password: "pw", Name: "cmf", Socket: "/var/run/postgresql", SSLMode: "verify-full"},
+			want:   "user=app password=pw host=/var/run/postgresql port=5432 dbname=cmf sslmode=verify-full",
+		},
+		{
+			name:   "Postgres Unix socket 未配置端口",
+			config: config.Database{Driver: "postgres", User: "app", Name: "cmf", Socket: "/var/run/postgresql"},
+			want:   "user=app password='' host=/var/run/postgresql dbname=cmf",
+		},
+		{
+			name:   "SQLite 未设置 Host 时使用 Name 作为路径",
+			config: config.Database{Driver: "sqlite", Name: "/data/cmf.db", Params: map[string]string{"_pragma": "busy_timeout(5000)"}},
+			want:   "/data/cmf.db?_pragma=busy_timeout%285000%29",
+		},
+		{
+			name:   "SQLite 旧配置只设置 Host",
+			config: config.Database{Driver: "sqlite3", Host: "./data/app.db", Name: "cmf"},
+			want:   "./data/app.db",
+		},
+		{
+			name:   "完整 DSN 覆盖其他参数",
+			config: config.Database{Driver: "postgres", Host: "pg", DSN: "postgres://app:p%40ss@pg:5432/cmf?sslmode=require"},
+			want:   "postgres://app:p%40ss@pg:5432/cmf?sslmode=require",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cmform.BuildDSN(tc.config)
+			if err != nil {
+				t.Fatalf("BuildDSN 失败: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("DSN 不正确\n期望: %s\n实际: %s", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := cmform.BuildDSN(config.Database{Driver: "oracle"}); !errors.Is(err, cmform.ErrUnknownDriver) {
+		t.Errorf("未知驱动应返回 ErrUnknownDriver，得到 %v", err)
+	}
+}
+
+// TestNewDBManager_UnknownDriver 测试未知驱动在创建连接时返回错误
+func TestNewDBManager_UnknownDriver(t *testing.T) {
+	cfg := newTestConfig("oracle", "localhost", 1521, "u", "p", "db", "")
+	if _, err := cmform.NewDBManager(cfg); !errors.Is(err, cmform.ErrUnknownDriver) {
+		t.Errorf("未知驱动应返回 ErrUnknownDriver，得到 %v", err)
+	}
+}
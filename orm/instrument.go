@@ -0,0 +1,402 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	cmflog "github.com/wuwuseo/cmf/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSlowThreshold 默认慢查询阈值
+	defaultSlowThreshold = 200 * time.Millisecond
+	// maxTrackedQueries 统计的不同语句数量上限，超出后计入 otherQueries，避免动态拼接的 SQL 使内存无限增长
+	maxTrackedQueries = 1000
+	otherQueries      = "(other)"
+)
+
+// QueryEvent 一次查询或执行的记录
+type QueryEvent struct {
+	Connection string
+	Query      string
+	Args       int // 参数个数，参数值不对外暴露
+	Duration   time.Duration
+	Err        error
+	Slow       bool
+	RequestID  string // 从 fiber.Ctx 或开启 PassLocalsToContext 后的 c.Context() 中读取
+}
+
+// QueryStat 按连接与语句聚合的查询统计
+type QueryStat struct {
+	Connection string        `json:"connection"`
+	Query      string        `json:"query"`
+	Count      int64         `json:"count"`
+	Errors     int64         `json:"errors"`
+	Slow       int64         `json:"slow"`
+	Total      time.Duration `json:"total"`
+	Max        time.Duration `json:"max"`
+}
+
+// InstrumentOption 查询监控选项
+type InstrumentOption func(*instrumenter)
+
+// WithConnectionName 设置日志与统计中的连接名称
+func WithConnectionName(name string) InstrumentOption {
+	return func(in *instrumenter) {
+		in.name = name
+	}
+}
+
+// WithSlowThreshold 设置慢查询阈值，小于等于 0 时不记录慢查询
+func WithSlowThreshold(threshold time.Duration) InstrumentOption {
+	return func(in *instrumenter) {
+		in.slowThreshold = threshold
+	}
+}
+
+// WithQueryLogger 设置慢查询日志使用的 Logger，默认使用全局 Logger
+func WithQueryLogger(logger cmflog.Logger) InstrumentOption {
+	return func(in *instrumenter) {
+		in.logger = logger
+	}
+}
+
+// WithQueryObserver 每次查询结束后调用 fn，用于接入外部指标系统，fn 应当快速返回
+func WithQueryObserver(fn func(QueryEvent)) InstrumentOption {
+	return func(in *instrumenter) {
+		in.observers = append(in.observers, fn)
+	}
+}
+
+// instrumenter 记录查询耗时、慢查询日志与统计
+type instrumenter struct {
+	name          string
+	slowThreshold time.Duration
+	logger        cmflog.Logger
+	observers     []func(QueryEvent)
+}
+
+// observe 记录一次查询，耗时从发出语句到驱动返回结果为止，不包含遍历结果集的时间
+func (in *instrumenter) observe(ctx context.Context, query string, args []driver.NamedValue, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	duration := time.Since(start)
+	query = strings.Join(strings.Fields(query), " ")
+	slow := in.slowThreshold > 0 && duration >= in.slowThreshold
+	queryStats.record(in.name, query, duration, err, slow)
+
+	if slow {
+		logger := in.logger
+		if logger == nil {
+			logger = cmflog.GetDefault()
+		}
+		logger.Warn("慢查询",
+			zap.String("connection", in.name),
+			zap.String("query", query),
+			zap.Strings("args", redactArgs(args)),
+			zap.Duration("duration", duration),
+			zap.String("request_id", requestid.FromContext(ctx)),
+			zap.Error(err),
+		)
+	}
+	if len(in.observers) > 0 {
+		event := QueryEvent{
+			Connection: in.name,
+			Query:      query,
+			Args:       len(args),
+			Duration:   duration,
+			Err:        err,
+			Slow:       slow,
+			RequestID:  requestid.FromContext(ctx),
+		}
+		for _, fn := range in.observers {
+			fn(event)
+		}
+	}
+}
+
+// redactArgs 只保留参数类型，避免密码、手机号等敏感数据进入日志
+func redactArgs(args []driver.NamedValue) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg.Value == nil {
+			redacted[i] = "<nil>"
+		} else {
+			redacted[i] = fmt.Sprintf("<%T>", arg.Value)
+		}
+	}
+	return redacted
+}
+
+// queryStatKey 统计的聚合键
+type queryStatKey struct {
+	connection string
+	query      string
+}
+
+// queryStatRegistry 全局查询统计
+type queryStatRegistry struct {
+	mu    sync.Mutex
+	stats map[queryStatKey]*QueryStat
+}
+
+var queryStats = &queryStatRegistry{stats: make(map[queryStatKey]*QueryStat)}
+
+func (r *queryStatRegistry) record(connection, query string, duration time.Duration, err error, slow bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := queryStatKey{connection: connection, query: query}
+	stat, ok := r.stats[key]
+	if !ok {
+		if len(r.stats) >= maxTrackedQueries {
+			key.query = otherQueries
+			stat = r.stats[key]
+		}
+		if stat == nil {
+			stat = &QueryStat{Connection: connection, Query: key.query}
+			r.stats[key] = stat
+		}
+	}
+	stat.Count++
+	stat.Total += duration
+	stat.Max = max(stat.Max, duration)
+	if err != nil {
+		stat.Errors++
+	}
+	if slow {
+		stat.Slow++
+	}
+}
+
+// QueryStats 返回查询统计快照，按总耗时从高到低排序
+func QueryStats() []QueryStat {
+	queryStats.mu.Lock()
+	defer queryStats.mu.Unlock()
+	stats := make([]QueryStat, 0, len(queryStats.stats))
+	for _, stat := range queryStats.stats {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Query < stats[j].Query
+	})
+	return stats
+}
+
+// ResetQueryStats 清空查询统计
+func ResetQueryStats() {
+	queryStats.mu.Lock()
+	defer queryStats.mu.Unlock()
+	queryStats.stats = make(map[queryStatKey]*QueryStat)
+}
+
+// openInstrumented 使用带监控的连接器打开连接池
+func openInstrumented(driverName, dsn string, opts []InstrumentOption) (*sql.DB, error) {
+	// sql.Open 负责查找已注册的驱动并校验 DSN
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	base := db.Driver()
+	db.Close()
+
+	var connector driver.Connector
+	if dc, ok := base.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = dsnConnector{dsn: dsn, driver: base}
+	}
+
+	in := &instrumenter{name: driverName, slowThreshold: defaultSlowThreshold}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return sql.OpenDB(&instrumentedConnector{Connector: connector, in: in}), nil
+}
+
+// dsnConnector 用于未实现 driver.DriverContext 的驱动
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type instrumentedConnector struct {
+	driver.Connector
+	in *instrumenter
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, in: c.in}, nil
+}
+
+// Close 关闭底层连接器，sql.DB.Close 时调用
+func (c *instrumentedConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// instrumentedConn 包装驱动连接，底层未实现的可选接口返回 driver.ErrSkip 或等价的默认行为
+type instrumentedConn struct {
+	driver.Conn
+	in *instrumenter
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.in.observe(ctx, query, args, start, err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.in.observe(ctx, query, args, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, in: c.in}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("驱动不支持设置事务隔离级别或只读事务")
+	}
+	return c.Conn.Begin() //nolint:staticcheck // 驱动未实现 ConnBeginTx 时的回退
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// instrumentedStmt 包装预处理语句
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+	in    *instrumenter
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			result, err = s.Stmt.Exec(values) //nolint:staticcheck // 驱动未实现 StmtExecContext 时的回退
+		}
+	}
+	s.in.observe(ctx, s.query, args, start, err)
+	return result, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values) //nolint:staticcheck // 驱动未实现 StmtQueryContext 时的回退
+		}
+	}
+	s.in.observe(ctx, s.query, args, start, err)
+	return rows, err
+}
+
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("驱动不支持命名参数")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,179 @@
+package orm_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	cmflog "github.com/wuwuseo/cmf/log"
+	cmform "github.com/wuwuseo/cmf/orm"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingLogger 记录 Warn 日志的字段
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []map[string]any
+}
+
+func (l *recordingLogger) Debug(string, ...zap.Field) {}
+func (l *recordingLogger) Info(string, ...zap.Field)  {}
+func (l *recordingLogger) Error(string, ...zap.Field) {}
+func (l *recordingLogger) Fatal(string, ...zap.Field) {}
+func (l *recordingLogger) Sync() error                { return nil }
+func (l *recordingLogger) With(...zap.Field) cmflog.Logger {
+	return l
+}
+
+func (l *recordingLogger) Warn(msg string, fields ...zap.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	l.mu.Lock()
+	l.entries = append(l.entries, enc.Fields)
+	l.mu.Unlock()
+}
+
+// TestGetSqlDb_SlowQueryLog 测试慢查询日志脱敏并携带请求 ID
+func TestGetSqlDb_SlowQueryLog(t *testing.T) {
+	logger := &recordingLogger{}
+	db, err := cmform.GetSqlDb("sqlite", filepath.Join(t.TempDir(), "slow.db"),
+		cmform.WithConnectionName("slowdb"), cmform.WithSlowThreshold(time.Nanosecond), cmform.WithQueryLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Exec("CREATE TABLE users (name TEXT, password TEXT)")
+
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{Generator: func() string { return "req-123" }}))
+	app.Get("/", func(c fiber.Ctx) error {
+		_, err := db.ExecContext(c, "INSERT INTO users (name, password) VALUES (?, ?)", "alice", "s3cret")
+		return err
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("请求失败: %v", err)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	var entry map[string]any
+	for _, e := range logger.entries {
+		if strings.HasPrefix(e["query"].(string), "INSERT") {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("应记录慢查询日志: %v", logger.entries)
+	}
+	if entry["request_id"] != "req-123" || entry["connection"] != "slowdb" {
+		t.Errorf("日志应包含请求 ID 与连接名称: %v", entry)
+	}
+	for _, arg := range entry["args"].([]any) {
+		if arg != "<string>" {
+			t.Errorf("参数应只保留类型，得到 %v", arg)
+		}
+	}
+}
+
+// TestGetSqlDb_RequestIDFromContext 测试开启 PassLocalsToContext 后经由 c.Context() 派生的上下文读取请求 ID
+func TestGetSqlDb_RequestIDFromContext(t *testing.T) {
+	var mu sync.Mutex
+	var events []cmform.QueryEvent
+	db, err := cmform.GetSqlDb("sqlite", filepath.Join(t.TempDir(), "reqctx.db"),
+		cmform.WithSlowThreshold(0),
+		cmform.WithQueryObserver(func(e cmform.QueryEvent) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	app := fiber.New(fiber.Config{PassLocalsToContext: true})
+	app.Use(requestid.New(requestid.Config{Generator: func() string { return "req-456" }}))
+	app.Get("/", func(c fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), time.Second)
+		defer cancel()
+		_, err := db.ExecContext(ctx, "CREATE TABLE t (v INTEGER)")
+		return err
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("请求失败: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].RequestID != "req-456" {
+		t.Errorf("c.Context() 派生的上下文应携带请求 ID: %+v", events)
+	}
+}
+
+// TestGetSqlDb_QueryStats 测试查询统计与观察者，包括预处理语句
+func TestGetSqlDb_QueryStats(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var events []cmform.QueryEvent
+	db, err := cmform.GetSqlDb("sqlite", filepath.Join(t.TempDir(), "stats.db"),
+		cmform.WithConnectionName("statsdb"), cmform.WithSlowThreshold(0),
+		cmform.WithQueryObserver(func(e cmform.QueryEvent) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cmform.ResetQueryStats()
+
+	db.ExecContext(ctx, "CREATE TABLE t (v INTEGER)")
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO t (v) VALUES (?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := stmt.ExecContext(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stmt.Close()
+	db.QueryContext(ctx, "SELECT   *\n FROM missing_table")
+
+	var insert, failed *cmform.QueryStat
+	for _, stat := range cmform.QueryStats() {
+		if stat.Connection != "statsdb" {
+			continue
+		}
+		switch stat.Query {
+		case "INSERT INTO t (v) VALUES (?)":
+			insert = &stat
+		case "SELECT * FROM missing_table":
+			failed = &stat
+		}
+	}
+	if insert == nil || insert.Count != 3 || insert.Slow != 0 {
+		t.Errorf("预处理语句统计不正确: %+v", insert)
+	}
+	if failed == nil || failed.Errors != 1 {
+		t.Errorf("失败的查询应计入错误数: %+v", failed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) < 5 || events[1].Args != 1 {
+		t.Errorf("观察者事件不正确: %+v", events)
+	}
+}
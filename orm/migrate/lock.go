@@ -0,0 +1,190 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+const (
+	lockRetryInterval = 200 * time.Millisecond
+	// defaultLockLease 锁表方式下锁的租期，持有期间每隔租期的 1/3 续期，超过租期未续期的锁视为持有进程已退出
+	defaultLockLease = 10 * time.Minute
+)
+
+// withLock 创建迁移记录表后在跨实例锁内执行 fn
+// MySQL 使用 GET_LOCK，Postgres 使用 advisory lock，SQLite 及其他驱动使用锁表
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+	defer cancel()
+
+	var release func() error
+	var err error
+	switch m.dialect.Driver {
+	case orm.DriverMySQL:
+		release, err = m.lockMySQL(lockCtx)
+	case orm.DriverPostgres:
+		release, err = m.lockPostgres(lockCtx)
+	default:
+		release, err = m.lockTable(lockCtx)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return ErrLockTimeout
+		}
+		return err
+	}
+
+	fnErr := fn()
+	if err := release(); err != nil && fnErr == nil {
+		return fmt.Errorf("释放迁移锁失败: %w", err)
+	}
+	return fnErr
+}
+
+// lockName 迁移锁名称，不同前缀的迁移互不影响
+func (m *Migrator) lockName() string {
+	return m.prefix + m.tableName
+}
+
+// lockMySQL 在独占连接上使用 GET_LOCK 获取会话级锁
+func (m *Migrator) lockMySQL(ctx context.Context) (func() error, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name := m.lockName()
+	for {
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&got); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if got.Valid && got.Int64 == 1 {
+			break
+		}
+		if err := waitRetry(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}, nil
+}
+
+// lockPostgres 在独占连接上使用 pg_try_advisory_lock 获取会话级锁
+func (m *Migrator) lockPostgres(ctx context.Context) (func() error, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(m.lockName()))
+	key := int64(hash.Sum64())
+	for {
+		var got bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&got); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if got {
+			break
+		}
+		if err := waitRetry(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}, nil
+}
+
+// lockTable 通过向锁表插入固定主键获取锁，适用于没有会话锁的数据库
+func (m *Migrator) lockTable(ctx context.Context) (func() error, error) {
+	table := m.dialect.Quote(m.lockName() + "_lock")
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER NOT NULL PRIMARY KEY, locked_at BIGINT NOT NULL)", table)
+	if _, err := m.db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("创建迁移锁表失败: %w", err)
+	}
+
+	p1, p2 := m.dialect.Placeholder(1), m.dialect.Placeholder(2)
+	for {
+		// 清理持有进程异常退出后遗留的锁
+		m.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE locked_at < %s", table, p1),
+			time.Now().Add(-m.lockLease).Unix())
+		_, err := m.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, locked_at) VALUES (%s, %s)", table, p1, p2),
+			1, time.Now().Unix())
+		if err == nil {
+			break
+		}
+		// 只有锁已被他人持有（主键冲突）时等待重试，其余错误直接返回
+		if !isUniqueViolation(err) {
+			return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+		if err := waitRetry(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// 迁移执行期间定期续期，避免耗时超过租期的迁移被其他实例当作遗留锁清理
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.lockLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.db.ExecContext(context.Background(), fmt.Sprintf("UPDATE %s SET locked_at = %s WHERE id = %s", table, p1, p2),
+					time.Now().Unix(), 1)
+			}
+		}
+	}()
+	return func() error {
+		close(stop)
+		<-done
+		_, err := m.db.ExecContext(context.Background(), fmt.Sprintf("DELETE FROM %s WHERE id = %s", table, p1), 1)
+		return err
+	}, nil
+}
+
+// isUniqueViolation 判断错误是否为唯一约束冲突
+// Postgres 驱动通过 SQLSTATE 23505 识别，MySQL 与 SQLite 驱动通过错误信息识别
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == "23505"
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || // SQLite
+		strings.Contains(msg, "Error 1062") || strings.Contains(msg, "Duplicate entry") || // MySQL
+		strings.Contains(msg, "SQLSTATE 23505") // 未实现 SQLState 的 Postgres 驱动
+}
+
+func waitRetry(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(lockRetryInterval):
+		return nil
+	}
+}
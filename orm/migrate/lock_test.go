@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestLockTable_RenewsLease(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "lock.db") + "?_pragma=busy_timeout(5000)"
+	open := func() *Migrator {
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		m, err := New(db, "sqlite", fstest.MapFS{}, WithLockLease(2*time.Second), WithLockTimeout(3500*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	holder, contender := open(), open()
+	ctx := context.Background()
+
+	acquired := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- holder.withLock(ctx, func() error {
+			close(acquired)
+			// 执行时间超过租期，持有期间续期使锁不被视为遗留锁
+			time.Sleep(4 * time.Second)
+			return nil
+		})
+	}()
+	<-acquired
+
+	err := contender.withLock(ctx, func() error { return nil })
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("持有者续期期间其他实例不应获取到锁，得到 %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("持有者执行失败: %v", err)
+	}
+	if err := contender.withLock(ctx, func() error { return nil }); err != nil {
+		t.Errorf("释放后应能获取锁: %v", err)
+	}
+}
@@ -0,0 +1,406 @@
+// Package migrate 提供版本化的 SQL 迁移
+//
+// 迁移文件命名为 {version}_{name}.up.sql 与 {version}_{name}.down.sql，
+// version 为正整数（如 1、20240101120000），可从 embed.FS 或 os.DirFS 加载。
+// SQL 中的 {prefix} 占位符会替换为连接配置的 TablePrefix，
+// 迁移记录保存在 {prefix}schema_migrations 表中。
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+const (
+	defaultTableName   = "schema_migrations"
+	defaultLockTimeout = time.Minute
+	prefixPlaceholder  = "{prefix}"
+)
+
+var (
+	// ErrNoDownMigration 迁移缺少 down 文件，无法回滚
+	ErrNoDownMigration = errors.New("migration has no down script")
+	// ErrMissingMigration 数据库中已执行的版本找不到对应的迁移文件
+	ErrMissingMigration = errors.New("applied migration file not found")
+	// ErrLockTimeout 在超时时间内未获取到迁移锁
+	ErrLockTimeout = errors.New("migration lock timeout")
+)
+
+// fileNamePattern 匹配 {version}_{name}.up.sql / .down.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration 单个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Missing   bool // 已执行但迁移文件不存在
+}
+
+// Load 从文件系统根目录加载迁移文件，按版本升序返回
+// 目录中的其他文件会被忽略，同一版本对应多个名称时返回错误
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移目录失败: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移版本号无效: %s", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件失败: %w", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s 与 %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少 up 文件", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Option 函数式可选参数
+type Option func(*Migrator)
+
+// WithTablePrefix 设置表前缀，用于迁移记录表与 SQL 中的 {prefix} 占位符
+func WithTablePrefix(prefix string) Option {
+	return func(m *Migrator) { m.prefix = prefix }
+}
+
+// WithTableName 设置迁移记录表名（不含前缀），默认 schema_migrations
+func WithTableName(name string) Option {
+	return func(m *Migrator) {
+		if name != "" {
+			m.tableName = name
+		}
+	}
+}
+
+// WithLockTimeout 设置获取迁移锁的超时时间，默认 1 分钟
+func WithLockTimeout(d time.Duration) Option {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.lockTimeout = d
+		}
+	}
+}
+
+// WithLockLease 设置锁表方式（SQLite 等没有会话锁的数据库）下迁移锁的租期，默认 10 分钟
+// 持有锁期间每隔租期的 1/3 续期，持有进程退出后超过租期的锁会被其他实例清理
+func WithLockLease(d time.Duration) Option {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.lockLease = d
+		}
+	}
+}
+
+// Migrator 迁移执行器
+// 每次 Up/Down/Redo 都在跨实例锁内执行，多个实例同时启动时只有一个执行迁移
+type Migrator struct {
+	db          *sql.DB
+	dialect     orm.Dialect
+	migrations  []Migration
+	prefix      string
+	tableName   string
+	lockTimeout time.Duration
+	lockLease   time.Duration
+}
+
+// New 创建迁移执行器，driver 用于选择方言与锁实现
+func New(db *sql.DB, driver string, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{
+		db:          db,
+		dialect:     orm.DialectFor(driver),
+		migrations:  migrations,
+		tableName:   defaultTableName,
+		lockTimeout: defaultLockTimeout,
+		lockLease:   defaultLockLease,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// NewFromManager 使用 DBManager 的连接、驱动与表前缀创建迁移执行器
+func NewFromManager(manager *orm.DBManager, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	cfg := manager.Config()
+	opts = append([]Option{WithTablePrefix(cfg.TablePrefix)}, opts...)
+	return New(manager.GetDB(), cfg.Driver, fsys, opts...)
+}
+
+// Migrations 返回已加载的全部迁移
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// table 返回加引号的迁移记录表名
+func (m *Migrator) table() string {
+	return m.dialect.Quote(m.prefix + m.tableName)
+}
+
+// Up 执行全部未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, migration, true); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，steps 小于 1 时回滚 1 个
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	steps = max(steps, 1)
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		targets, err := m.lastApplied(ctx, steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range targets {
+			if err := m.apply(ctx, migration, false); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo 回滚最近执行的一个迁移后重新执行
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(ctx, func() error {
+		targets, err := m.lastApplied(ctx, 1)
+		if err != nil || len(targets) == 0 {
+			return err
+		}
+		if err := m.apply(ctx, targets[0], false); err != nil {
+			return err
+		}
+		if err := m.apply(ctx, targets[0], true); err != nil {
+			return err
+		}
+		redone = &targets[0]
+		return nil
+	})
+	return redone, err
+}
+
+// Status 返回全部迁移的执行状态，按版本升序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]struct{}, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = struct{}{}
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if _, ok := known[version]; !ok {
+			statuses = append(statuses, Status{
+				Version:   version,
+				Name:      record.name,
+				Applied:   true,
+				AppliedAt: record.appliedAt,
+				Missing:   true,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// appliedRecord 迁移记录表中的一行
+type appliedRecord struct {
+	name      string
+	appliedAt time.Time
+}
+
+// ensureTable 创建迁移记录表
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at BIGINT NOT NULL
+)`, m.table())
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移版本
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedRecord, error) {
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.table()))
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedRecord)
+	for rows.Next() {
+		var version, appliedAt int64
+		var name string
+		if err := rows.Scan(&version, &name, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedRecord{name: name, appliedAt: time.Unix(appliedAt, 0)}
+	}
+	return applied, rows.Err()
+}
+
+// lastApplied 返回最近执行的 n 个迁移，按版本倒序
+func (m *Migrator) lastApplied(ctx context.Context, n int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if len(versions) > n {
+		versions = versions[:n]
+	}
+
+	targets := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		migration, ok := m.find(version)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingMigration, version, applied[version].name)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+		}
+		targets = append(targets, migration)
+	}
+	return targets, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// apply 在事务中执行迁移脚本并更新迁移记录
+// MySQL 的 DDL 会隐式提交事务，失败时可能需要手动清理已执行的语句
+func (m *Migrator) apply(ctx context.Context, migration Migration, up bool) error {
+	script, direction := migration.Up, "up"
+	if !up {
+		script, direction = migration.Down, "down"
+	}
+	script = strings.ReplaceAll(script, prefixPlaceholder, m.prefix)
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, statement := range SplitStatementsFor(m.dialect.Driver, script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("执行迁移 %d_%s.%s.sql 失败: %w", migration.Version, migration.Name, direction, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)",
+				m.table(), m.dialect.Placeholder(1), m.dialect.Placeholder(2), m.dialect.Placeholder(3)),
+			migration.Version, migration.Name, time.Now().Unix())
+	} else {
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.table(), m.dialect.Placeholder(1)),
+			migration.Version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("更新迁移记录失败: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,266 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/orm"
+	"github.com/wuwuseo/cmf/orm/migrate"
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB 在临时目录中创建 SQLite 数据库
+func openTestDB(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, dsn
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE {prefix}users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);\n-- 初始数据\nINSERT INTO {prefix}users (name) VALUES ('a;b');")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE {prefix}users;")},
+		"2_add_email.up.sql":      {Data: []byte("ALTER TABLE {prefix}users ADD COLUMN email TEXT;")},
+		"2_add_email.down.sql":    {Data: []byte("ALTER TABLE {prefix}users DROP COLUMN email;")},
+		"3_create_posts.up.sql":   {Data: []byte("CREATE TABLE {prefix}posts (id INTEGER PRIMARY KEY);")},
+		"3_create_posts.down.sql": {Data: []byte("DROP TABLE {prefix}posts;")},
+		"README.md":               {Data: []byte("忽略非迁移文件")},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := migrate.Load(testMigrations())
+	if err != nil {
+		t.Fatalf("加载迁移失败: %v", err)
+	}
+	if len(migrations) != 3 || migrations[0].Version != 1 || migrations[2].Name != "create_posts" {
+		t.Fatalf("迁移加载结果不正确: %+v", migrations)
+	}
+
+	_, err = migrate.Load(fstest.MapFS{
+		"1_a.up.sql": {Data: []byte("SELECT 1;")},
+		"1_b.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	if err == nil {
+		t.Error("重复版本应返回错误")
+	}
+	if _, err := migrate.Load(fstest.MapFS{"1_a.down.sql": {Data: []byte("SELECT 1;")}}); err == nil {
+		t.Error("缺少 up 文件应返回错误")
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t)
+	m, err := migrate.New(db, "sqlite", testMigrations(), migrate.WithTablePrefix("cmf_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up 失败: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Fatalf("应执行 3 个迁移，实际 %d", len(applied))
+	}
+	if !tableExists(t, db, "cmf_users") || !tableExists(t, db, "cmf_schema_migrations") {
+		t.Fatal("{prefix} 占位符应替换为表前缀")
+	}
+	var name string
+	db.QueryRow("SELECT name FROM cmf_users").Scan(&name)
+	if name != "a;b" {
+		t.Errorf("引号内的分号不应拆分语句，得到 %q", name)
+	}
+
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Errorf("重复执行 Up 不应再执行迁移: %v %v", again, err)
+	}
+
+	rolled, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down 失败: %v", err)
+	}
+	if len(rolled) != 2 || rolled[0].Version != 3 || rolled[1].Version != 2 {
+		t.Fatalf("应按版本倒序回滚，得到 %+v", rolled)
+	}
+	if tableExists(t, db, "cmf_posts") {
+		t.Error("回滚后 posts 表应被删除")
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status 失败: %v", err)
+	}
+	want := []bool{true, false, false}
+	for i, status := range statuses {
+		if status.Applied != want[i] {
+			t.Errorf("版本 %d 状态应为 %v", status.Version, want[i])
+		}
+	}
+	if statuses[0].AppliedAt.IsZero() {
+		t.Error("已执行的迁移应记录执行时间")
+	}
+}
+
+func TestMigrator_Redo(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t)
+	m, err := migrate.New(db, "sqlite", testMigrations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO posts (id) VALUES (1)")
+
+	redone, err := m.Redo(ctx)
+	if err != nil || redone == nil || redone.Version != 3 {
+		t.Fatalf("Redo 应重做最后一个迁移: %+v %v", redone, err)
+	}
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&n)
+	if n != 0 {
+		t.Error("Redo 应重新创建表")
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t)
+	m, err := migrate.New(db, "sqlite", fstest.MapFS{
+		"1_ok.up.sql":     {Data: []byte("CREATE TABLE ok (id INTEGER);")},
+		"2_broken.up.sql": {Data: []byte("CREATE TABLE broken (id INTEGER); INSERT INTO missing VALUES (1);")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("迁移失败时应返回错误")
+	}
+	if tableExists(t, db, "broken") {
+		t.Error("失败的迁移应在事务中回滚")
+	}
+	statuses, _ := m.Status(ctx)
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("只应记录成功的迁移: %+v", statuses)
+	}
+
+	if _, err := m.Down(ctx, 1); !errors.Is(err, migrate.ErrNoDownMigration) {
+		t.Errorf("缺少 down 文件时应返回 ErrNoDownMigration，得到 %v", err)
+	}
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	ctx := context.Background()
+	_, dsn := openTestDB(t)
+
+	var wg sync.WaitGroup
+	counts := make([]int, 4)
+	errs := make([]error, 4)
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := sql.Open("sqlite", dsn)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer db.Close()
+			m, err := migrate.New(db, "sqlite", testMigrations())
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			applied, err := m.Up(ctx)
+			counts[i], errs[i] = len(applied), err
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range counts {
+		if errs[i] != nil {
+			t.Fatalf("并发 Up 失败: %v", errs[i])
+		}
+		total += counts[i]
+	}
+	if total != 3 {
+		t.Errorf("多个实例并发执行时每个迁移只应执行一次，共执行 %d 次", total)
+	}
+}
+
+func TestMigrator_LockErrorNotRetried(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t)
+	// 锁表结构不兼容时插入失败，该错误不是锁冲突，应立即返回而不是等待到超时
+	if _, err := db.Exec("CREATE TABLE schema_migrations_lock (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New(db, "sqlite", testMigrations(), migrate.WithLockTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = m.Up(ctx)
+	if err == nil || errors.Is(err, migrate.ErrLockTimeout) {
+		t.Fatalf("锁表插入失败应直接返回原始错误，得到 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("非锁冲突错误不应重试，耗时 %v", elapsed)
+	}
+}
+
+func TestNewFromManager_DirFS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for name, file := range testMigrations() {
+		os.WriteFile(filepath.Join(dir, name), file.Data, 0o644)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Default = "default"
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "manager.db"), TablePrefix: "app_"},
+	}
+	manager, err := orm.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	m, err := migrate.NewFromManager(manager, os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up 失败: %v", err)
+	}
+	if !tableExists(t, manager.GetDB(), "app_users") {
+		t.Error("应使用连接配置中的表前缀")
+	}
+}
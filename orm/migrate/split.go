@@ -0,0 +1,141 @@
+package migrate
+
+import (
+	"strings"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+// noSplitDirective 脚本包含该注释行时整体作为一条语句执行，用于包含分号的触发器、存储过程等
+const noSplitDirective = "-- migrate:nosplit"
+
+// SplitStatements 按分号拆分 SQL 脚本
+// 忽略引号（'、"、`）、注释（--、/* */）以及 Postgres 美元引号（$$、$tag$）内的分号，
+// 去除只包含空白与注释的语句；引号内的反斜杠不作为转义，MySQL 脚本请使用 SplitStatementsFor
+func SplitStatements(script string) []string {
+	return SplitStatementsFor("", script)
+}
+
+// SplitStatementsFor 按驱动的字符串转义规则拆分 SQL 脚本
+// MySQL 的 '、" 字符串以及 Postgres 的 E'...' 字符串中，反斜杠转义其后的字符，如 'it\'s;'
+func SplitStatementsFor(driver, script string) []string {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == noSplitDirective {
+			if strings.TrimSpace(stripComments(script)) == "" {
+				return nil
+			}
+			return []string{strings.TrimSpace(script)}
+		}
+	}
+
+	var statements []string
+	start := 0
+	appendStatement := func(end int) {
+		statement := strings.TrimSpace(script[start:end])
+		if strings.TrimSpace(stripComments(statement)) != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(script); {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c, backslashEscapes(driver, script, i))
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			i = skipLineComment(script, i)
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipBlockComment(script, i)
+		case c == '$':
+			i = skipDollarQuoted(script, i)
+		case c == ';':
+			appendStatement(i)
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	appendStatement(len(script))
+	return statements
+}
+
+// backslashEscapes 判断位置 i 开始的引号字符串中反斜杠是否为转义符
+func backslashEscapes(driver, s string, i int) bool {
+	switch orm.NormalizeDriver(driver) {
+	case orm.DriverMySQL:
+		return s[i] != '`'
+	case orm.DriverPostgres:
+		// E'...' 转义字符串，E 不能是标识符的一部分
+		return s[i] == '\'' && i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i == 1 || !isIdentByte(s[i-2]))
+	}
+	return false
+}
+
+// skipQuoted 跳过引号字符串，连续两个引号视为转义；backslash 为 true 时反斜杠转义其后的字符
+func skipQuoted(s string, i int, quote byte, backslash bool) int {
+	for i++; i < len(s); i++ {
+		if backslash && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func skipLineComment(s string, i int) int {
+	if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(s)
+}
+
+func skipBlockComment(s string, i int) int {
+	if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return len(s)
+}
+
+// skipDollarQuoted 跳过 $tag$...$tag$，不是美元引号时（如 $1 参数）只前进一个字符
+func skipDollarQuoted(s string, i int) int {
+	end := i + 1
+	for end < len(s) && (isIdentByte(s[end]) && !(end == i+1 && s[end] >= '0' && s[end] <= '9')) {
+		end++
+	}
+	if end >= len(s) || s[end] != '$' {
+		return i + 1
+	}
+	tag := s[i : end+1]
+	if close := strings.Index(s[end+1:], tag); close >= 0 {
+		return end + 1 + close + len(tag)
+	}
+	return len(s)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// stripComments 移除注释，仅用于判断语句是否为空
+func stripComments(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "--"):
+			i = skipLineComment(s, i)
+		case strings.HasPrefix(s[i:], "/*"):
+			i = skipBlockComment(s, i)
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String()
+}
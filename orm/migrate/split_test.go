@@ -0,0 +1,59 @@
+package migrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wuwuseo/cmf/orm/migrate"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := map[string]struct {
+		script string
+		want   []string
+	}{
+		"基本拆分": {
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		"引号内分号": {
+			script: `INSERT INTO a VALUES ('x;y', "z;w", ` + "`c;d`" + `, 'it''s;');`,
+			want:   []string{`INSERT INTO a VALUES ('x;y', "z;w", ` + "`c;d`" + `, 'it''s;')`},
+		},
+		"注释": {
+			script: "-- 注释; 不拆分\nSELECT 1; /* 块注释; */ SELECT 2;\n-- 结尾注释",
+			want:   []string{"-- 注释; 不拆分\nSELECT 1", "/* 块注释; */ SELECT 2"},
+		},
+		"美元引号": {
+			script: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;\nSELECT $1;",
+			want:   []string{"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql", "SELECT $1"},
+		},
+		"不拆分指令": {
+			script: "-- migrate:nosplit\nCREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = 1; END;",
+			want:   []string{"-- migrate:nosplit\nCREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = 1; END;"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := migrate.SplitStatements(tc.script)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("拆分结果不正确\n期望: %q\n实际: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsFor_BackslashEscapes(t *testing.T) {
+	script := `INSERT INTO a VALUES ('it\'s;', "say \"hi;\"", 'C:\\');` + "\nSELECT 2;"
+	want := []string{`INSERT INTO a VALUES ('it\'s;', "say \"hi;\"", 'C:\\')`, "SELECT 2"}
+	if got := migrate.SplitStatementsFor("mysql", script); !reflect.DeepEqual(got, want) {
+		t.Errorf("MySQL 应识别反斜杠转义\n期望: %q\n实际: %q", want, got)
+	}
+
+	// Postgres 标准字符串中反斜杠是普通字符，E'...' 中才是转义符
+	script = `SELECT 'C:\'; SELECT E'it\'s;';`
+	want = []string{`SELECT 'C:\'`, `SELECT E'it\'s;'`}
+	if got := migrate.SplitStatementsFor("postgres", script); !reflect.DeepEqual(got, want) {
+		t.Errorf("Postgres 应只在 E 字符串中识别反斜杠转义\n期望: %q\n实际: %q", want, got)
+	}
+}
@@ -3,8 +3,9 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"time"
+	"sync"
 
 	"github.com/google/wire"
 	"github.com/wuwuseo/cmf/config"
@@ -14,87 +15,126 @@ import (
 var ProviderSet = wire.NewSet(NewDBManager)
 
 // DBManager 数据库连接池管理器
+// 默认连接在创建时打开，其他命名连接在首次通过 Connection 获取时打开
 type DBManager struct {
-	db     *sql.DB
-	config config.Database
+	db          *sql.DB
+	config      config.Database
+	cfg         *config.Config
+	defaultName string
+
+	mu          sync.Mutex
+	connections map[string]*Connection
+	closed      bool
 }
 
 // NewDBManager 创建并配置数据库连接池管理器
 // 接收 *config.Config 作为显式依赖，便于 Wire 进行依赖注入
 func NewDBManager(cfg *config.Config) (*DBManager, error) {
-	dbConfig := GetDatabaseConfig(nil, cfg)
-	dsn := getDSNFromDatabase(dbConfig)
-	db, err := GetSqlDb(dbConfig.Driver, dsn)
+	// 与 GetDatabaseConfig 一致：默认连接不存在时使用第一个可用的连接
+	defaultName := cfg.Database.Default
+	if defaultName == "" {
+		defaultName = "default"
+	}
+	if _, ok := cfg.Database.Connections[defaultName]; !ok {
+		for name := range cfg.Database.Connections {
+			defaultName = name
+			break
+		}
+	}
+	dbConfig := GetDatabaseConfig([]string{defaultName}, cfg)
+	conn, err := openConnection(defaultName, dbConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	// 配置连接池参数
-	if dbConfig.MaxOpenConns > 0 {
-		db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	return &DBManager{
+		db:          conn.Primary(),
+		config:      dbConfig,
+		cfg:         cfg,
+		defaultName: defaultName,
+		connections: map[string]*Connection{defaultName: conn},
+	}, nil
+}
+
+// GetDB 获取默认连接主库的 *sql.DB 实例
+func (m *DBManager) GetDB() *sql.DB {
+	return m.db
+}
+
+// Connection 获取命名连接，name 为空时返回默认连接
+// 连接在首次获取时打开并缓存，由 Close 统一关闭
+func (m *DBManager) Connection(name ...string) (*Connection, error) {
+	connName := m.defaultName
+	if len(name) > 0 && name[0] != "" {
+		connName = name[0]
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errors.New("数据库连接管理器已关闭")
 	}
-	if dbConfig.MaxIdleConns > 0 {
-		db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	if conn, ok := m.connections[connName]; ok {
+		return conn, nil
 	}
-	if dbConfig.ConnMaxLifetime > 0 {
-		db.SetConnMaxLifetime(time.Duration(dbConfig.ConnMaxLifetime) * time.Second)
+	dbConfig, ok := m.cfg.Database.Connections[connName]
+	if !ok {
+		return nil, fmt.Errorf("未找到数据库配置: %s", connName)
 	}
-	if dbConfig.ConnMaxIdleTime > 0 {
-		db.SetConnMaxIdleTime(time.Duration(dbConfig.ConnMaxIdleTime) * time.Second)
+	conn, err := openConnection(connName, dbConfig)
+	if err != nil {
+		return nil, err
 	}
+	m.connections[connName] = conn
+	return conn, nil
+}
 
-	return &DBManager{
-		db:     db,
-		config: dbConfig,
-	}, nil
+// Config 返回默认连接的数据库配置
+func (m *DBManager) Config() config.Database {
+	return m.config
 }
 
-// GetDB 获取 *sql.DB 实例
-func (m *DBManager) GetDB() *sql.DB {
-	return m.db
+// Dialect 返回默认连接的 SQL 方言
+func (m *DBManager) Dialect() Dialect {
+	return DialectFor(m.config.Driver)
 }
 
-// Ping 执行数据库健康检查
+// Ping 执行默认连接的健康检查
 func (m *DBManager) Ping(ctx context.Context) error {
 	return m.db.PingContext(ctx)
 }
 
-// Close 优雅关闭数据库连接
+// Close 优雅关闭全部已打开的连接
 func (m *DBManager) Close() error {
-	if m.db != nil {
-		return m.db.Close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
 	}
-	return nil
+	m.closed = true
+	var errs []error
+	for _, conn := range m.connections {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
 }
 
-func GetSqlDb(driver string, dsn string) (*sql.DB, error) {
-	db, err := sql.Open(driver, dsn)
-	if err != nil {
-		return db, err
-	}
-	return db, nil
+// GetSqlDb 打开数据库连接池
+// 底层驱动被包装以记录每条语句的耗时：超过阈值（默认 200ms）的慢查询通过日志输出，
+// 参数只记录类型；统计数据通过 QueryStats 获取
+func GetSqlDb(driver string, dsn string, opts ...InstrumentOption) (*sql.DB, error) {
+	return openInstrumented(driver, dsn, opts)
 }
 
 // GetDatabaseSourceDns 根据配置生成数据库连接字符串
 // connectionName 参数用于指定要使用的数据库连接名称，默认值为空字符串
+// 驱动不受支持时返回空字符串，需要错误信息时使用 BuildDSN
 func GetDatabaseSourceDns(config *config.Config, connectionName ...string) string {
 	// 获取连接名称，如果提供了参数则使用参数值，否则使用配置中的默认值
 	dbConfig := GetDatabaseConfig(connectionName, config)
 
-	return getDSNFromDatabase(dbConfig)
-}
-
-func getDSNFromDatabase(dbConfig config.Database) string {
-	switch dbConfig.Driver {
-	case "postgres":
-		return fmt.Sprintf("user=%s password=%s host=%s port=%d dbname=%s", dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.Name)
-	case "mysql":
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.Name)
-	case "sqlite3":
-		return dbConfig.Host
-	default:
-		return ""
-	}
+	dsn, _ := BuildDSN(dbConfig)
+	return dsn
 }
 
 func GetDatabaseConfig(connectionName []string, config *config.Config) config.Database {
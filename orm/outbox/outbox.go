@@ -0,0 +1,157 @@
+// Package outbox 实现事务性发件箱（transactional outbox）
+//
+// 业务数据与事件在同一个数据库事务中写入：Publish 将事件插入 {prefix}outbox 表，
+// 事务提交后由 Relay 轮询认领并分发给按主题注册的处理函数（进程内推送、Webhook、Redis Streams 等）。
+// 处理失败按退避重试，同一 Key 的事件按写入顺序依次投递，已投递的记录定期清理。
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+const defaultTableName = "outbox"
+
+// ErrNoTransaction Publish 不在事务中调用
+var ErrNoTransaction = errors.New("outbox publish requires a transaction")
+
+// Message 发件箱中的事件
+type Message struct {
+	ID        int64
+	Topic     string
+	Key       string // 聚合键，相同 Key 的事件按写入顺序投递，为空时不保证顺序
+	Payload   []byte
+	Attempts  int // 已失败的投递次数
+	CreatedAt time.Time
+}
+
+// Decode 将 JSON 消息体解码到 v
+func (m *Message) Decode(v any) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// Option 函数式可选参数
+type Option func(*Outbox)
+
+// WithTableName 设置发件箱表名（不含前缀），默认 outbox
+func WithTableName(name string) Option {
+	return func(o *Outbox) {
+		if name != "" {
+			o.tableName = name
+		}
+	}
+}
+
+// Outbox 事务性发件箱
+type Outbox struct {
+	conn      *orm.Connection
+	tableName string
+}
+
+// New 创建发件箱，表名自动添加连接的表前缀
+func New(conn *orm.Connection, opts ...Option) *Outbox {
+	o := &Outbox{conn: conn, tableName: defaultTableName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// EnsureTable 创建发件箱表与索引
+func (o *Outbox) EnsureTable(ctx context.Context) error {
+	b := o.conn.Builder()
+	table := b.Table(o.tableName)
+	idx := func(suffix string) string {
+		return b.Dialect().Quote(o.conn.Config().TablePrefix + o.tableName + "_" + suffix)
+	}
+
+	var id, payload string
+	switch b.Dialect().Driver {
+	case orm.DriverMySQL:
+		id, payload = "BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY", "LONGBLOB"
+	case orm.DriverPostgres:
+		id, payload = "BIGSERIAL PRIMARY KEY", "BYTEA"
+	default:
+		id, payload = "INTEGER PRIMARY KEY AUTOINCREMENT", "BLOB"
+	}
+	columns := fmt.Sprintf(`id %s,
+	topic VARCHAR(255) NOT NULL,
+	aggregate_key VARCHAR(255) NOT NULL DEFAULT '',
+	payload %s NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	available_at BIGINT NOT NULL,
+	created_at BIGINT NOT NULL,
+	locked_by VARCHAR(255) NOT NULL DEFAULT '',
+	locked_until BIGINT NOT NULL DEFAULT 0,
+	delivered_at BIGINT NULL,
+	failed_at BIGINT NULL,
+	last_error TEXT NULL`, id, payload)
+
+	// MySQL 不支持 CREATE INDEX IF NOT EXISTS，索引在建表语句中声明
+	if b.Dialect().Driver == orm.DriverMySQL {
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s,\n\tINDEX %s (delivered_at, available_at),\n\tINDEX %s (aggregate_key, id)\n)",
+			table, columns, idx("pending"), idx("key"))
+		if _, err := o.conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("创建发件箱表失败: %w", err)
+		}
+		return nil
+	}
+	for _, query := range []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", table, columns),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (delivered_at, available_at)", idx("pending"), table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (aggregate_key, id)", idx("key"), table),
+	} {
+		if _, err := o.conn.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("创建发件箱表失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Publish 在 ctx 中的事务内写入事件，必须在 Connection.WithTx 或 DBManager.WithTx 中调用
+// payload 为 []byte 或 string 时原样写入，其他类型编码为 JSON
+func (o *Outbox) Publish(ctx context.Context, topic, key string, payload any) error {
+	tx, ok := o.conn.Querier(ctx).(*sql.Tx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	return o.PublishTx(ctx, tx, topic, key, payload)
+}
+
+// PublishTx 在指定事务内写入事件，用于自行管理 *sql.Tx 的代码
+func (o *Outbox) PublishTx(ctx context.Context, tx *sql.Tx, topic, key string, payload any) error {
+	if topic == "" {
+		return errors.New("outbox 事件的 topic 不能为空")
+	}
+	data, err := encodePayload(payload)
+	if err != nil {
+		return fmt.Errorf("编码事件失败: %w", err)
+	}
+	now := time.Now().UnixMilli()
+	query, args, err := o.conn.Builder().Insert(o.tableName).
+		Columns("topic", "aggregate_key", "payload", "available_at", "created_at").
+		Values(topic, key, data, now, now).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func encodePayload(payload any) ([]byte, error) {
+	switch v := payload.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,305 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/orm"
+	"github.com/wuwuseo/cmf/orm/outbox"
+	_ "modernc.org/sqlite"
+)
+
+func newTestOutbox(t *testing.T) (*orm.Connection, *outbox.Outbox) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "outbox.db"), TablePrefix: "cmf_"},
+	}
+	manager, err := orm.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	conn, _ := manager.Connection()
+	box := outbox.New(conn)
+	if err := box.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "CREATE TABLE cmf_orders (id INTEGER PRIMARY KEY, status TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	return conn, box
+}
+
+// publishOrder 在同一事务中写入订单与事件
+func publishOrder(t *testing.T, conn *orm.Connection, box *outbox.Outbox, id int, key string) {
+	t.Helper()
+	ctx := context.Background()
+	err := conn.WithTx(ctx, nil, func(ctx context.Context) error {
+		if _, err := conn.Builder().Insert("orders").Columns("id", "status").Values(id, "created").Exec(ctx); err != nil {
+			return err
+		}
+		return box.Publish(ctx, "order.created", key, map[string]int{"id": id})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recorder 记录处理函数收到的事件
+type recorder struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+func (r *recorder) handle(ctx context.Context, msg *outbox.Message) error {
+	var payload struct{ ID int }
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, payload.ID)
+	return nil
+}
+
+func (r *recorder) received() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.ids...)
+}
+
+func TestPublish_RequiresTransaction(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+
+	if err := box.Publish(ctx, "order.created", "", "{}"); !errors.Is(err, outbox.ErrNoTransaction) {
+		t.Fatalf("事务外发布应返回 ErrNoTransaction，实际 %v", err)
+	}
+
+	// 事务回滚时事件与业务数据一起丢弃
+	rollback := errors.New("rollback")
+	err := conn.WithTx(ctx, nil, func(ctx context.Context) error {
+		if err := box.Publish(ctx, "order.created", "1", "{}"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("期望返回回滚错误，实际 %v", err)
+	}
+	relay := outbox.NewRelay(box, outbox.RelayConfig{})
+	pending, _, err := relay.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("回滚后不应有待投递事件，实际 %d", pending)
+	}
+}
+
+func TestRelay_ProcessOnce(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+	publishOrder(t, conn, box, 1, "")
+	publishOrder(t, conn, box, 2, "")
+
+	rec := &recorder{}
+	relay := outbox.NewRelay(box, outbox.RelayConfig{})
+	relay.Handle("order.created", rec.handle)
+
+	n, err := relay.ProcessOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("期望投递 2 条事件，实际 %d", n)
+	}
+	if got := rec.received(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("期望按写入顺序收到 [1 2]，实际 %v", got)
+	}
+
+	// 已投递的事件不会再次投递
+	if n, _ := relay.ProcessOnce(ctx); n != 0 {
+		t.Errorf("不应重复投递，实际 %d", n)
+	}
+	if pending, _, _ := relay.Stats(ctx); pending != 0 {
+		t.Errorf("期望没有待投递事件，实际 %d", pending)
+	}
+}
+
+func TestRelay_UnhandledTopic(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+	publishOrder(t, conn, box, 1, "")
+
+	relay := outbox.NewRelay(box, outbox.RelayConfig{})
+	relay.Handle("user.created", func(context.Context, *outbox.Message) error { return nil })
+	if n, err := relay.ProcessOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("未注册的主题不应被认领，实际 %d, %v", n, err)
+	}
+
+	rec := &recorder{}
+	relay.Handle(outbox.AnyTopic, rec.handle)
+	if n, err := relay.ProcessOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("通配处理函数应认领全部主题，实际 %d, %v", n, err)
+	}
+}
+
+func TestRelay_RetryAndFail(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+	publishOrder(t, conn, box, 1, "")
+
+	attempts := 0
+	relay := outbox.NewRelay(box, outbox.RelayConfig{MaxAttempts: 3, RetryBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	relay.Handle("order.created", func(ctx context.Context, msg *outbox.Message) error {
+		if msg.Attempts != attempts {
+			t.Errorf("期望 Attempts 为 %d，实际 %d", attempts, msg.Attempts)
+		}
+		attempts++
+		if attempts == 2 {
+			panic("boom")
+		}
+		return errors.New("unavailable")
+	})
+
+	for range 5 {
+		if _, err := relay.ProcessOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if attempts != 3 {
+		t.Errorf("期望最多投递 3 次，实际 %d", attempts)
+	}
+	pending, failed, err := relay.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 || failed != 1 {
+		t.Errorf("期望 0 条待投递、1 条失败，实际 %d、%d", pending, failed)
+	}
+}
+
+func TestRelay_OrderingPerKey(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+	publishOrder(t, conn, box, 1, "a")
+	publishOrder(t, conn, box, 2, "a")
+	publishOrder(t, conn, box, 3, "b")
+
+	rec := &recorder{}
+	fail := true
+	relay := outbox.NewRelay(box, outbox.RelayConfig{RetryBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	relay.Handle("order.created", func(ctx context.Context, msg *outbox.Message) error {
+		if msg.Key == "a" && fail {
+			fail = false
+			return errors.New("unavailable")
+		}
+		return rec.handle(ctx, msg)
+	})
+
+	// 同一 Key 每批只认领最早的事件，失败后后续事件等待其重试成功
+	if n, _ := relay.ProcessOnce(ctx); n != 2 {
+		t.Fatalf("期望第一批认领 2 条事件，实际 %d", n)
+	}
+	if got := rec.received(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("期望只投递了 Key b 的事件，实际 %v", got)
+	}
+	for range 3 {
+		time.Sleep(5 * time.Millisecond)
+		if _, err := relay.ProcessOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.received(); len(got) != 3 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("期望 Key a 的事件按顺序投递，实际 %v", got)
+	}
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	ctx := context.Background()
+	publishOrder(t, conn, box, 1, "")
+	publishOrder(t, conn, box, 2, "")
+
+	relay := outbox.NewRelay(box, outbox.RelayConfig{Retention: time.Millisecond})
+	relay.Handle("order.created", func(context.Context, *outbox.Message) error { return nil })
+	if _, err := relay.ProcessOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	publishOrder(t, conn, box, 3, "")
+
+	n, err := relay.Cleanup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("期望清理 2 条已投递记录，实际 %d", n)
+	}
+	if pending, _, _ := relay.Stats(ctx); pending != 1 {
+		t.Errorf("未投递的事件不应被清理，实际待投递 %d", pending)
+	}
+}
+
+func TestRelay_StartClose(t *testing.T) {
+	conn, box := newTestOutbox(t)
+	rec := &recorder{}
+	relay := outbox.NewRelay(box, outbox.RelayConfig{PollInterval: 10 * time.Millisecond})
+	relay.Handle("order.created", rec.handle)
+	if err := relay.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay.Start(context.Background()); !errors.Is(err, outbox.ErrRelayStarted) {
+		t.Errorf("重复启动应返回 ErrRelayStarted，实际 %v", err)
+	}
+
+	publishOrder(t, conn, box, 1, "")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rec.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := relay.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.received(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("期望后台投递事件 1，实际 %v", got)
+	}
+}
+
+// fakeStream 记录发布到 Stream 的消息
+type fakeStream struct {
+	stream  string
+	payload any
+}
+
+func (f *fakeStream) Publish(ctx context.Context, stream string, payload any) (string, error) {
+	f.stream, f.payload = stream, payload
+	return "1-0", nil
+}
+
+func TestStreamHandler(t *testing.T) {
+	stream := &fakeStream{}
+	msg := &outbox.Message{Topic: "order.created", Payload: []byte(`{"id":1}`)}
+
+	if err := outbox.StreamHandler(stream, "")(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if stream.stream != "order.created" || string(stream.payload.([]byte)) != `{"id":1}` {
+		t.Errorf("stream 为空时应发布到主题同名的 Stream，实际 %s %v", stream.stream, stream.payload)
+	}
+
+	if err := outbox.StreamHandler(stream, "events")(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if stream.stream != "events" {
+		t.Errorf("期望发布到 events，实际 %s", stream.stream)
+	}
+}
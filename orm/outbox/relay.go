@@ -0,0 +1,407 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	cmflog "github.com/wuwuseo/cmf/log"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize       = 100
+	defaultPollInterval    = time.Second
+	defaultLockTimeout     = time.Minute
+	defaultMaxAttempts     = 10
+	defaultRetryBackoff    = time.Second
+	defaultMaxBackoff      = 5 * time.Minute
+	defaultRetention       = 24 * time.Hour
+	defaultCleanupInterval = 10 * time.Minute
+	maxErrorLength         = 1000
+
+	// AnyTopic 注册到该主题的处理函数处理没有专属处理函数的全部主题
+	AnyTopic = "*"
+)
+
+// ErrRelayStarted Relay 已启动
+var ErrRelayStarted = errors.New("outbox relay already started")
+
+// Handler 事件处理函数，返回 nil 时标记为已投递，返回错误或 panic 时按退避重试
+// 同一事件可能因进程崩溃等原因被投递多次，处理函数应当幂等
+type Handler func(ctx context.Context, msg *Message) error
+
+// StreamPublisher 发布到 Redis Stream，redis.Producer 实现了该接口
+type StreamPublisher interface {
+	Publish(ctx context.Context, stream string, payload any) (string, error)
+}
+
+// StreamHandler 返回将事件原样转发到 Redis Stream 的处理函数，stream 为空时使用事件的 Topic
+func StreamHandler(publisher StreamPublisher, stream string) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		target := stream
+		if target == "" {
+			target = msg.Topic
+		}
+		_, err := publisher.Publish(ctx, target, msg.Payload)
+		return err
+	}
+}
+
+// RelayConfig 投递器配置
+type RelayConfig struct {
+	WorkerID     string        // 认领记录时写入的实例标识，默认 主机名-进程号
+	BatchSize    int           // 每次认领的最大事件数，默认 100
+	PollInterval time.Duration // 没有待投递事件时的轮询间隔，默认 1 秒
+	// LockTimeout 认领的租约时长，超过后其他实例可以重新认领，应大于单批事件的处理耗时，默认 1 分钟
+	LockTimeout time.Duration
+
+	MaxAttempts  int           // 最大投递次数，达到后标记为失败不再投递，默认 10
+	RetryBackoff time.Duration // 首次重试的等待时间，之后按指数增长，默认 1 秒
+	MaxBackoff   time.Duration // 重试等待时间上限，默认 5 分钟
+
+	Retention       time.Duration // 已投递记录的保留时长，默认 24 小时
+	CleanupInterval time.Duration // 清理已投递记录的间隔，默认 10 分钟
+
+	Logger cmflog.Logger // 日志，默认使用全局日志
+}
+
+// Relay 发件箱投递器
+// 轮询认领到期的事件并按主题分发，多个实例可以同时运行：认领通过条件更新实现，
+// 同一事件同一时间只会被一个实例处理；同一 Key 存在更早的未投递事件时，后续事件等待其完成
+type Relay struct {
+	outbox *Outbox
+	config RelayConfig
+	logger cmflog.Logger
+
+	handlersMu sync.RWMutex
+	handlers   map[string]Handler
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewRelay 创建投递器
+func NewRelay(outbox *Outbox, config RelayConfig) *Relay {
+	if config.WorkerID == "" {
+		hostname, _ := os.Hostname()
+		config.WorkerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = defaultLockTimeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = defaultCleanupInterval
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = cmflog.GetDefault()
+	}
+	return &Relay{
+		outbox:   outbox,
+		config:   config,
+		logger:   logger.With(zap.String("outbox", outbox.tableName), zap.String("worker", config.WorkerID)),
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle 注册主题的处理函数，topic 为 AnyTopic 时处理其他未注册的主题
+// 未注册处理函数的主题不会被当前实例认领，可由其他服务的实例处理
+func (r *Relay) Handle(topic string, handler Handler) {
+	r.handlersMu.Lock()
+	defer r.handlersMu.Unlock()
+	r.handlers[topic] = handler
+}
+
+func (r *Relay) handler(topic string) Handler {
+	r.handlersMu.RLock()
+	defer r.handlersMu.RUnlock()
+	if h, ok := r.handlers[topic]; ok {
+		return h
+	}
+	return r.handlers[AnyTopic]
+}
+
+// topics 返回可认领的主题，注册了 AnyTopic 时返回 nil 表示不限制
+func (r *Relay) topics() []any {
+	r.handlersMu.RLock()
+	defer r.handlersMu.RUnlock()
+	if _, ok := r.handlers[AnyTopic]; ok {
+		return nil
+	}
+	topics := make([]any, 0, len(r.handlers))
+	for topic := range r.handlers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Start 创建发件箱表（不存在时）并启动投递与清理协程
+// 作为服务注册到 Bootstrap 后会随应用启动
+func (r *Relay) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return ErrRelayStarted
+	}
+	if err := r.outbox.EnsureTable(ctx); err != nil {
+		return err
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	r.cancel = cancel
+	r.started = true
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.poll(runCtx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.cleanupLoop(runCtx)
+	}()
+	r.logger.Info("发件箱投递器已启动")
+	return nil
+}
+
+// Close 停止认领新事件，并等待进行中的批次处理完成
+func (r *Relay) Close() error {
+	r.mu.Lock()
+	if !r.started {
+		r.mu.Unlock()
+		return nil
+	}
+	r.started = false
+	r.cancel()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	r.logger.Info("发件箱投递器已停止")
+	return nil
+}
+
+func (r *Relay) poll(ctx context.Context) {
+	for ctx.Err() == nil {
+		// 处理函数使用不随 Close 取消的上下文，保证进行中的批次正常完成
+		n, err := r.ProcessOnce(context.WithoutCancel(ctx))
+		if err != nil {
+			r.logger.Error("投递发件箱事件失败", zap.Error(err))
+		}
+		if err == nil && n >= r.config.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+func (r *Relay) cleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.config.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("清理已投递的发件箱事件失败", zap.Error(err))
+		}
+	}
+}
+
+// ProcessOnce 认领并投递一批到期的事件，返回本批认领的事件数
+func (r *Relay) ProcessOnce(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, msg := range messages {
+		err := r.dispatch(ctx, msg)
+		if markErr := r.finish(ctx, msg, err); markErr != nil {
+			return len(messages), markErr
+		}
+	}
+	return len(messages), nil
+}
+
+// claim 查询到期的事件并逐条通过条件更新认领
+func (r *Relay) claim(ctx context.Context) ([]*Message, error) {
+	topics := r.topics()
+	if topics != nil && len(topics) == 0 {
+		return nil, nil
+	}
+	conn := r.outbox.conn
+	b := conn.Builder()
+	now := time.Now().UnixMilli()
+
+	query := b.Select("o.id", "o.topic", "o.aggregate_key", "o.payload", "o.attempts", "o.created_at").
+		From(r.outbox.tableName+" o").
+		Where("o.delivered_at IS NULL").
+		Where("o.failed_at IS NULL").
+		Where("o.available_at <= ?", now).
+		Where("o.locked_until < ?", now).
+		// 同一 Key 只投递最早的未完成事件，失败的事件不阻塞后续事件
+		Where(fmt.Sprintf(`o.aggregate_key = '' OR NOT EXISTS (
+	SELECT 1 FROM %s p WHERE p.aggregate_key = o.aggregate_key AND p.id < o.id AND p.delivered_at IS NULL AND p.failed_at IS NULL)`,
+			b.Table(r.outbox.tableName))).
+		OrderBy("o.id").
+		Limit(r.config.BatchSize)
+	if topics != nil {
+		query.WhereIn("o.topic", topics...)
+	}
+	sqlText, args, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Primary().QueryContext(ctx, sqlText, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询待投递事件失败: %w", err)
+	}
+	var candidates []*Message
+	for rows.Next() {
+		msg := &Message{}
+		var createdAt int64
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload, &msg.Attempts, &createdAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		msg.CreatedAt = time.UnixMilli(createdAt)
+		candidates = append(candidates, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lockedUntil := time.Now().Add(r.config.LockTimeout).UnixMilli()
+	claimed := candidates[:0]
+	for _, msg := range candidates {
+		result, err := b.Update(r.outbox.tableName).
+			Set("locked_by", r.config.WorkerID).
+			Set("locked_until", lockedUntil).
+			Where("id = ?", msg.ID).
+			Where("locked_until < ?", now).
+			Where("delivered_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return claimed, fmt.Errorf("认领事件 %d 失败: %w", msg.ID, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 1 {
+			claimed = append(claimed, msg)
+		}
+	}
+	return claimed, nil
+}
+
+// dispatch 调用处理函数，panic 视为处理失败
+func (r *Relay) dispatch(ctx context.Context, msg *Message) (err error) {
+	handler := r.handler(msg.Topic)
+	if handler == nil {
+		return fmt.Errorf("主题 %s 没有处理函数", msg.Topic)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panic: %v", p)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// finish 根据处理结果标记为已投递、等待重试或失败，并释放认领
+func (r *Relay) finish(ctx context.Context, msg *Message, handleErr error) error {
+	now := time.Now()
+	update := r.outbox.conn.Builder().Update(r.outbox.tableName).
+		Set("locked_by", "").
+		Set("locked_until", 0).
+		Where("id = ?", msg.ID).
+		Where("locked_by = ?", r.config.WorkerID)
+
+	if handleErr == nil {
+		update.Set("delivered_at", now.UnixMilli())
+	} else {
+		attempts := msg.Attempts + 1
+		errText := handleErr.Error()
+		if len(errText) > maxErrorLength {
+			errText = errText[:maxErrorLength]
+		}
+		update.Set("attempts", attempts).Set("last_error", errText)
+		if attempts >= r.config.MaxAttempts {
+			update.Set("failed_at", now.UnixMilli())
+			r.logger.Error("发件箱事件投递失败次数达到上限，不再重试",
+				zap.Int64("id", msg.ID), zap.String("topic", msg.Topic), zap.String("key", msg.Key),
+				zap.Int("attempts", attempts), zap.Error(handleErr))
+		} else {
+			update.Set("available_at", now.Add(r.backoff(attempts)).UnixMilli())
+			r.logger.Warn("发件箱事件投递失败，等待重试",
+				zap.Int64("id", msg.ID), zap.String("topic", msg.Topic), zap.String("key", msg.Key),
+				zap.Int("attempts", attempts), zap.Error(handleErr))
+		}
+	}
+	if _, err := update.Exec(ctx); err != nil {
+		return fmt.Errorf("更新事件 %d 状态失败: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.RetryBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.config.MaxBackoff)
+}
+
+// Cleanup 删除投递时间早于保留时长的记录，返回删除的行数
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-r.config.Retention).UnixMilli()
+	result, err := r.outbox.conn.Builder().Delete(r.outbox.tableName).
+		Where("delivered_at IS NOT NULL").
+		Where("delivered_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Stats 返回待投递与失败的事件数
+func (r *Relay) Stats(ctx context.Context) (pending, failed int64, err error) {
+	b := r.outbox.conn.Builder()
+	err = b.Select("COUNT(*)").From(r.outbox.tableName).
+		Where("delivered_at IS NULL").Where("failed_at IS NULL").
+		QueryRow(ctx, &pending)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = b.Select("COUNT(*)").From(r.outbox.tableName).
+		Where("failed_at IS NOT NULL").
+		QueryRow(ctx, &failed)
+	return pending, failed, err
+}
@@ -0,0 +1,306 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const defaultPageSize = 20
+
+// ErrRecordNotFound 记录不存在
+var ErrRecordNotFound = errors.New("记录不存在")
+
+// Tabler 模型实现该接口时，NewRepository 未指定表名则使用 TableName 的返回值
+type Tabler interface {
+	TableName() string
+}
+
+// Page 分页结果
+type Page[T any] struct {
+	Items []T   `json:"items"`
+	Total int64 `json:"total"`
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+	Pages int   `json:"pages"`
+}
+
+// Repository 基于结构体 db 标签的通用仓储
+//
+// 表名自动添加连接的表前缀；存在 created_at、updated_at 列时自动维护时间戳；
+// 存在 deleted_at 列时启用软删除（字段为 *time.Time、sql.NullTime 或整数），查询自动排除已删除记录。
+// 所有操作通过 Querier(ctx) 执行，在 WithTx 中自动使用事务
+type Repository[T any] struct {
+	builder  *Builder
+	table    string
+	meta     *modelMeta
+	unscoped bool
+}
+
+// NewRepository 创建仓储，table 为不含前缀的表名
+func NewRepository[T any](conn *Connection, table string) (*Repository[T], error) {
+	meta, err := modelMetaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	if meta.pk == nil {
+		return nil, fmt.Errorf("%s 没有主键字段，请为 id 列或带 pk 选项的字段添加 db 标签", meta.typ)
+	}
+	if meta.deletedAt != nil && !softDeleteType(meta.deletedAt.typ) {
+		// time.Time 插入时写入零值而不是 NULL，新记录会被 deleted_at IS NULL 条件排除
+		return nil, fmt.Errorf("%s 的 deleted_at 字段类型 %s 无法表示未删除，请使用 *time.Time、sql.NullTime 或整数类型", meta.typ, meta.deletedAt.typ)
+	}
+	if table == "" {
+		var zero T
+		if tabler, ok := any(&zero).(Tabler); ok {
+			table = tabler.TableName()
+		} else if tabler, ok := any(zero).(Tabler); ok {
+			table = tabler.TableName()
+		}
+	}
+	if table == "" {
+		return nil, ErrNoTable
+	}
+	return &Repository[T]{builder: conn.Builder(), table: table, meta: meta}, nil
+}
+
+// Unscoped 返回包含已软删除记录的仓储副本，Delete 将执行物理删除
+func (r *Repository[T]) Unscoped() *Repository[T] {
+	clone := *r
+	clone.unscoped = true
+	return &clone
+}
+
+// Find 按主键查询，不存在时返回 ErrRecordNotFound
+func (r *Repository[T]) Find(ctx context.Context, id any) (*T, error) {
+	items, err := r.query(ctx, r.selectQuery(map[string]any{r.meta.pk.column: id}).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return &items[0], nil
+}
+
+// FindBy 按列值查询，值为 nil 时匹配 IS NULL，为切片时匹配 IN
+func (r *Repository[T]) FindBy(ctx context.Context, where map[string]any) ([]T, error) {
+	return r.query(ctx, r.selectQuery(where).OrderBy(r.builder.dialect.Quote(r.meta.pk.column)))
+}
+
+// FindWhere 按条件表达式查询，如 FindWhere(ctx, "age > ?", 18)
+func (r *Repository[T]) FindWhere(ctx context.Context, expr string, args ...any) ([]T, error) {
+	return r.query(ctx, r.selectQuery(nil).Where(expr, args...).OrderBy(r.builder.dialect.Quote(r.meta.pk.column)))
+}
+
+// Count 统计符合条件的记录数
+func (r *Repository[T]) Count(ctx context.Context, where map[string]any) (int64, error) {
+	var count int64
+	query := r.scope(r.builder.Select("COUNT(*)").From(r.table), where)
+	if err := query.QueryRow(ctx, &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Paginate 分页查询，page 从 1 开始，size 小于等于 0 时使用默认值 20
+func (r *Repository[T]) Paginate(ctx context.Context, page, size int, where map[string]any) (*Page[T], error) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	total, err := r.Count(ctx, where)
+	if err != nil {
+		return nil, err
+	}
+	result := &Page[T]{Total: total, Page: page, Size: size, Pages: int((total + int64(size) - 1) / int64(size))}
+	if total == 0 || (page-1)*size >= int(total) {
+		result.Items = []T{}
+		return result, nil
+	}
+	query := r.selectQuery(where).OrderBy(r.builder.dialect.Quote(r.meta.pk.column)).Limit(size).Offset((page - 1) * size)
+	if result.Items, err = r.query(ctx, query); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Create 插入记录，自增主键为零值时由数据库生成并回写到 entity
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	v := reflect.ValueOf(entity).Elem()
+	now := time.Now()
+	for _, f := range []*fieldMeta{r.meta.createdAt, r.meta.updatedAt} {
+		if f != nil && v.FieldByIndex(f.index).IsZero() {
+			setTimestamp(v.FieldByIndex(f.index), now)
+		}
+	}
+
+	pk := v.FieldByIndex(r.meta.pk.index)
+	generate := r.meta.autoPK && pk.IsZero()
+	values := make(map[string]any, len(r.meta.fields))
+	for _, f := range r.meta.fields {
+		if f == r.meta.pk && generate {
+			continue
+		}
+		values[f.column] = v.FieldByIndex(f.index).Interface()
+	}
+	insert := r.builder.Insert(r.table).SetMap(values)
+	if !generate {
+		_, err := insert.Exec(ctx)
+		return err
+	}
+
+	// Postgres 没有 LastInsertId，使用 RETURNING 获取生成的主键
+	if r.builder.dialect.Driver == DriverPostgres {
+		return insert.Returning(r.builder.dialect.Quote(r.meta.pk.column)).QueryRow(ctx, pk.Addr().Interface())
+	}
+	result, err := insert.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if pk.CanInt() {
+		pk.SetInt(id)
+	} else {
+		pk.SetUint(uint64(id))
+	}
+	return nil
+}
+
+// Update 按主键更新除主键与 created_at 外的全部列，并刷新 updated_at
+// 启用软删除时不更新已删除的记录，记录不存在时返回 ErrRecordNotFound
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	v := reflect.ValueOf(entity).Elem()
+	if r.meta.updatedAt != nil {
+		setTimestamp(v.FieldByIndex(r.meta.updatedAt.index), time.Now())
+	}
+	values := make(map[string]any, len(r.meta.fields))
+	for _, f := range r.meta.fields {
+		if f == r.meta.pk || f == r.meta.createdAt || f == r.meta.deletedAt {
+			continue
+		}
+		values[f.column] = v.FieldByIndex(f.index).Interface()
+	}
+	id := v.FieldByIndex(r.meta.pk.index).Interface()
+	update := r.builder.Update(r.table).SetMap(values).
+		Where(r.builder.dialect.Quote(r.meta.pk.column)+" = ?", id)
+	if r.meta.deletedAt != nil && !r.unscoped {
+		update.Where(r.notDeleted())
+	}
+	result, err := update.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// MySQL 的影响行数不包含值未变化的行，需要再确认记录是否存在
+		count, err := r.Count(ctx, map[string]any{r.meta.pk.column: id})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// Delete 按主键删除，启用软删除时设置 deleted_at，记录不存在时返回 ErrRecordNotFound
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	var result sql.Result
+	var err error
+	pkCond := r.builder.dialect.Quote(r.meta.pk.column) + " = ?"
+	if r.meta.deletedAt != nil && !r.unscoped {
+		update := r.builder.Update(r.table).Where(pkCond, id).Where(r.notDeleted())
+		deletedAt := reflect.New(r.meta.deletedAt.typ).Elem()
+		setTimestamp(deletedAt, time.Now())
+		result, err = update.Set(r.meta.deletedAt.column, deletedAt.Interface()).Exec(ctx)
+	} else {
+		result, err = r.builder.Delete(r.table).Where(pkCond, id).Exec(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// selectQuery 查询模型全部列
+func (r *Repository[T]) selectQuery(where map[string]any) *SelectBuilder {
+	columns := r.meta.columns()
+	for i, column := range columns {
+		columns[i] = r.builder.dialect.Quote(column)
+	}
+	return r.scope(r.builder.Select(columns...).From(r.table), where)
+}
+
+// scope 添加条件与软删除过滤
+func (r *Repository[T]) scope(query *SelectBuilder, where map[string]any) *SelectBuilder {
+	columns := make([]string, 0, len(where))
+	for column := range where {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		quoted := r.builder.dialect.Quote(column)
+		value := where[column]
+		rv := reflect.ValueOf(value)
+		switch {
+		case value == nil:
+			query.Where(quoted + " IS NULL")
+		case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8:
+			values := make([]any, rv.Len())
+			for i := range values {
+				values[i] = rv.Index(i).Interface()
+			}
+			query.WhereIn(quoted, values...)
+		default:
+			query.Where(quoted+" = ?", value)
+		}
+	}
+	if r.meta.deletedAt != nil && !r.unscoped {
+		query.Where(r.notDeleted())
+	}
+	return query
+}
+
+// softDeleteType 判断 deleted_at 字段类型能否表示未删除：可为 NULL 的时间或以 0 表示未删除的整数
+func softDeleteType(typ reflect.Type) bool {
+	switch typ {
+	case reflect.TypeFor[*time.Time](), reflect.TypeFor[sql.NullTime]():
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// notDeleted 未删除条件，整数类型的 deleted_at 以 0 表示未删除
+func (r *Repository[T]) notDeleted() string {
+	column := r.builder.dialect.Quote(r.meta.deletedAt.column)
+	switch r.meta.deletedAt.typ.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return column + " = 0"
+	}
+	return column + " IS NULL"
+}
+
+func (r *Repository[T]) query(ctx context.Context, query *SelectBuilder) ([]T, error) {
+	rows, err := query.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ScanRows[T](rows)
+}
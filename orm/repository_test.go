@@ -0,0 +1,206 @@
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wuwuseo/cmf/config"
+	cmform "github.com/wuwuseo/cmf/orm"
+)
+
+// Timestamps 嵌入的时间戳字段
+type Timestamps struct {
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// Article 测试模型
+type Article struct {
+	ID      int64  `db:"id"`
+	Title   string `db:"title"`
+	Views   int    `db:"views"`
+	Ignored string `db:"-"`
+	Timestamps
+}
+
+func (Article) TableName() string { return "articles" }
+
+func newArticleRepo(t *testing.T) (*cmform.Repository[Article], *cmform.Connection) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "repo.db"), TablePrefix: "cmf_"},
+	}
+	manager, err := cmform.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	_, err = manager.GetDB().Exec(`CREATE TABLE cmf_articles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, views INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _ := manager.Connection()
+	repo, err := cmform.NewRepository[Article](conn, "")
+	if err != nil {
+		t.Fatalf("NewRepository 失败: %v", err)
+	}
+	return repo, conn
+}
+
+// TestRepository_CRUD 测试增删改查与时间戳
+func TestRepository_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newArticleRepo(t)
+
+	article := &Article{Title: "hello", Views: 1}
+	if err := repo.Create(ctx, article); err != nil {
+		t.Fatalf("Create 失败: %v", err)
+	}
+	if article.ID == 0 || article.CreatedAt.IsZero() || article.UpdatedAt.IsZero() {
+		t.Fatalf("应回写主键与时间戳: %+v", article)
+	}
+
+	found, err := repo.Find(ctx, article.ID)
+	if err != nil || found.Title != "hello" || found.CreatedAt.Unix() != article.CreatedAt.Unix() {
+		t.Fatalf("Find 结果不正确: %+v %v", found, err)
+	}
+
+	found.Title = "world"
+	found.Views = 5
+	if err := repo.Update(ctx, found); err != nil {
+		t.Fatalf("Update 失败: %v", err)
+	}
+	items, err := repo.FindBy(ctx, map[string]any{"title": "world", "views": []int{5, 6}})
+	if err != nil || len(items) != 1 || items[0].ID != article.ID {
+		t.Fatalf("FindBy 结果不正确: %+v %v", items, err)
+	}
+
+	if _, err := repo.Find(ctx, 999); !errors.Is(err, cmform.ErrRecordNotFound) {
+		t.Errorf("不存在的记录应返回 ErrRecordNotFound，得到 %v", err)
+	}
+}
+
+// TestRepository_SoftDelete 测试软删除
+func TestRepository_SoftDelete(t *testing.T) {
+	ctx := context.Background()
+	repo, conn := newArticleRepo(t)
+
+	a, b := &Article{Title: "a"}, &Article{Title: "b"}
+	repo.Create(ctx, a)
+	repo.Create(ctx, b)
+
+	if err := repo.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("Delete 失败: %v", err)
+	}
+	if err := repo.Delete(ctx, a.ID); !errors.Is(err, cmform.ErrRecordNotFound) {
+		t.Errorf("重复删除应返回 ErrRecordNotFound，得到 %v", err)
+	}
+	a.Title = "restored"
+	if err := repo.Update(ctx, a); !errors.Is(err, cmform.ErrRecordNotFound) {
+		t.Errorf("更新已软删除的记录应返回 ErrRecordNotFound，得到 %v", err)
+	}
+	if err := repo.Update(ctx, &Article{ID: 999, Title: "missing"}); !errors.Is(err, cmform.ErrRecordNotFound) {
+		t.Errorf("更新不存在的记录应返回 ErrRecordNotFound，得到 %v", err)
+	}
+	if err := repo.Update(ctx, b); err != nil {
+		t.Errorf("更新未删除的记录失败: %v", err)
+	}
+	if _, err := repo.Find(ctx, a.ID); !errors.Is(err, cmform.ErrRecordNotFound) {
+		t.Errorf("软删除的记录不应被查询到，得到 %v", err)
+	}
+	if count, _ := repo.Count(ctx, nil); count != 1 {
+		t.Errorf("软删除后应剩 1 条，实际 %d 条", count)
+	}
+
+	trashed, err := repo.Unscoped().Find(ctx, a.ID)
+	if err != nil || trashed.DeletedAt == nil {
+		t.Fatalf("Unscoped 应查询到已删除记录: %+v %v", trashed, err)
+	}
+	if err := repo.Unscoped().Delete(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	conn.Primary().QueryRow("SELECT COUNT(*) FROM cmf_articles").Scan(&total)
+	if total != 1 {
+		t.Errorf("Unscoped 删除应为物理删除，剩余 %d 行", total)
+	}
+}
+
+// TestRepository_Paginate 测试分页
+func TestRepository_Paginate(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newArticleRepo(t)
+	for i := range 5 {
+		repo.Create(ctx, &Article{Title: "p", Views: i})
+	}
+
+	page, err := repo.Paginate(ctx, 2, 2, map[string]any{"title": "p"})
+	if err != nil {
+		t.Fatalf("Paginate 失败: %v", err)
+	}
+	if page.Total != 5 || page.Pages != 3 || len(page.Items) != 2 || page.Items[0].Views != 2 {
+		t.Errorf("分页结果不正确: %+v", page)
+	}
+	page, _ = repo.Paginate(ctx, 9, 2, nil)
+	if len(page.Items) != 0 || page.Items == nil {
+		t.Errorf("超出范围的页应返回空列表: %+v", page)
+	}
+}
+
+// TestScanRows 测试将任意查询扫描为结构体
+func TestScanRows(t *testing.T) {
+	ctx := context.Background()
+	repo, conn := newArticleRepo(t)
+	repo.Create(ctx, &Article{Title: "x", Views: 3})
+
+	type summary struct {
+		Title string `db:"title"`
+		Total int    `db:"total"`
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT title, views * 2 AS total, 'extra' AS other FROM cmf_articles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := cmform.ScanRows[summary](rows)
+	if err != nil || len(items) != 1 || items[0].Total != 6 {
+		t.Errorf("ScanRows 结果不正确: %+v %v", items, err)
+	}
+
+	type noKey struct {
+		Name string `db:"name"`
+	}
+	if _, err := cmform.NewRepository[noKey](conn, "t"); err == nil {
+		t.Error("没有主键的模型应返回错误")
+	}
+
+	// time.Time 的 deleted_at 插入零值而不是 NULL，新记录会被软删除条件排除
+	type plainDeletedAt struct {
+		ID        int64     `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+	if _, err := cmform.NewRepository[plainDeletedAt](conn, "t"); err == nil {
+		t.Error("deleted_at 为 time.Time 的模型应返回错误")
+	}
+	type nullDeletedAt struct {
+		ID        int64        `db:"id"`
+		DeletedAt sql.NullTime `db:"deleted_at"`
+	}
+	if _, err := cmform.NewRepository[nullDeletedAt](conn, "t"); err != nil {
+		t.Errorf("deleted_at 为 sql.NullTime 的模型应允许创建: %v", err)
+	}
+	type intDeletedAt struct {
+		ID        int64 `db:"id"`
+		DeletedAt int64 `db:"deleted_at"`
+	}
+	if _, err := cmform.NewRepository[intDeletedAt](conn, "t"); err != nil {
+		t.Errorf("deleted_at 为整数的模型应允许创建: %v", err)
+	}
+}
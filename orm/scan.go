@@ -0,0 +1,171 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 约定的时间戳与软删除列
+const (
+	columnCreatedAt = "created_at"
+	columnUpdatedAt = "updated_at"
+	columnDeletedAt = "deleted_at"
+)
+
+// fieldMeta 结构体字段与列的映射
+type fieldMeta struct {
+	column string
+	index  []int
+	typ    reflect.Type
+}
+
+// modelMeta 结构体的列映射，按类型缓存
+type modelMeta struct {
+	typ      reflect.Type
+	fields   []*fieldMeta
+	byColumn map[string]*fieldMeta
+
+	pk        *fieldMeta
+	autoPK    bool // 整数主键，插入时为零值则由数据库生成
+	createdAt *fieldMeta
+	updatedAt *fieldMeta
+	deletedAt *fieldMeta
+}
+
+var modelMetas sync.Map
+
+// modelMetaOf 解析结构体的 db 标签
+//
+// 只映射带 db 标签的导出字段，db:"-" 忽略，支持嵌入结构体（非指针）；
+// 标签选项 pk 指定主键，未指定时使用 id 列
+func modelMetaOf(typ reflect.Type) (*modelMeta, error) {
+	if cached, ok := modelMetas.Load(typ); ok {
+		return cached.(*modelMeta), nil
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s 不是结构体", typ)
+	}
+
+	meta := &modelMeta{typ: typ, byColumn: make(map[string]*fieldMeta)}
+	for _, f := range reflect.VisibleFields(typ) {
+		tag := f.Tag.Get("db")
+		if !f.IsExported() || tag == "" || tag == "-" || viaPointer(typ, f.Index) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		field := &fieldMeta{column: name, index: f.Index, typ: f.Type}
+		if _, dup := meta.byColumn[name]; dup {
+			return nil, fmt.Errorf("%s 的列 %s 重复", typ, name)
+		}
+		meta.fields = append(meta.fields, field)
+		meta.byColumn[name] = field
+		if opts == "pk" {
+			meta.pk = field
+		}
+	}
+	if meta.pk == nil {
+		meta.pk = meta.byColumn["id"]
+	}
+	if meta.pk != nil {
+		switch meta.pk.typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			meta.autoPK = true
+		}
+	}
+	meta.createdAt = meta.byColumn[columnCreatedAt]
+	meta.updatedAt = meta.byColumn[columnUpdatedAt]
+	meta.deletedAt = meta.byColumn[columnDeletedAt]
+
+	cached, _ := modelMetas.LoadOrStore(typ, meta)
+	return cached.(*modelMeta), nil
+}
+
+// viaPointer 字段是否经由嵌入的指针结构体提升而来，这类字段不做映射
+func viaPointer(typ reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		f := typ.Field(i)
+		if f.Type.Kind() == reflect.Pointer {
+			return true
+		}
+		typ = f.Type
+	}
+	return false
+}
+
+// columns 返回全部列名
+func (m *modelMeta) columns() []string {
+	columns := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		columns[i] = f.column
+	}
+	return columns
+}
+
+// ScanRows 将查询结果扫描为结构体切片并关闭 rows
+// 列按 db 标签映射到字段，结构体中不存在的列被忽略
+func ScanRows[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+	meta, err := modelMetaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	dest := make([]any, len(columns))
+	for rows.Next() {
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		for i, column := range columns {
+			if f, ok := meta.byColumn[column]; ok {
+				dest[i] = v.FieldByIndex(f.index).Addr().Interface()
+			} else {
+				dest[i] = new(any)
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ScanRow 将查询结果的第一行扫描为结构体并关闭 rows，没有结果时返回 sql.ErrNoRows
+func ScanRow[T any](rows *sql.Rows) (*T, error) {
+	items, err := ScanRows[T](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &items[0], nil
+}
+
+// setTimestamp 将时间写入时间戳字段，支持 time.Time、*time.Time、sql.NullTime 与整数（Unix 秒）
+func setTimestamp(v reflect.Value, t time.Time) {
+	switch v.Interface().(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(t))
+	case *time.Time:
+		v.Set(reflect.ValueOf(&t))
+	case sql.NullTime:
+		v.Set(reflect.ValueOf(sql.NullTime{Time: t, Valid: true}))
+	default:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			v.SetInt(t.Unix())
+		case reflect.Uint, reflect.Uint32, reflect.Uint64:
+			v.SetUint(uint64(t.Unix()))
+		}
+	}
+}
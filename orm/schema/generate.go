@@ -0,0 +1,231 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NullStyle 可空列的 Go 类型风格
+type NullStyle int
+
+const (
+	// NullSQL 使用 sql.NullString、sql.NullInt64、sql.NullTime 等类型
+	NullSQL NullStyle = iota
+	// NullPointer 使用 *string、*int64、*time.Time 等指针类型
+	NullPointer
+)
+
+// GenerateOptions 代码生成选项
+type GenerateOptions struct {
+	Package     string    // 包名，默认 models
+	TablePrefix string    // 生成类型名与 TableName 时去掉的表前缀
+	NullStyle   NullStyle // 可空列的类型风格
+	Repository  bool      // 是否生成 New{Type}Repository 构造函数
+}
+
+// GoType 返回列类型对应的 Go 类型以及需要导入的包
+func GoType(column Column, style NullStyle) (string, []string) {
+	base, unsigned := baseType(column.Type)
+
+	var typ, null string
+	var imports []string
+	switch base {
+	case "tinyint":
+		if strings.HasPrefix(column.Type, "tinyint(1)") {
+			typ, null = "bool", "sql.NullBool"
+		} else if unsigned {
+			typ, null = "uint8", "sql.NullByte"
+		} else {
+			typ, null = "int8", "sql.NullInt16"
+		}
+	case "bool", "boolean":
+		typ, null = "bool", "sql.NullBool"
+	case "smallint", "int2", "smallserial", "serial2":
+		typ, null = "int16", "sql.NullInt16"
+	case "mediumint", "int", "int4", "serial", "serial4":
+		if unsigned {
+			typ, null = "uint32", "sql.NullInt64"
+		} else {
+			typ, null = "int32", "sql.NullInt32"
+		}
+	case "integer":
+		// SQLite 的 INTEGER 为 64 位
+		typ, null = "int64", "sql.NullInt64"
+	case "bigint", "int8", "bigserial", "serial8":
+		if unsigned {
+			typ, null = "uint64", "sql.NullInt64"
+		} else {
+			typ, null = "int64", "sql.NullInt64"
+		}
+	case "float", "double", "real", "float4", "float8", "double precision":
+		typ, null = "float64", "sql.NullFloat64"
+	case "date", "datetime", "timestamp", "timestamptz", "timestamp with time zone", "timestamp without time zone":
+		typ, null = "time.Time", "sql.NullTime"
+		imports = append(imports, "time")
+	case "json", "jsonb":
+		imports = append(imports, "encoding/json")
+		return "json.RawMessage", imports
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "bytea":
+		return "[]byte", nil
+	default:
+		// 字符串、decimal（避免精度丢失）、枚举、uuid 等
+		typ, null = "string", "sql.NullString"
+	}
+
+	if !column.Nullable {
+		return typ, imports
+	}
+	if style == NullPointer {
+		return "*" + typ, imports
+	}
+	if null == "sql.NullTime" {
+		imports = nil
+	}
+	return null, append(imports, "database/sql")
+}
+
+// baseType 去掉长度、精度与 unsigned 等修饰，返回基础类型
+func baseType(typ string) (string, bool) {
+	unsigned := strings.Contains(typ, "unsigned")
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i] + typ[strings.IndexByte(typ, ')')+1:]
+	}
+	typ = strings.TrimSpace(strings.NewReplacer("unsigned", "", "zerofill", "").Replace(typ))
+	return strings.Join(strings.Fields(typ), " "), unsigned
+}
+
+// Generate 生成表对应的结构体、TableName 方法以及可选的仓储构造函数，返回格式化后的源码
+func Generate(tables []Table, opts GenerateOptions) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+	imports := make(map[string]bool)
+	if opts.Repository && len(tables) > 0 {
+		imports["github.com/wuwuseo/cmf/orm"] = true
+	}
+
+	var body bytes.Buffer
+	for _, table := range tables {
+		short := strings.TrimPrefix(table.Name, opts.TablePrefix)
+		typeName := exportedName(singular(short))
+
+		comment := fmt.Sprintf("%s 对应表 %s", typeName, table.Name)
+		if table.Comment != "" {
+			comment += "：" + singleLine(table.Comment)
+		}
+		fmt.Fprintf(&body, "\n// %s\ntype %s struct {\n", comment, typeName)
+		for _, column := range table.Columns {
+			typ, pkgs := GoType(column, opts.NullStyle)
+			for _, pkg := range pkgs {
+				imports[pkg] = true
+			}
+			fmt.Fprintf(&body, "\t%s %s `%s`", exportedName(column.Name), typ, tags(column))
+			if column.Comment != "" {
+				fmt.Fprintf(&body, " // %s", singleLine(column.Comment))
+			}
+			body.WriteString("\n")
+		}
+		body.WriteString("}\n")
+
+		fmt.Fprintf(&body, "\n// TableName 返回不含前缀的表名\nfunc (%s) TableName() string {\n\treturn %s\n}\n",
+			typeName, strconv.Quote(short))
+		if opts.Repository {
+			fmt.Fprintf(&body, "\n// New%[1]sRepository 创建 %[1]s 仓储\nfunc New%[1]sRepository(conn *orm.Connection) (*orm.Repository[%[1]s], error) {\n\treturn orm.NewRepository[%[1]s](conn, \"\")\n}\n",
+				typeName)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by cmf orm/schema. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n", opts.Package)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		out.WriteString("\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		out.WriteString(")\n")
+	}
+	out.Write(body.Bytes())
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成的代码失败: %w", err)
+	}
+	return source, nil
+}
+
+// tags 生成 db、json 与 validate 标签
+func tags(column Column) string {
+	db := column.Name
+	if column.PrimaryKey && column.Name != "id" {
+		db += ",pk"
+	}
+	result := fmt.Sprintf(`db:"%s" json:"%s"`, db, column.Name)
+
+	var rules []string
+	managed := column.PrimaryKey || column.AutoIncrement ||
+		column.Name == "created_at" || column.Name == "updated_at" || column.Name == "deleted_at"
+	if !managed && !column.Nullable && !column.HasDefault {
+		rules = append(rules, "required")
+	}
+	if typ, _ := GoType(Column{Type: column.Type}, NullSQL); typ == "string" && column.Length > 0 && !managed {
+		if column.Nullable {
+			rules = append(rules, "omitempty")
+		}
+		rules = append(rules, "max="+strconv.FormatInt(column.Length, 10))
+	}
+	if len(rules) > 0 {
+		result += fmt.Sprintf(` validate:"%s"`, strings.Join(rules, ","))
+	}
+	return result
+}
+
+// commonInitialisms 按 Go 命名习惯全部大写的缩写
+var commonInitialisms = map[string]bool{
+	"id": true, "ip": true, "url": true, "uri": true, "api": true, "uuid": true, "json": true,
+	"sql": true, "http": true, "https": true, "html": true, "xml": true, "ttl": true, "uid": true,
+}
+
+// exportedName 将 snake_case 转换为导出的 CamelCase，如 user_id -> UserID
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' || r == '.' }) {
+		lower := strings.ToLower(part)
+		if commonInitialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	result := b.String()
+	if result == "" || result[0] >= '0' && result[0] <= '9' {
+		result = "T" + result
+	}
+	return result
+}
+
+// singular 将表名的复数形式转换为单数作为类型名，只处理常见的英文规则
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && !strings.HasSuffix(name, "us") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
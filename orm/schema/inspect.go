@@ -0,0 +1,273 @@
+// Package schema 读取数据库表结构并生成对应的 Go 模型与仓储代码
+//
+// MySQL 与 Postgres 通过 information_schema 读取，SQLite 通过 sqlite_master 与 PRAGMA table_info 读取。
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+// ErrTableNotFound 表不存在
+var ErrTableNotFound = errors.New("table not found")
+
+// internalTables 框架自身使用的表（不含前缀），Inspect 未指定表名时跳过
+var internalTables = map[string]bool{
+	"schema_migrations":      true,
+	"schema_migrations_lock": true,
+	"seeds":                  true,
+}
+
+// Column 列结构
+type Column struct {
+	Name          string
+	Type          string // 数据库报告的类型，小写，如 varchar(255)、int unsigned、timestamp with time zone
+	Nullable      bool
+	PrimaryKey    bool
+	AutoIncrement bool
+	HasDefault    bool
+	Length        int64 // 字符类型的最大长度，未知时为 0
+	Comment       string
+}
+
+// Table 表结构
+type Table struct {
+	Name    string // 完整表名，包含前缀
+	Comment string
+	Columns []Column
+}
+
+// Inspector 表结构读取器
+type Inspector struct {
+	db      *sql.DB
+	dialect orm.Dialect
+	prefix  string
+}
+
+// NewInspector 使用 DBManager 的命名连接创建读取器，connection 为空时使用默认连接
+func NewInspector(manager *orm.DBManager, connection ...string) (*Inspector, error) {
+	conn, err := manager.Connection(connection...)
+	if err != nil {
+		return nil, err
+	}
+	return &Inspector{db: conn.Primary(), dialect: conn.Dialect(), prefix: conn.Config().TablePrefix}, nil
+}
+
+// Prefix 返回连接配置的表前缀
+func (i *Inspector) Prefix() string {
+	return i.prefix
+}
+
+// Tables 返回当前数据库中的全部表名，按名称排序
+func (i *Inspector) Tables(ctx context.Context) ([]string, error) {
+	var query string
+	switch i.dialect.Driver {
+	case orm.DriverMySQL:
+		query = "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME"
+	case orm.DriverPostgres:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	case orm.DriverSQLite:
+		query = "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	default:
+		return nil, fmt.Errorf("%w: %q", orm.ErrUnknownDriver, i.dialect.Driver)
+	}
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("读取表列表失败: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// Inspect 读取多个表的结构，names 为完整表名
+// names 为空时读取带有表前缀的全部表，并跳过迁移、种子数据等框架内部表
+func (i *Inspector) Inspect(ctx context.Context, names ...string) ([]Table, error) {
+	if len(names) == 0 {
+		all, err := i.Tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range all {
+			short, ok := strings.CutPrefix(name, i.prefix)
+			if ok && !internalTables[short] {
+				names = append(names, name)
+			}
+		}
+	}
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		table, err := i.Table(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, *table)
+	}
+	return tables, nil
+}
+
+// Table 读取单个表的结构，name 为完整表名
+func (i *Inspector) Table(ctx context.Context, name string) (*Table, error) {
+	var table *Table
+	var err error
+	switch i.dialect.Driver {
+	case orm.DriverMySQL:
+		table, err = i.mysqlTable(ctx, name)
+	case orm.DriverPostgres:
+		table, err = i.postgresTable(ctx, name)
+	case orm.DriverSQLite:
+		table, err = i.sqliteTable(ctx, name)
+	default:
+		return nil, fmt.Errorf("%w: %q", orm.ErrUnknownDriver, i.dialect.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取表 %s 结构失败: %w", name, err)
+	}
+	if len(table.Columns) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, name)
+	}
+	return table, nil
+}
+
+func (i *Inspector) mysqlTable(ctx context.Context, name string) (*Table, error) {
+	table := &Table{Name: name}
+	err := i.db.QueryRowContext(ctx,
+		"SELECT TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", name).
+		Scan(&table.Comment)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	rows, err := i.db.QueryContext(ctx, `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, EXTRA,
+	COLUMN_DEFAULT IS NOT NULL, COALESCE(CHARACTER_MAXIMUM_LENGTH, 0), COLUMN_COMMENT
+FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Column
+		var nullable, key, extra string
+		if err := rows.Scan(&c.Name, &c.Type, &nullable, &key, &extra, &c.HasDefault, &c.Length, &c.Comment); err != nil {
+			return nil, err
+		}
+		c.Type = strings.ToLower(c.Type)
+		c.Nullable = nullable == "YES"
+		c.PrimaryKey = key == "PRI"
+		c.AutoIncrement = strings.Contains(strings.ToLower(extra), "auto_increment")
+		table.Columns = append(table.Columns, c)
+	}
+	return table, rows.Err()
+}
+
+func (i *Inspector) postgresTable(ctx context.Context, name string) (*Table, error) {
+	table := &Table{Name: name}
+	err := i.db.QueryRowContext(ctx,
+		"SELECT COALESCE(obj_description(to_regclass(quote_ident(current_schema()) || '.' || quote_ident($1)), 'pg_class'), '')", name).
+		Scan(&table.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	primary := make(map[string]bool)
+	pkRows, err := i.db.QueryContext(ctx, `SELECT kcu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema AND tc.table_name = kcu.table_name
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1`, name)
+	if err != nil {
+		return nil, err
+	}
+	for pkRows.Next() {
+		var column string
+		if err := pkRows.Scan(&column); err != nil {
+			pkRows.Close()
+			return nil, err
+		}
+		primary[column] = true
+	}
+	pkRows.Close()
+
+	rows, err := i.db.QueryContext(ctx, `SELECT column_name,
+	CASE WHEN data_type IN ('USER-DEFINED', 'ARRAY') THEN udt_name ELSE data_type END,
+	is_nullable, COALESCE(column_default, ''), is_identity, COALESCE(character_maximum_length, 0),
+	COALESCE(col_description(to_regclass(quote_ident(table_schema) || '.' || quote_ident(table_name)), ordinal_position), '')
+FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c Column
+		var nullable, columnDefault, identity string
+		if err := rows.Scan(&c.Name, &c.Type, &nullable, &columnDefault, &identity, &c.Length, &c.Comment); err != nil {
+			return nil, err
+		}
+		c.Type = strings.ToLower(c.Type)
+		c.Nullable = nullable == "YES"
+		c.PrimaryKey = primary[c.Name]
+		c.AutoIncrement = identity == "YES" || strings.HasPrefix(columnDefault, "nextval(")
+		c.HasDefault = columnDefault != ""
+		table.Columns = append(table.Columns, c)
+	}
+	return table, rows.Err()
+}
+
+// typeLength 匹配类型中的长度，如 varchar(255)
+var typeLength = regexp.MustCompile(`\((\d+)\)`)
+
+func (i *Inspector) sqliteTable(ctx context.Context, name string) (*Table, error) {
+	rows, err := i.db.QueryContext(ctx, "SELECT name, type, \"notnull\", dflt_value IS NOT NULL, pk FROM pragma_table_info(?)", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	table := &Table{Name: name}
+	pkCount := 0
+	for rows.Next() {
+		var c Column
+		var notNull bool
+		var pk int
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &c.HasDefault, &pk); err != nil {
+			return nil, err
+		}
+		c.Type = strings.ToLower(c.Type)
+		c.PrimaryKey = pk > 0
+		// 主键列在 SQLite 中可以为 NULL，但 INTEGER PRIMARY KEY 是 rowid 的别名，总是有值
+		c.Nullable = !notNull && !c.PrimaryKey
+		if match := typeLength.FindStringSubmatch(c.Type); match != nil {
+			c.Length, _ = strconv.ParseInt(match[1], 10, 64)
+		}
+		if c.PrimaryKey {
+			pkCount++
+		}
+		table.Columns = append(table.Columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// 单列的 INTEGER PRIMARY KEY 由 SQLite 自动分配
+	if pkCount == 1 {
+		for n := range table.Columns {
+			if table.Columns[n].PrimaryKey && table.Columns[n].Type == "integer" {
+				table.Columns[n].AutoIncrement = true
+			}
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,143 @@
+package schema_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/orm"
+	"github.com/wuwuseo/cmf/orm/schema"
+	_ "modernc.org/sqlite"
+)
+
+func newInspector(t *testing.T) *schema.Inspector {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "schema.db"), TablePrefix: "cmf_"},
+	}
+	manager, err := orm.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	_, err = manager.GetDB().Exec(`
+CREATE TABLE cmf_user_roles (
+	id INTEGER PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	role_name VARCHAR(64) NOT NULL,
+	note TEXT,
+	avatar_url VARCHAR(255),
+	score REAL NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	deleted_at DATETIME
+);
+CREATE TABLE cmf_seeds (name TEXT PRIMARY KEY);
+CREATE TABLE other (id INTEGER PRIMARY KEY);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspector, err := schema.NewInspector(manager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inspector
+}
+
+// TestInspector_SQLite 测试读取 SQLite 表结构
+func TestInspector_SQLite(t *testing.T) {
+	ctx := context.Background()
+	inspector := newInspector(t)
+
+	tables, err := inspector.Inspect(ctx)
+	if err != nil {
+		t.Fatalf("Inspect 失败: %v", err)
+	}
+	if len(tables) != 1 || tables[0].Name != "cmf_user_roles" {
+		t.Fatalf("应只读取带前缀的业务表: %+v", tables)
+	}
+	columns := tables[0].Columns
+	if len(columns) != 8 {
+		t.Fatalf("列数量不正确: %+v", columns)
+	}
+	id, roleName, note, score := columns[0], columns[2], columns[3], columns[5]
+	if !id.PrimaryKey || !id.AutoIncrement || id.Nullable {
+		t.Errorf("主键列不正确: %+v", id)
+	}
+	if roleName.Nullable || roleName.Length != 64 || roleName.Type != "varchar(64)" {
+		t.Errorf("varchar 列不正确: %+v", roleName)
+	}
+	if !note.Nullable || !score.HasDefault {
+		t.Errorf("可空与默认值不正确: %+v %+v", note, score)
+	}
+
+	if _, err := inspector.Table(ctx, "missing"); !errors.Is(err, schema.ErrTableNotFound) {
+		t.Errorf("不存在的表应返回 ErrTableNotFound，得到 %v", err)
+	}
+}
+
+// TestGenerate 测试生成模型代码
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	inspector := newInspector(t)
+	tables, err := inspector.Inspect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := schema.Generate(tables, schema.GenerateOptions{Package: "model", TablePrefix: inspector.Prefix(), Repository: true})
+	if err != nil {
+		t.Fatalf("Generate 失败: %v", err)
+	}
+	code := string(source)
+	for _, want := range []string{
+		"package model",
+		`"database/sql"`,
+		"type UserRole struct {",
+		"ID        int64          `db:\"id\" json:\"id\"`",
+		"UserID    int64          `db:\"user_id\" json:\"user_id\" validate:\"required\"`",
+		"RoleName  string         `db:\"role_name\" json:\"role_name\" validate:\"required,max=64\"`",
+		"AvatarURL sql.NullString `db:\"avatar_url\" json:\"avatar_url\" validate:\"omitempty,max=255\"`",
+		"Score     float64        `db:\"score\" json:\"score\"`",
+		"CreatedAt time.Time      `db:\"created_at\" json:\"created_at\"`",
+		"DeletedAt sql.NullTime   `db:\"deleted_at\" json:\"deleted_at\"`",
+		`return "user_roles"`,
+		"func NewUserRoleRepository(conn *orm.Connection) (*orm.Repository[UserRole], error) {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("生成的代码缺少 %q:\n%s", want, code)
+		}
+	}
+
+	source, _ = schema.Generate(tables, schema.GenerateOptions{TablePrefix: "cmf_", NullStyle: schema.NullPointer})
+	if code := string(source); !strings.Contains(code, "*time.Time") || !strings.Contains(code, "*string") || strings.Contains(code, `"database/sql"`) {
+		t.Errorf("指针风格生成结果不正确:\n%s", code)
+	}
+}
+
+// TestGoType 测试 MySQL 与 Postgres 类型映射
+func TestGoType(t *testing.T) {
+	cases := []struct {
+		column schema.Column
+		style  schema.NullStyle
+		want   string
+	}{
+		{schema.Column{Type: "tinyint(1)"}, schema.NullSQL, "bool"},
+		{schema.Column{Type: "int(10) unsigned"}, schema.NullSQL, "uint32"},
+		{schema.Column{Type: "bigint", Nullable: true}, schema.NullSQL, "sql.NullInt64"},
+		{schema.Column{Type: "decimal(10,2)"}, schema.NullSQL, "string"},
+		{schema.Column{Type: "timestamp with time zone", Nullable: true}, schema.NullPointer, "*time.Time"},
+		{schema.Column{Type: "character varying", Nullable: true}, schema.NullPointer, "*string"},
+		{schema.Column{Type: "jsonb", Nullable: true}, schema.NullSQL, "json.RawMessage"},
+		{schema.Column{Type: "bytea", Nullable: true}, schema.NullPointer, "[]byte"},
+		{schema.Column{Type: "double precision"}, schema.NullSQL, "float64"},
+	}
+	for _, tc := range cases {
+		if got, _ := schema.GoType(tc.column, tc.style); got != tc.want {
+			t.Errorf("GoType(%q) = %s，期望 %s", tc.column.Type, got, tc.want)
+		}
+	}
+}
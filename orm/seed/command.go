@@ -0,0 +1,66 @@
+package seed
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Command 执行命令行子命令，便于接入应用自己的 CLI，例如：
+//
+//	seed run [-env development] [name ...]
+//	seed rerun [-env development] name ...
+//	seed status [-env development]
+//
+// args 不包含 "seed" 本身，输出写入 w
+func (r *Runner) Command(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("缺少子命令，可用: run、rerun、status")
+	}
+	flags := flag.NewFlagSet("seed "+args[0], flag.ContinueOnError)
+	flags.SetOutput(w)
+	env := flags.String("env", r.env, "执行环境")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	runner := *r
+	runner.env = *env
+
+	switch args[0] {
+	case "run", "rerun":
+		var done []string
+		var err error
+		if args[0] == "run" {
+			done, err = runner.Run(ctx, flags.Args()...)
+		} else {
+			done, err = runner.Rerun(ctx, flags.Args()...)
+		}
+		for _, name := range done {
+			fmt.Fprintf(w, "seeded %s\n", name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(w, "nothing to seed")
+		}
+		return err
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tENABLED\tRAN AT")
+		for _, status := range statuses {
+			ranAt := "-"
+			if status.Ran {
+				ranAt = status.RanAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%s\t%t\t%s\n", status.Name, status.Enabled, ranAt)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("未知的子命令 %q，可用: run、rerun、status", args[0])
+	}
+}
@@ -0,0 +1,332 @@
+// Package seed 提供可重复执行的种子数据
+//
+// 各模块通过 Register 注册具名的 Seeder（如默认管理员、基础角色、演示内容），
+// Seeder 可以声明依赖，执行时按依赖顺序排列。每个 Seeder 在独立事务中执行，
+// 执行记录与数据在同一事务内写入 {prefix}seeds 表，已执行的 Seeder 不会重复执行。
+// Environments 限定 Seeder 只在指定环境执行，如演示数据只在 development 环境写入。
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wuwuseo/cmf/orm"
+)
+
+const defaultTableName = "seeds"
+
+var (
+	// ErrDuplicateSeeder 同名 Seeder 重复注册
+	ErrDuplicateSeeder = errors.New("seeder already registered")
+	// ErrUnknownSeeder Seeder 或其依赖未注册
+	ErrUnknownSeeder = errors.New("seeder not registered")
+	// ErrDependencyCycle Seeder 之间存在循环依赖
+	ErrDependencyCycle = errors.New("seeder dependency cycle")
+	// ErrEnvironment Seeder 依赖了在当前环境不执行的 Seeder
+	ErrEnvironment = errors.New("seeder dependency not enabled in environment")
+)
+
+// Seeder 具名的种子数据
+type Seeder struct {
+	Name      string
+	DependsOn []string // 依赖的 Seeder 名称，先于当前 Seeder 执行
+	// Environments 执行的环境，为空时在所有环境执行
+	Environments []string
+	// Run 写入数据，ctx 中带有事务，通过 conn.Querier(ctx)、conn.Builder() 或 orm.Repository 访问数据库
+	Run func(ctx context.Context, conn *orm.Connection) error
+}
+
+// enabledIn 是否在环境 env 中执行
+func (s Seeder) enabledIn(env string) bool {
+	return len(s.Environments) == 0 || slices.Contains(s.Environments, env)
+}
+
+// Registry Seeder 注册表
+type Registry struct {
+	mu      sync.RWMutex
+	seeders map[string]Seeder
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{seeders: make(map[string]Seeder)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry 返回包级别的默认注册表
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register 向默认注册表注册 Seeder，通常在模块的 init 中调用，名称重复或无效时 panic
+func Register(seeder Seeder) {
+	if err := defaultRegistry.Register(seeder); err != nil {
+		panic(err)
+	}
+}
+
+// Register 注册 Seeder
+func (r *Registry) Register(seeder Seeder) error {
+	if seeder.Name == "" || seeder.Run == nil {
+		return errors.New("seeder 的 Name 与 Run 不能为空")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seeders[seeder.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateSeeder, seeder.Name)
+	}
+	r.seeders[seeder.Name] = seeder
+	return nil
+}
+
+// Names 返回已注册的 Seeder 名称，按名称排序
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.seeders))
+	for name := range r.seeders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve 返回 names 及其全部依赖的执行顺序，names 为空时包含全部 Seeder
+// 不在环境 env 中执行的 Seeder 被跳过，被跳过的 Seeder 不能作为其他 Seeder 的依赖
+func (r *Registry) resolve(names []string, env string) ([]Seeder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(names) == 0 {
+		for name := range r.seeders {
+			names = append(names, name)
+		}
+	}
+	names = slices.Clone(names)
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var ordered []Seeder
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		seeder, ok := r.seeders[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSeeder, name)
+		}
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, append(path, name))
+		}
+		state[name] = visiting
+		deps := slices.Clone(seeder.DependsOn)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+			if seeder.enabledIn(env) && !r.seeders[dep].enabledIn(env) {
+				return fmt.Errorf("%w: %s 依赖的 %s 不在环境 %q 中执行", ErrEnvironment, name, dep, env)
+			}
+		}
+		state[name] = visited
+		if seeder.enabledIn(env) {
+			ordered = append(ordered, seeder)
+		}
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Option 函数式可选参数
+type Option func(*Runner)
+
+// WithRegistry 使用指定的注册表，默认使用 DefaultRegistry
+func WithRegistry(registry *Registry) Option {
+	return func(r *Runner) { r.registry = registry }
+}
+
+// WithEnvironment 设置当前环境，默认读取 CMF_APP_ENV 环境变量
+func WithEnvironment(env string) Option {
+	return func(r *Runner) { r.env = env }
+}
+
+// WithTableName 设置执行记录表名（不含前缀），默认 seeds
+func WithTableName(name string) Option {
+	return func(r *Runner) {
+		if name != "" {
+			r.tableName = name
+		}
+	}
+}
+
+// Status Seeder 的执行状态
+type Status struct {
+	Name    string
+	Enabled bool // 是否在当前环境执行
+	Ran     bool
+	RanAt   time.Time
+}
+
+// Runner Seeder 执行器
+type Runner struct {
+	conn      *orm.Connection
+	registry  *Registry
+	env       string
+	tableName string
+}
+
+// New 创建执行器
+func New(conn *orm.Connection, opts ...Option) *Runner {
+	r := &Runner{
+		conn:      conn,
+		registry:  defaultRegistry,
+		env:       os.Getenv("CMF_APP_ENV"),
+		tableName: defaultTableName,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Environment 返回当前环境
+func (r *Runner) Environment() string {
+	return r.env
+}
+
+// Run 按依赖顺序执行 names 及其依赖中尚未执行的 Seeder，names 为空时执行全部，返回本次执行的名称
+func (r *Runner) Run(ctx context.Context, names ...string) ([]string, error) {
+	return r.run(ctx, names, false)
+}
+
+// Rerun 重新执行 names 指定的 Seeder（即使已执行过），其依赖仍只执行一次
+// Seeder 需要自行保证重复执行的幂等性，如先查询后写入或使用 upsert
+func (r *Runner) Rerun(ctx context.Context, names ...string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, errors.New("Rerun 需要指定 Seeder 名称")
+	}
+	return r.run(ctx, names, true)
+}
+
+func (r *Runner) run(ctx context.Context, names []string, force bool) ([]string, error) {
+	seeders, err := r.registry.resolve(names, r.env)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	ran, err := r.ran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, seeder := range seeders {
+		_, already := ran[seeder.Name]
+		if already && !(force && slices.Contains(names, seeder.Name)) {
+			continue
+		}
+		if err := r.apply(ctx, seeder); err != nil {
+			return done, err
+		}
+		done = append(done, seeder.Name)
+	}
+	return done, nil
+}
+
+// apply 在事务中执行 Seeder 并写入执行记录
+// 多个实例同时执行时，记录表的主键冲突会使后提交的事务连同数据一起回滚
+func (r *Runner) apply(ctx context.Context, seeder Seeder) error {
+	b := r.conn.Builder()
+	err := r.conn.WithTx(ctx, nil, func(ctx context.Context) error {
+		if err := seeder.Run(ctx, r.conn); err != nil {
+			return err
+		}
+		if _, err := b.Delete(r.tableName).Where("name = ?", seeder.Name).Exec(ctx); err != nil {
+			return err
+		}
+		_, err := b.Insert(r.tableName).Columns("name", "ran_at").Values(seeder.Name, time.Now().Unix()).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("执行 seeder %s 失败: %w", seeder.Name, err)
+	}
+	return nil
+}
+
+// Status 返回全部已注册 Seeder 的执行状态，按名称排序
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	if err := r.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	ran, err := r.ran(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.registry.mu.RLock()
+	defer r.registry.mu.RUnlock()
+	statuses := make([]Status, 0, len(r.registry.seeders))
+	for name, seeder := range r.registry.seeders {
+		status := Status{Name: name, Enabled: seeder.enabledIn(r.env)}
+		if ranAt, ok := ran[name]; ok {
+			status.Ran = true
+			status.RanAt = ranAt
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// ensureTable 创建执行记录表
+func (r *Runner) ensureTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	ran_at BIGINT NOT NULL
+)`, r.conn.Builder().Table(r.tableName))
+	if _, err := r.conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("创建 seed 记录表失败: %w", err)
+	}
+	return nil
+}
+
+// ran 读取已执行的 Seeder，从主库读取以免副本延迟导致重复执行
+func (r *Runner) ran(ctx context.Context) (map[string]time.Time, error) {
+	query, args, err := r.conn.Builder().Select("name", "ran_at").From(r.tableName).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.conn.Primary().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("读取 seed 记录失败: %w", err)
+	}
+	defer rows.Close()
+	ran := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var ranAt int64
+		if err := rows.Scan(&name, &ranAt); err != nil {
+			return nil, err
+		}
+		ran[name] = time.Unix(ranAt, 0)
+	}
+	return ran, rows.Err()
+}
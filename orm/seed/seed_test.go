@@ -0,0 +1,175 @@
+package seed_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	"github.com/wuwuseo/cmf/orm"
+	"github.com/wuwuseo/cmf/orm/seed"
+	_ "modernc.org/sqlite"
+)
+
+func newTestConn(t *testing.T) *orm.Connection {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "seed.db"), TablePrefix: "cmf_"},
+	}
+	manager, err := orm.NewDBManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	if _, err := manager.GetDB().Exec("CREATE TABLE cmf_roles (name TEXT PRIMARY KEY); CREATE TABLE cmf_users (name TEXT, role TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	conn, _ := manager.Connection()
+	return conn
+}
+
+func insert(table string, columns []string, values ...any) func(context.Context, *orm.Connection) error {
+	return func(ctx context.Context, conn *orm.Connection) error {
+		_, err := conn.Builder().Insert(table).Columns(columns...).Values(values...).Exec(ctx)
+		return err
+	}
+}
+
+func countRows(t *testing.T, conn *orm.Connection, table string) int {
+	t.Helper()
+	var n int
+	if err := conn.Builder().Select("COUNT(*)").From(table).QueryRow(context.Background(), &n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testRegistry(t *testing.T) *seed.Registry {
+	t.Helper()
+	registry := seed.NewRegistry()
+	for _, s := range []seed.Seeder{
+		{Name: "demo_users", DependsOn: []string{"admin"}, Environments: []string{"development"},
+			Run: insert("users", []string{"name", "role"}, "demo", "editor")},
+		{Name: "admin", DependsOn: []string{"roles"}, Run: insert("users", []string{"name", "role"}, "admin", "admin")},
+		{Name: "roles", Run: insert("roles", []string{"name"}, "admin")},
+	} {
+		if err := registry.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+// TestRunner_RunInDependencyOrder 测试按依赖顺序执行且不重复执行
+func TestRunner_RunInDependencyOrder(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConn(t)
+	registry := testRegistry(t)
+
+	prod := seed.New(conn, seed.WithRegistry(registry), seed.WithEnvironment("production"))
+	done, err := prod.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run 失败: %v", err)
+	}
+	if strings.Join(done, ",") != "roles,admin" {
+		t.Errorf("执行顺序不正确: %v", done)
+	}
+
+	dev := seed.New(conn, seed.WithRegistry(registry), seed.WithEnvironment("development"))
+	done, err = dev.Run(ctx)
+	if err != nil || strings.Join(done, ",") != "demo_users" {
+		t.Errorf("开发环境应只补充演示数据: %v %v", done, err)
+	}
+	if done, _ := dev.Run(ctx); len(done) != 0 {
+		t.Errorf("已执行的 Seeder 不应重复执行: %v", done)
+	}
+	if countRows(t, conn, "users") != 2 || countRows(t, conn, "seeds") != 3 {
+		t.Error("数据或执行记录数量不正确")
+	}
+
+	statuses, err := prod.Status(ctx)
+	if err != nil || len(statuses) != 3 || statuses[1].Name != "demo_users" || statuses[1].Enabled || !statuses[1].Ran {
+		t.Errorf("状态不正确: %+v %v", statuses, err)
+	}
+}
+
+// TestRunner_FailureRollsBack 测试失败的 Seeder 回滚数据且不写入记录
+func TestRunner_FailureRollsBack(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConn(t)
+	registry := seed.NewRegistry()
+	registry.Register(seed.Seeder{Name: "broken", Run: func(ctx context.Context, conn *orm.Connection) error {
+		if err := insert("roles", []string{"name"}, "x")(ctx, conn); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+
+	runner := seed.New(conn, seed.WithRegistry(registry))
+	if _, err := runner.Run(ctx); err == nil {
+		t.Fatal("Seeder 失败时应返回错误")
+	}
+	if countRows(t, conn, "roles") != 0 || countRows(t, conn, "seeds") != 0 {
+		t.Error("失败的 Seeder 应回滚数据与记录")
+	}
+}
+
+// TestRegistry_Errors 测试重复注册、未知依赖、循环依赖与环境不匹配
+func TestRegistry_Errors(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConn(t)
+	noop := func(context.Context, *orm.Connection) error { return nil }
+
+	registry := seed.NewRegistry()
+	registry.Register(seed.Seeder{Name: "a", DependsOn: []string{"b"}, Run: noop})
+	registry.Register(seed.Seeder{Name: "b", DependsOn: []string{"a"}, Run: noop})
+	registry.Register(seed.Seeder{Name: "c", DependsOn: []string{"missing"}, Run: noop})
+	registry.Register(seed.Seeder{Name: "dev", Environments: []string{"development"}, Run: noop})
+	registry.Register(seed.Seeder{Name: "base", DependsOn: []string{"dev"}, Run: noop})
+
+	if err := registry.Register(seed.Seeder{Name: "a", Run: noop}); !errors.Is(err, seed.ErrDuplicateSeeder) {
+		t.Errorf("重复注册应返回 ErrDuplicateSeeder，得到 %v", err)
+	}
+	runner := seed.New(conn, seed.WithRegistry(registry), seed.WithEnvironment("production"))
+	if _, err := runner.Run(ctx, "a"); !errors.Is(err, seed.ErrDependencyCycle) {
+		t.Errorf("循环依赖应返回 ErrDependencyCycle，得到 %v", err)
+	}
+	if _, err := runner.Run(ctx, "c"); !errors.Is(err, seed.ErrUnknownSeeder) {
+		t.Errorf("未知依赖应返回 ErrUnknownSeeder，得到 %v", err)
+	}
+	if _, err := runner.Run(ctx, "base"); !errors.Is(err, seed.ErrEnvironment) {
+		t.Errorf("依赖当前环境不执行的 Seeder 应返回 ErrEnvironment，得到 %v", err)
+	}
+}
+
+// TestRunner_Command 测试命令行子命令
+func TestRunner_Command(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConn(t)
+	runner := seed.New(conn, seed.WithRegistry(testRegistry(t)), seed.WithEnvironment("production"))
+
+	var out bytes.Buffer
+	if err := runner.Command(ctx, []string{"run", "-env", "development", "demo_users"}, &out); err != nil {
+		t.Fatalf("run 失败: %v", err)
+	}
+	if !strings.Contains(out.String(), "seeded demo_users") {
+		t.Errorf("输出不正确: %s", out.String())
+	}
+
+	out.Reset()
+	if err := runner.Command(ctx, []string{"rerun", "roles"}, &out); err == nil {
+		t.Error("重复插入主键应使 rerun 失败")
+	}
+
+	out.Reset()
+	if err := runner.Command(ctx, []string{"status"}, &out); err != nil || !strings.Contains(out.String(), "demo_users") {
+		t.Errorf("status 输出不正确: %s %v", out.String(), err)
+	}
+	if err := runner.Command(ctx, []string{"unknown"}, &out); err == nil {
+		t.Error("未知子命令应返回错误")
+	}
+}
@@ -0,0 +1,217 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	txRetryBaseDelay = 10 * time.Millisecond
+	txRetryMaxDelay  = time.Second
+)
+
+// Querier *sql.DB、*sql.Tx 与 *Connection 共有的查询方法
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// TxOptions 事务选项
+type TxOptions struct {
+	Isolation sql.IsolationLevel // 隔离级别，默认使用数据库默认值
+	ReadOnly  bool               // 只读事务
+	// MaxRetries 死锁或序列化失败时的最大重试次数
+	// 为 0 时使用连接配置的 tx_retries，小于 0 时不重试
+	MaxRetries int
+}
+
+// txKey 上下文中事务的键，按连接区分，不同连接的事务互不影响
+type txKey struct {
+	conn *Connection
+}
+
+// txState 上下文中的事务状态
+type txState struct {
+	tx *sql.Tx
+
+	mu        sync.Mutex
+	savepoint int
+}
+
+// WithTx 在默认连接上执行事务，详见 Connection.WithTx
+func (m *DBManager) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	conn, err := m.Connection()
+	if err != nil {
+		return err
+	}
+	return conn.WithTx(ctx, opts, fn)
+}
+
+// Querier 返回默认连接上下文中的事务，不在事务中时返回连接本身
+func (m *DBManager) Querier(ctx context.Context) Querier {
+	conn, err := m.Connection()
+	if err != nil {
+		return m.db
+	}
+	return conn.Querier(ctx)
+}
+
+// Querier 返回上下文中属于该连接的事务，不在事务中时返回连接本身（读写分离）
+func (c *Connection) Querier(ctx context.Context) Querier {
+	if state, ok := ctx.Value(txKey{c}).(*txState); ok {
+		return state.tx
+	}
+	return c
+}
+
+// WithTx 在主库上开启事务并将其放入 fn 的上下文，fn 内通过 Querier(ctx) 获取事务
+// fn 返回错误或 panic 时回滚，否则提交；嵌套调用使用 SAVEPOINT，只回滚嵌套部分。
+// 最外层事务遇到死锁或序列化失败时按 MaxRetries 重新执行 fn，fn 应当可以安全重试
+func (c *Connection) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{c}).(*txState); ok {
+		return c.withSavepoint(ctx, state, fn)
+	}
+
+	var txOpts *sql.TxOptions
+	retries := c.config.TxRetries
+	if opts != nil {
+		txOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+		if opts.MaxRetries != 0 {
+			retries = opts.MaxRetries
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.runTx(ctx, txOpts, fn)
+		if err == nil || attempt >= retries || !IsRetryableTxError(c.config.Driver, err) {
+			return err
+		}
+		delay := min(txRetryBaseDelay<<attempt, txRetryMaxDelay)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx 执行一次事务
+func (c *Connection) runTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) (err error) {
+	tx, err := c.primary.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{c}, &txState{tx: tx})); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// withSavepoint 在已有事务中通过 SAVEPOINT 执行嵌套事务
+func (c *Connection) withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	state.mu.Lock()
+	state.savepoint++
+	name := fmt.Sprintf("cmf_sp_%d", state.savepoint)
+	state.mu.Unlock()
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			state.tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+name)
+			panic(r)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		if _, rbErr := state.tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// RetryClassifier 判断事务错误是否可以重试
+type RetryClassifier func(err error) bool
+
+var retryClassifiers sync.Map
+
+func init() {
+	RegisterRetryClassifier(DriverMySQL, isMySQLRetryable)
+	RegisterRetryClassifier(DriverPostgres, isPostgresRetryable)
+	RegisterRetryClassifier(DriverSQLite, isSQLiteRetryable)
+}
+
+// RegisterRetryClassifier 注册驱动的事务重试判断函数，覆盖内置实现
+func RegisterRetryClassifier(driver string, classifier RetryClassifier) {
+	retryClassifiers.Store(NormalizeDriver(driver), classifier)
+}
+
+// IsRetryableTxError 判断错误是否为该驱动下可重试的死锁或序列化失败
+func IsRetryableTxError(driver string, err error) bool {
+	if err == nil {
+		return false
+	}
+	classifier, ok := retryClassifiers.Load(NormalizeDriver(driver))
+	if !ok {
+		return false
+	}
+	return classifier.(RetryClassifier)(err)
+}
+
+// mysqlErrorNumber 匹配 go-sql-driver/mysql 的错误格式，如 "Error 1213 (40001): Deadlock found"
+var mysqlErrorNumber = regexp.MustCompile(`Error (\d+)`)
+
+// isMySQLRetryable 1213 死锁、1205 锁等待超时
+func isMySQLRetryable(err error) bool {
+	for _, match := range mysqlErrorNumber.FindAllStringSubmatch(err.Error(), -1) {
+		if match[1] == "1213" || match[1] == "1205" {
+			return true
+		}
+	}
+	return false
+}
+
+// isPostgresRetryable 40001 序列化失败、40P01 死锁
+// pgx 与 lib/pq 的错误类型都实现了 SQLState 方法
+func isPostgresRetryable(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		code := state.SQLState()
+		return code == "40001" || code == "40P01"
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "SQLSTATE 40001") || strings.Contains(msg, "SQLSTATE 40P01")
+}
+
+// isSQLiteRetryable SQLITE_BUSY、SQLITE_LOCKED
+func isSQLiteRetryable(err error) bool {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		// 扩展错误码的低 8 位为主错误码
+		code := coded.Code() & 0xff
+		return code == 5 || code == 6
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
@@ -0,0 +1,191 @@
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/wuwuseo/cmf/config"
+	cmform "github.com/wuwuseo/cmf/orm"
+)
+
+// pgError 模拟实现 SQLState 的 Postgres 驱动错误
+type pgError struct{ code string }
+
+func (e *pgError) Error() string    { return "pg error " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+func newTxManager(t *testing.T) *cmform.DBManager {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Default = "default"
+	cfg.Database.Connections = map[string]config.Database{
+		"default": {Driver: "sqlite", Host: filepath.Join(t.TempDir(), "tx.db")},
+	}
+	manager, err := cmform.NewDBManager(cfg)
+	if err != nil {
+		t.Fatalf("NewDBManager 失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	if _, err := manager.GetDB().Exec("CREATE TABLE items (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func countItems(t *testing.T, manager *cmform.DBManager) int {
+	t.Helper()
+	var n int
+	if err := manager.GetDB().QueryRow("SELECT COUNT(*) FROM items").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func insertItem(ctx context.Context, manager *cmform.DBManager, name string) error {
+	_, err := manager.Querier(ctx).ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", name)
+	return err
+}
+
+// TestWithTx_CommitAndRollback 测试成功提交与错误回滚
+func TestWithTx_CommitAndRollback(t *testing.T) {
+	ctx := context.Background()
+	manager := newTxManager(t)
+
+	if _, ok := manager.Querier(ctx).(*sql.Tx); ok {
+		t.Error("事务外 Querier 不应返回事务")
+	}
+	err := manager.WithTx(ctx, nil, func(ctx context.Context) error {
+		if _, ok := manager.Querier(ctx).(*sql.Tx); !ok {
+			t.Error("事务内 Querier 应返回事务")
+		}
+		return insertItem(ctx, manager, "a")
+	})
+	if err != nil || countItems(t, manager) != 1 {
+		t.Fatalf("事务应提交: %v", err)
+	}
+
+	boom := errors.New("boom")
+	err = manager.WithTx(ctx, nil, func(ctx context.Context) error {
+		insertItem(ctx, manager, "b")
+		return boom
+	})
+	if !errors.Is(err, boom) || countItems(t, manager) != 1 {
+		t.Errorf("返回错误时应回滚: %v", err)
+	}
+}
+
+// TestWithTx_PanicRollsBack 测试 panic 时回滚并继续抛出
+func TestWithTx_PanicRollsBack(t *testing.T) {
+	ctx := context.Background()
+	manager := newTxManager(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("panic 应继续抛出，得到 %v", r)
+			}
+		}()
+		manager.WithTx(ctx, nil, func(ctx context.Context) error {
+			insertItem(ctx, manager, "a")
+			panic("boom")
+		})
+	}()
+	if countItems(t, manager) != 0 {
+		t.Error("panic 时应回滚")
+	}
+}
+
+// TestWithTx_NestedSavepoint 测试嵌套事务只回滚嵌套部分
+func TestWithTx_NestedSavepoint(t *testing.T) {
+	ctx := context.Background()
+	manager := newTxManager(t)
+
+	err := manager.WithTx(ctx, nil, func(ctx context.Context) error {
+		if err := insertItem(ctx, manager, "outer"); err != nil {
+			return err
+		}
+		nestedErr := manager.WithTx(ctx, nil, func(ctx context.Context) error {
+			insertItem(ctx, manager, "inner")
+			return errors.New("inner failed")
+		})
+		if nestedErr == nil {
+			t.Error("嵌套事务应返回错误")
+		}
+		return manager.WithTx(ctx, nil, func(ctx context.Context) error {
+			return insertItem(ctx, manager, "inner-ok")
+		})
+	})
+	if err != nil {
+		t.Fatalf("外层事务失败: %v", err)
+	}
+
+	rows, _ := manager.GetDB().Query("SELECT name FROM items ORDER BY name")
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if len(names) != 2 || names[0] != "inner-ok" || names[1] != "outer" {
+		t.Errorf("嵌套回滚结果不正确: %v", names)
+	}
+}
+
+// TestWithTx_RetryOnBusy 测试可重试错误按次数重新执行
+func TestWithTx_RetryOnBusy(t *testing.T) {
+	ctx := context.Background()
+	manager := newTxManager(t)
+
+	calls := 0
+	err := manager.WithTx(ctx, &cmform.TxOptions{MaxRetries: 2}, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("database is locked (5) (SQLITE_BUSY)")
+		}
+		return insertItem(ctx, manager, "a")
+	})
+	if err != nil || calls != 3 || countItems(t, manager) != 1 {
+		t.Errorf("应重试直到成功: calls=%d err=%v", calls, err)
+	}
+
+	calls = 0
+	manager.WithTx(ctx, nil, func(ctx context.Context) error {
+		calls++
+		return errors.New("database is locked")
+	})
+	if calls != 1 {
+		t.Errorf("未配置重试时只应执行一次，实际 %d 次", calls)
+	}
+}
+
+// TestIsRetryableTxError 测试各驱动的可重试错误识别
+func TestIsRetryableTxError(t *testing.T) {
+	cases := []struct {
+		driver string
+		err    error
+		want   bool
+	}{
+		{"mysql", errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), true},
+		{"mysql", errors.New("Error 1205 (HY000): Lock wait timeout exceeded"), true},
+		{"mysql", errors.New("Error 1062 (23000): Duplicate entry"), false},
+		{"postgres", &pgError{"40001"}, true},
+		{"pgx", &pgError{"40P01"}, true},
+		{"postgres", &pgError{"23505"}, false},
+		{"sqlite", errors.New("database is locked"), true},
+		{"unknown", errors.New("database is locked"), false},
+	}
+	for _, tc := range cases {
+		if got := cmform.IsRetryableTxError(tc.driver, tc.err); got != tc.want {
+			t.Errorf("IsRetryableTxError(%s, %v) = %v, 期望 %v", tc.driver, tc.err, got, tc.want)
+		}
+	}
+
+	cmform.RegisterRetryClassifier("unknown", func(err error) bool { return true })
+	if !cmform.IsRetryableTxError("unknown", errors.New("any")) {
+		t.Error("应使用注册的重试判断函数")
+	}
+}
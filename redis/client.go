@@ -4,13 +4,20 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"sync"
+	"net"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/wuwuseo/cmf/config"
 )
 
+// 连接模式
+const (
+	ModeStandalone = "standalone" // 单节点模式
+	ModeSentinel   = "sentinel"   // Sentinel 高可用模式
+	ModeCluster    = "cluster"    // Cluster 集群模式
+)
+
 // Options 定义Redis客户端的配置选项
 // 这些选项将用于创建Redis连接
 type Options struct {
@@ -27,55 +34,110 @@ type Options struct {
 	ConnMaxIdleTime time.Duration // 连接最大空闲时间
 	ConnMaxLifetime time.Duration // 连接最大生命周期
 	TLSConfig       *tls.Config   // TLS配置，用于加密连接
-}
 
-// 使用sync.Map来存储单例Redis客户端实例
-var clientMap sync.Map
+	Mode             string   // 连接模式，为空时按单节点处理
+	MasterName       string   // Sentinel 模式下的主节点名称
+	SentinelAddrs    []string // Sentinel 节点地址列表
+	SentinelUsername string   // Sentinel 用户名
+	SentinelPassword string   // Sentinel 密码
+	ClusterNodes     []string // Cluster 模式下的节点地址列表，为空时使用 Addr
+}
 
 // NewClient 创建一个新的Redis客户端实例
-// 该函数封装了go-redis的NewClient函数，提供了更便捷的使用方式
-func NewClient(options *Options) *redis.Client {
-	redisOptions := &redis.Options{
-		Addr:            options.Addr,
-		Username:        options.Username,
-		Password:        options.Password,
-		DB:              options.DB,
-		DialTimeout:     options.DialTimeout,
-		ReadTimeout:     options.ReadTimeout,
-		WriteTimeout:    options.WriteTimeout,
-		PoolSize:        options.PoolSize,
-		MinIdleConns:    options.MinIdleConns,
-		MaxIdleConns:    options.MaxIdleConns,
-		ConnMaxIdleTime: options.ConnMaxIdleTime,
-		ConnMaxLifetime: options.ConnMaxLifetime,
-		TLSConfig:       options.TLSConfig,
+// 该函数封装了go-redis的客户端构造函数，根据 Mode 返回单节点、Sentinel 或 Cluster 客户端
+// 配置 TLSConfig 时使用 dialTLS 拨号，按每个节点的地址校验服务端证书
+func NewClient(options *Options) redis.UniversalClient {
+	var dialer func(context.Context, string, string) (net.Conn, error)
+	if options.TLSConfig != nil {
+		dialer = dialTLS(options.TLSConfig, options.DialTimeout)
 	}
-	return redis.NewClient(redisOptions)
-}
-
-// NewClientFromConfig 从配置对象创建Redis客户端实例
-// 该函数使用应用的全局配置来初始化Redis客户端，并使用sync.Map保持单例模式
-func NewClientFromConfig(ctx context.Context, config *config.Config, storeName ...string) (*redis.Client, error) {
-	// 从配置中获取Redis相关配置
-	// 处理存储名称参数
-	redisDefault := config.Redis.Default
-	var storeKey string
-	if len(storeName) > 0 {
-		storeKey = storeName[0]
-	} else {
-		storeKey = redisDefault
+	switch options.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       options.MasterName,
+			SentinelAddrs:    options.SentinelAddrs,
+			SentinelUsername: options.SentinelUsername,
+			SentinelPassword: options.SentinelPassword,
+			Username:         options.Username,
+			Password:         options.Password,
+			DB:               options.DB,
+			DialTimeout:      options.DialTimeout,
+			ReadTimeout:      options.ReadTimeout,
+			WriteTimeout:     options.WriteTimeout,
+			PoolSize:         options.PoolSize,
+			MinIdleConns:     options.MinIdleConns,
+			MaxIdleConns:     options.MaxIdleConns,
+			ConnMaxIdleTime:  options.ConnMaxIdleTime,
+			ConnMaxLifetime:  options.ConnMaxLifetime,
+			TLSConfig:        options.TLSConfig,
+			Dialer:           dialer,
+		})
+	case ModeCluster:
+		addrs := options.ClusterNodes
+		if len(addrs) == 0 && options.Addr != "" {
+			addrs = []string{options.Addr}
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           addrs,
+			Username:        options.Username,
+			Password:        options.Password,
+			DialTimeout:     options.DialTimeout,
+			ReadTimeout:     options.ReadTimeout,
+			WriteTimeout:    options.WriteTimeout,
+			PoolSize:        options.PoolSize,
+			MinIdleConns:    options.MinIdleConns,
+			MaxIdleConns:    options.MaxIdleConns,
+			ConnMaxIdleTime: options.ConnMaxIdleTime,
+			ConnMaxLifetime: options.ConnMaxLifetime,
+			TLSConfig:       options.TLSConfig,
+			Dialer:          dialer,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:            options.Addr,
+			Username:        options.Username,
+			Password:        options.Password,
+			DB:              options.DB,
+			DialTimeout:     options.DialTimeout,
+			ReadTimeout:     options.ReadTimeout,
+			WriteTimeout:    options.WriteTimeout,
+			PoolSize:        options.PoolSize,
+			MinIdleConns:    options.MinIdleConns,
+			MaxIdleConns:    options.MaxIdleConns,
+			ConnMaxIdleTime: options.ConnMaxIdleTime,
+			ConnMaxLifetime: options.ConnMaxLifetime,
+			TLSConfig:       options.TLSConfig,
+			Dialer:          dialer,
+		})
 	}
+}
 
-	// 检查是否已经存在该storeName的客户端实例
-	if client, ok := clientMap.Load(storeKey); ok {
-		return client.(*redis.Client), nil
+// validateOptions 校验连接模式及其必填项
+func validateOptions(options *Options) error {
+	switch options.Mode {
+	case "", ModeStandalone:
+	case ModeSentinel:
+		if options.MasterName == "" || len(options.SentinelAddrs) == 0 {
+			return fmt.Errorf("Redis Sentinel模式需要配置master_name和sentinel_addrs")
+		}
+	case ModeCluster:
+		if len(options.ClusterNodes) == 0 && options.Addr == "" {
+			return fmt.Errorf("Redis Cluster模式需要配置cluster_nodes")
+		}
+	default:
+		return fmt.Errorf("不支持的Redis连接模式: %s", options.Mode)
 	}
+	return nil
+}
 
-	redisConfig, ok := config.Redis.Connections[storeKey]
-	if !ok {
-		return nil, fmt.Errorf("未找到Redis配置: %s", storeKey)
-	}
+// NewClientFromConfig 从配置对象创建Redis客户端实例
+// 该函数通过默认管理器获取客户端，相同名称返回同一实例，管理器关闭时统一释放
+func NewClientFromConfig(ctx context.Context, config *config.Config, storeName ...string) (redis.UniversalClient, error) {
+	return DefaultManager().client(ctx, config, storeName...)
+}
 
+// optionsFromConfig 将连接配置转换为客户端选项，并校验连接模式与 TLS 证书
+func optionsFromConfig(redisConfig config.Redis) (*Options, error) {
 	// 创建选项对象，使用配置中的值
 	options := &Options{
 		Addr:            redisConfig.Addr,
@@ -90,29 +152,31 @@ func NewClientFromConfig(ctx context.Context, config *config.Config, storeName .
 		MaxIdleConns:    redisConfig.MaxIdleConns,
 		ConnMaxIdleTime: time.Duration(redisConfig.ConnMaxIdleTime) * time.Minute,
 		ConnMaxLifetime: time.Duration(redisConfig.ConnMaxLifetime) * time.Hour,
-	}
 
-	// 如果需要TLS连接，配置TLS
-	if redisConfig.UseTLS {
-		options.TLSConfig = &tls.Config{}
-		// 可以在这里添加更多TLS配置
+		Mode:             redisConfig.Mode,
+		MasterName:       redisConfig.MasterName,
+		SentinelAddrs:    redisConfig.SentinelAddrs,
+		SentinelUsername: redisConfig.SentinelUsername,
+		SentinelPassword: redisConfig.SentinelPassword,
+		ClusterNodes:     redisConfig.ClusterNodes,
 	}
-
-	// 创建客户端
-	client := NewClient(options)
-
-	// 测试连接
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("连接Redis失败: %w", err)
+	if err := validateOptions(options); err != nil {
+		return nil, err
 	}
 
-	// 将客户端存储到sync.Map中，确保单例
-	actual, loaded := clientMap.LoadOrStore(storeKey, client)
-	if loaded {
-		// 如果已经存在，则关闭新创建的客户端，返回已存在的客户端
-		client.Close()
-		return actual.(*redis.Client), nil
+	// 如果需要TLS连接，配置TLS
+	if redisConfig.UseTLS {
+		tlsConfig, err := NewTLSConfig(TLSOptions{
+			CAFile:             redisConfig.TLSCAFile,
+			CertFile:           redisConfig.TLSCertFile,
+			KeyFile:            redisConfig.TLSKeyFile,
+			ServerName:         redisConfig.TLSServerName,
+			InsecureSkipVerify: redisConfig.TLSInsecureSkipVerify,
+		})
+		if err != nil {
+			return nil, err
+		}
+		options.TLSConfig = tlsConfig
 	}
-
-	return client, nil
+	return options, nil
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/testcontainers/testcontainers-go/modules/redis"
 
 	"github.com/wuwuseo/cmf/config"
@@ -15,24 +16,25 @@ import (
 var (
 	testRedisContainer *redis.RedisContainer
 	testRedisAddr      string
+	testRedisSkip      string // 容器不可用的原因，非空时需要 Redis 的测试被跳过
 )
 
 // TestMain 在所有测试运行前启动 Redis 容器，测试结束后终止容器
-// 如果 Docker 环境不可用，则跳过所有测试
+// 如果 Docker 环境不可用，只跳过需要 Redis 服务的测试，其余测试照常运行
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 
 	redisContainer, err := redis.Run(ctx, "redis:7-alpine")
 	if err != nil {
-		println("跳过 Redis 测试：无法启动 testcontainers Redis 容器，", err.Error())
-		os.Exit(0)
+		testRedisSkip = "无法启动 testcontainers Redis 容器，" + err.Error()
+		os.Exit(m.Run())
 	}
 
 	connStr, err := redisContainer.ConnectionString(ctx)
 	if err != nil {
 		redisContainer.Terminate(ctx)
-		println("跳过 Redis 测试：无法获取 Redis 连接地址，", err.Error())
-		os.Exit(0)
+		testRedisSkip = "无法获取 Redis 连接地址，" + err.Error()
+		os.Exit(m.Run())
 	}
 
 	testRedisContainer = redisContainer
@@ -51,6 +53,14 @@ func TestMain(m *testing.M) {
 // 测试辅助函数
 // =============================================================================
 
+// requireRedis Redis 容器不可用时跳过当前测试
+func requireRedis(t *testing.T) {
+	t.Helper()
+	if testRedisSkip != "" {
+		t.Skip("跳过 Redis 测试：" + testRedisSkip)
+	}
+}
+
 // newTestConfig 创建一个用于测试的 Config 对象，包含测试 Redis 容器的连接信息
 func newTestConfig(storeName string) *config.Config {
 	cfg := &config.Config{}
@@ -69,6 +79,7 @@ func newTestConfig(storeName string) *config.Config {
 
 // TestNewClient_Ping 测试通过 NewClient 创建客户端后可以 Ping 通 Redis
 func TestNewClient_Ping(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 
 	client := cmfredis.NewClient(&cmfredis.Options{
@@ -83,6 +94,7 @@ func TestNewClient_Ping(t *testing.T) {
 
 // TestNewClient_SetGet 测试通过 NewClient 创建客户端后可以 Set/Get key
 func TestNewClient_SetGet(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 
 	client := cmfredis.NewClient(&cmfredis.Options{
@@ -117,6 +129,7 @@ func TestNewClient_SetGet(t *testing.T) {
 
 // TestNewClientFromConfig_Success 测试从 Config 创建 Redis 客户端并验证连接
 func TestNewClientFromConfig_Success(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 	storeName := "test-cfc-success"
 	cfg := newTestConfig(storeName)
@@ -134,6 +147,7 @@ func TestNewClientFromConfig_Success(t *testing.T) {
 
 // TestNewClientFromConfig_DefaultStoreName 测试不指定 storeName 时使用配置中的默认值
 func TestNewClientFromConfig_DefaultStoreName(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 	storeName := "test-cfc-default"
 	cfg := newTestConfig(storeName)
@@ -152,6 +166,7 @@ func TestNewClientFromConfig_DefaultStoreName(t *testing.T) {
 
 // TestNewClientFromConfig_SameStoreNameReturnsSameInstance 测试相同 storeName 返回同一实例（单例验证）
 func TestNewClientFromConfig_SameStoreNameReturnsSameInstance(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 	storeName := "test-cfc-singleton-same"
 	cfg := newTestConfig(storeName)
@@ -175,6 +190,7 @@ func TestNewClientFromConfig_SameStoreNameReturnsSameInstance(t *testing.T) {
 
 // TestNewClientFromConfig_DifferentStoreNameReturnsDifferentInstance 测试不同 storeName 返回不同实例
 func TestNewClientFromConfig_DifferentStoreNameReturnsDifferentInstance(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 	storeName1 := "test-cfc-diff-1"
 	storeName2 := "test-cfc-diff-2"
@@ -241,6 +257,7 @@ func TestNewClientFromConfig_DefaultStoreNotFound(t *testing.T) {
 // TestNewClientFromConfig_TLS 测试 TLS 配置
 // 由于 testcontainers Redis 默认不支持 TLS，启用 TLS 后连接会失败，验证错误不为 nil 即可
 func TestNewClientFromConfig_TLS(t *testing.T) {
+	requireRedis(t)
 	ctx := context.Background()
 	storeName := "test-cfc-tls"
 	cfg := &config.Config{}
@@ -256,3 +273,52 @@ func TestNewClientFromConfig_TLS(t *testing.T) {
 		t.Fatal("TLS 连接到非 TLS Redis 应返回错误，但没有返回错误")
 	}
 }
+
+// =============================================================================
+// 连接模式测试
+// =============================================================================
+
+// TestNewClient_Modes 测试不同连接模式返回对应的 go-redis 客户端类型
+func TestNewClient_Modes(t *testing.T) {
+	standalone := cmfredis.NewClient(&cmfredis.Options{Addr: testRedisAddr})
+	defer standalone.Close()
+	if _, ok := standalone.(*goredis.Client); !ok {
+		t.Errorf("standalone 模式应返回 *redis.Client，得到 %T", standalone)
+	}
+
+	sentinel := cmfredis.NewClient(&cmfredis.Options{
+		Mode:          cmfredis.ModeSentinel,
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"127.0.0.1:26379"},
+	})
+	defer sentinel.Close()
+	if _, ok := sentinel.(*goredis.Client); !ok {
+		t.Errorf("sentinel 模式应返回故障转移 *redis.Client，得到 %T", sentinel)
+	}
+
+	cluster := cmfredis.NewClient(&cmfredis.Options{
+		Mode:         cmfredis.ModeCluster,
+		ClusterNodes: []string{"127.0.0.1:7000", "127.0.0.1:7001"},
+	})
+	defer cluster.Close()
+	if _, ok := cluster.(*goredis.ClusterClient); !ok {
+		t.Errorf("cluster 模式应返回 *redis.ClusterClient，得到 %T", cluster)
+	}
+}
+
+// TestNewClientFromConfig_InvalidMode 测试连接模式配置不完整或不支持时返回错误
+func TestNewClientFromConfig_InvalidMode(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Redis.Connections = map[string]config.Redis{
+		"test-mode-sentinel": {Mode: cmfredis.ModeSentinel, SentinelAddrs: []string{"127.0.0.1:26379"}},
+		"test-mode-cluster":  {Mode: cmfredis.ModeCluster},
+		"test-mode-unknown":  {Mode: "ring", Addr: testRedisAddr},
+	}
+
+	for name := range cfg.Redis.Connections {
+		if _, err := cmfredis.NewClientFromConfig(ctx, cfg, name); err == nil {
+			t.Errorf("%s 配置无效时应返回错误", name)
+		}
+	}
+}
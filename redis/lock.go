@@ -0,0 +1,360 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotObtained 锁已被其他持有者占用
+	ErrLockNotObtained = errors.New("lock not obtained")
+	// ErrLockNotHeld 锁已过期或已被释放，当前持有者不再拥有该锁
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+const (
+	defaultLockPrefix        = "lock:"
+	defaultLockRetryInterval = 100 * time.Millisecond
+)
+
+// 释放锁：仅当值与持有者 token 一致时删除
+var releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// 续期锁：仅当值与持有者 token 一致时延长过期时间
+var refreshScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Locker 互斥锁接口，分布式（Redis）与单机（内存）实现共用
+type Locker interface {
+	// Lock 获取锁，被占用时按重试间隔等待，直到获取成功或 ctx 结束
+	Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+	// TryLock 尝试获取锁，被占用时立即返回 ErrLockNotObtained
+	TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+}
+
+// lockBackend 锁的底层存储操作
+type lockBackend interface {
+	acquire(ctx context.Context, key string, token string, ttl time.Duration) (bool, error)
+	release(ctx context.Context, key string, token string) (bool, error)
+	refresh(ctx context.Context, key string, token string, ttl time.Duration) (bool, error)
+}
+
+// LockerOption 函数式可选参数
+type LockerOption func(*lockerOptions)
+
+type lockerOptions struct {
+	prefix        string
+	retryInterval time.Duration
+	autoRenew     bool
+}
+
+// WithLockPrefix 设置锁键前缀，默认 lock:
+func WithLockPrefix(prefix string) LockerOption {
+	return func(o *lockerOptions) { o.prefix = prefix }
+}
+
+// WithLockRetryInterval 设置 Lock 等待时的重试间隔，默认 100ms
+func WithLockRetryInterval(d time.Duration) LockerOption {
+	return func(o *lockerOptions) {
+		if d > 0 {
+			o.retryInterval = d
+		}
+	}
+}
+
+// WithoutAutoRenew 关闭持有期间的自动续期
+func WithoutAutoRenew() LockerOption {
+	return func(o *lockerOptions) { o.autoRenew = false }
+}
+
+func newLockerOptions(opts ...LockerOption) lockerOptions {
+	o := lockerOptions{
+		prefix:        defaultLockPrefix,
+		retryInterval: defaultLockRetryInterval,
+		autoRenew:     true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// RedisLocker 基于 Redis 的分布式锁
+// 使用 SET NX PX 加锁，随机 token 标识持有者，Lua 脚本比较后删除/续期
+type RedisLocker struct {
+	client  redis.UniversalClient
+	options lockerOptions
+}
+
+// NewLocker 创建基于 Redis 的分布式锁
+func NewLocker(client redis.UniversalClient, opts ...LockerOption) *RedisLocker {
+	return &RedisLocker{client: client, options: newLockerOptions(opts...)}
+}
+
+// Lock 获取锁，被占用时等待直到获取成功或 ctx 结束
+func (l *RedisLocker) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return obtainLock(ctx, l, l.options, key, ttl, true)
+}
+
+// TryLock 尝试获取锁，被占用时立即返回 ErrLockNotObtained
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return obtainLock(ctx, l, l.options, key, ttl, false)
+}
+
+func (l *RedisLocker) acquire(ctx context.Context, key string, token string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+func (l *RedisLocker) release(ctx context.Context, key string, token string) (bool, error) {
+	n, err := releaseScript.Run(ctx, l.client, []string{key}, token).Int64()
+	return n == 1, err
+}
+
+func (l *RedisLocker) refresh(ctx context.Context, key string, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshScript.Run(ctx, l.client, []string{key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// MemoryLocker 进程内互斥锁，与 RedisLocker 行为一致，适用于单节点部署和测试
+type MemoryLocker struct {
+	mu      sync.Mutex
+	entries map[string]memoryLockEntry
+	options lockerOptions
+}
+
+type memoryLockEntry struct {
+	token    string
+	expireAt time.Time
+}
+
+// NewMemoryLocker 创建进程内互斥锁
+func NewMemoryLocker(opts ...LockerOption) *MemoryLocker {
+	return &MemoryLocker{
+		entries: make(map[string]memoryLockEntry),
+		options: newLockerOptions(opts...),
+	}
+}
+
+// Lock 获取锁，被占用时等待直到获取成功或 ctx 结束
+func (l *MemoryLocker) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return obtainLock(ctx, l, l.options, key, ttl, true)
+}
+
+// TryLock 尝试获取锁，被占用时立即返回 ErrLockNotObtained
+func (l *MemoryLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return obtainLock(ctx, l, l.options, key, ttl, false)
+}
+
+func (l *MemoryLocker) acquire(_ context.Context, key string, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.entries[key]; ok && time.Now().Before(entry.expireAt) {
+		return false, nil
+	}
+	l.entries[key] = memoryLockEntry{token: token, expireAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (l *MemoryLocker) release(_ context.Context, key string, token string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	if !ok || entry.token != token || !time.Now().Before(entry.expireAt) {
+		return false, nil
+	}
+	delete(l.entries, key)
+	return true, nil
+}
+
+func (l *MemoryLocker) refresh(_ context.Context, key string, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	if !ok || entry.token != token || !time.Now().Before(entry.expireAt) {
+		return false, nil
+	}
+	entry.expireAt = time.Now().Add(ttl)
+	l.entries[key] = entry
+	return true, nil
+}
+
+// Lock 已获取的锁句柄
+type Lock struct {
+	backend lockBackend
+	key     string
+	token   string
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	released bool
+	stop     chan struct{}
+	lost     chan struct{}
+	lostOnce sync.Once
+	renewWG  sync.WaitGroup
+}
+
+// obtainLock 生成 token 并尝试获取锁，wait 为 true 时在占用期间持续重试
+func obtainLock(ctx context.Context, backend lockBackend, opts lockerOptions, key string, ttl time.Duration, wait bool) (*Lock, error) {
+	if key == "" || ttl <= 0 {
+		return nil, errors.New("lock key 和 ttl 不能为空")
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	fullKey := opts.prefix + key
+
+	for {
+		ok, err := backend.acquire(ctx, fullKey, token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if !wait {
+			return nil, ErrLockNotObtained
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.retryInterval):
+		}
+	}
+
+	lock := &Lock{
+		backend: backend,
+		key:     fullKey,
+		token:   token,
+		ttl:     ttl,
+		stop:    make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+	if opts.autoRenew {
+		lock.renewWG.Add(1)
+		go lock.autoRenew()
+	}
+	return lock, nil
+}
+
+// Key 返回锁的完整键名
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 返回持有者 token
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Lost 返回一个在自动续期失败、锁已丢失时关闭的 channel
+// 持有者应监听该 channel 并中止受保护的操作
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 将锁的过期时间重置为 ttl，ttl 为 0 时沿用获取时的 ttl
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	l.mu.Lock()
+	if ttl > 0 {
+		l.ttl = ttl
+	}
+	ttl = l.ttl
+	released := l.released
+	l.mu.Unlock()
+	if released {
+		return ErrLockNotHeld
+	}
+
+	ok, err := l.backend.refresh(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.markLost()
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock 释放锁并停止自动续期
+// 锁已过期或被他人持有时返回 ErrLockNotHeld
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return ErrLockNotHeld
+	}
+	l.released = true
+	close(l.stop)
+	l.mu.Unlock()
+	l.renewWG.Wait()
+
+	ok, err := l.backend.release(ctx, l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// autoRenew 持有期间每隔 ttl/3 续期一次
+// 续期返回未持有，或连续失败超过 ttl 时，视为锁已丢失
+func (l *Lock) autoRenew() {
+	defer l.renewWG.Done()
+
+	lastRenewed := time.Now()
+	for {
+		l.mu.Lock()
+		ttl := l.ttl
+		l.mu.Unlock()
+
+		interval := max(ttl/3, time.Millisecond)
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(interval):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		ok, err := l.backend.refresh(ctx, l.key, l.token, ttl)
+		cancel()
+		switch {
+		case err == nil && ok:
+			lastRenewed = time.Now()
+		case err == nil && !ok, time.Since(lastRenewed) >= ttl:
+			l.markLost()
+			return
+		}
+	}
+}
+
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// newLockToken 生成随机持有者 token
+func newLockToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
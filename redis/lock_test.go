@@ -0,0 +1,151 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cmfredis "github.com/wuwuseo/cmf/redis"
+)
+
+// newTestLockers 返回需要验证的锁实现：内存实现与基于测试容器的 Redis 实现
+// Redis 容器不可用时只验证内存实现
+func newTestLockers(t *testing.T) map[string]cmfredis.Locker {
+	t.Helper()
+	lockers := map[string]cmfredis.Locker{
+		"memory": cmfredis.NewMemoryLocker(cmfredis.WithLockRetryInterval(10 * time.Millisecond)),
+	}
+	if testRedisSkip != "" {
+		t.Log("跳过 Redis 锁：" + testRedisSkip)
+		return lockers
+	}
+	client := cmfredis.NewClient(&cmfredis.Options{Addr: testRedisAddr})
+	t.Cleanup(func() { client.Close() })
+	lockers["redis"] = cmfredis.NewLocker(client, cmfredis.WithLockRetryInterval(10*time.Millisecond))
+	return lockers
+}
+
+func TestLocker_MutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	for name, locker := range newTestLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			lock, err := locker.TryLock(ctx, "test-mutex-"+name, time.Second)
+			if err != nil {
+				t.Fatalf("首次加锁失败: %v", err)
+			}
+
+			if _, err := locker.TryLock(ctx, "test-mutex-"+name, time.Second); !errors.Is(err, cmfredis.ErrLockNotObtained) {
+				t.Fatalf("锁被占用时应返回 ErrLockNotObtained，得到 %v", err)
+			}
+
+			if err := lock.Unlock(ctx); err != nil {
+				t.Fatalf("解锁失败: %v", err)
+			}
+			if err := lock.Unlock(ctx); !errors.Is(err, cmfredis.ErrLockNotHeld) {
+				t.Errorf("重复解锁应返回 ErrLockNotHeld，得到 %v", err)
+			}
+
+			again, err := locker.TryLock(ctx, "test-mutex-"+name, time.Second)
+			if err != nil {
+				t.Fatalf("释放后应能再次加锁: %v", err)
+			}
+			_ = again.Unlock(ctx)
+		})
+	}
+}
+
+func TestLocker_LockWaitsForRelease(t *testing.T) {
+	ctx := context.Background()
+	for name, locker := range newTestLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			first, err := locker.Lock(ctx, "test-wait-"+name, time.Second)
+			if err != nil {
+				t.Fatalf("加锁失败: %v", err)
+			}
+			time.AfterFunc(50*time.Millisecond, func() { _ = first.Unlock(context.Background()) })
+
+			waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+			defer cancel()
+			second, err := locker.Lock(waitCtx, "test-wait-"+name, time.Second)
+			if err != nil {
+				t.Fatalf("等待释放后应能加锁: %v", err)
+			}
+			_ = second.Unlock(ctx)
+		})
+	}
+}
+
+func TestLocker_LockContextTimeout(t *testing.T) {
+	ctx := context.Background()
+	for name, locker := range newTestLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			held, err := locker.Lock(ctx, "test-timeout-"+name, time.Second)
+			if err != nil {
+				t.Fatalf("加锁失败: %v", err)
+			}
+			defer held.Unlock(ctx)
+
+			waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			if _, err := locker.Lock(waitCtx, "test-timeout-"+name, time.Second); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("超时应返回 context.DeadlineExceeded，得到 %v", err)
+			}
+		})
+	}
+}
+
+func TestLocker_AutoRenew(t *testing.T) {
+	ctx := context.Background()
+	for name, locker := range newTestLockers(t) {
+		t.Run(name, func(t *testing.T) {
+			lock, err := locker.TryLock(ctx, "test-renew-"+name, 150*time.Millisecond)
+			if err != nil {
+				t.Fatalf("加锁失败: %v", err)
+			}
+
+			// 超过 ttl 后仍由自动续期保持持有
+			time.Sleep(400 * time.Millisecond)
+			if _, err := locker.TryLock(ctx, "test-renew-"+name, time.Second); !errors.Is(err, cmfredis.ErrLockNotObtained) {
+				t.Fatalf("自动续期期间锁不应被他人获取，得到 %v", err)
+			}
+			select {
+			case <-lock.Lost():
+				t.Fatal("自动续期期间锁不应丢失")
+			default:
+			}
+			if err := lock.Unlock(ctx); err != nil {
+				t.Errorf("解锁失败: %v", err)
+			}
+		})
+	}
+}
+
+func TestLocker_ExpiresWithoutAutoRenew(t *testing.T) {
+	ctx := context.Background()
+	locker := cmfredis.NewMemoryLocker(cmfredis.WithoutAutoRenew())
+
+	lock, err := locker.TryLock(ctx, "test-expire", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("加锁失败: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	other, err := locker.TryLock(ctx, "test-expire", time.Second)
+	if err != nil {
+		t.Fatalf("过期后应能被他人获取: %v", err)
+	}
+	defer other.Unlock(ctx)
+
+	if err := lock.Refresh(ctx, time.Second); !errors.Is(err, cmfredis.ErrLockNotHeld) {
+		t.Errorf("过期后续期应返回 ErrLockNotHeld，得到 %v", err)
+	}
+	select {
+	case <-lock.Lost():
+	default:
+		t.Error("续期失败后 Lost 应被关闭")
+	}
+	if err := lock.Unlock(ctx); !errors.Is(err, cmfredis.ErrLockNotHeld) {
+		t.Errorf("不应释放他人持有的锁，得到 %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/wuwuseo/cmf/config"
+)
+
+// ErrManagerClosed 管理器已关闭
+var ErrManagerClosed = errors.New("redis manager closed")
+
+const (
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = 30 * time.Second
+)
+
+// Manager 命名 Redis 客户端管理器
+// 首次获取时才建立连接，连接失败后按指数退避等待，退避期内直接返回上次的错误；
+// Close 关闭全部客户端，可注册为 Bootstrap 服务在应用关闭时统一释放
+type Manager struct {
+	cfg         *config.Config
+	backoffBase time.Duration
+	backoffMax  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*managedClient
+	closed  bool
+}
+
+// managedClient 单个命名连接的状态
+type managedClient struct {
+	mu          sync.Mutex
+	client      redis.UniversalClient
+	lastErr     error
+	failures    int
+	nextAttempt time.Time
+}
+
+// ConnectionStatus 单个命名连接的健康状态与连接池统计
+type ConnectionStatus struct {
+	Name      string           `json:"name"`
+	Connected bool             `json:"connected"`       // 是否已建立客户端
+	Healthy   bool             `json:"healthy"`         // 最近一次检查是否可用
+	Latency   time.Duration    `json:"latency"`         // Ping 耗时，仅 Health 填充
+	Error     string           `json:"error,omitempty"` // 最近一次错误
+	Failures  int              `json:"failures"`        // 连续连接失败次数
+	NextRetry time.Time        `json:"next_retry"`      // 退避结束时间
+	Pool      *redis.PoolStats `json:"pool,omitempty"`  // 连接池统计
+}
+
+// ManagerOption 函数式可选参数
+type ManagerOption func(*Manager)
+
+// WithBackoff 设置连接失败后的退避时间，base 为首次等待时间，每次失败翻倍直到 max
+func WithBackoff(base time.Duration, max time.Duration) ManagerOption {
+	return func(m *Manager) {
+		if base > 0 {
+			m.backoffBase = base
+		}
+		if max >= m.backoffBase {
+			m.backoffMax = max
+		}
+	}
+}
+
+// NewManager 创建 Redis 客户端管理器，cfg 为 nil 时只能通过 NewClientFromConfig 使用
+func NewManager(cfg *config.Config, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		cfg:         cfg,
+		backoffBase: defaultBackoffBase,
+		backoffMax:  defaultBackoffMax,
+		entries:     make(map[string]*managedClient),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+var (
+	defaultManagerMu sync.RWMutex
+	defaultManager   = NewManager(nil)
+)
+
+// DefaultManager 返回 NewClientFromConfig 使用的默认管理器
+func DefaultManager() *Manager {
+	defaultManagerMu.RLock()
+	defer defaultManagerMu.RUnlock()
+	return defaultManager
+}
+
+// SetDefaultManager 替换默认管理器，不会关闭旧的管理器
+// 测试之间可传入新的管理器以重置全部客户端
+func SetDefaultManager(m *Manager) {
+	defaultManagerMu.Lock()
+	defer defaultManagerMu.Unlock()
+	defaultManager = m
+}
+
+// Client 获取命名客户端，未指定名称时使用配置中的默认连接
+func (m *Manager) Client(ctx context.Context, name ...string) (redis.UniversalClient, error) {
+	if m.cfg == nil {
+		return nil, errors.New("Redis管理器未设置配置")
+	}
+	return m.client(ctx, m.cfg, name...)
+}
+
+// client 按配置获取或创建命名客户端
+func (m *Manager) client(ctx context.Context, cfg *config.Config, name ...string) (redis.UniversalClient, error) {
+	storeKey := cfg.Redis.Default
+	if len(name) > 0 {
+		storeKey = name[0]
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrManagerClosed
+	}
+	entry, ok := m.entries[storeKey]
+	if !ok {
+		entry = &managedClient{}
+		m.entries[storeKey] = entry
+	}
+	m.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.client != nil {
+		return entry.client, nil
+	}
+	if wait := time.Until(entry.nextAttempt); wait > 0 {
+		return nil, fmt.Errorf("Redis连接 %s 处于重试退避期，%s 后重试: %w", storeKey, wait.Round(time.Millisecond), entry.lastErr)
+	}
+
+	redisConfig, ok := cfg.Redis.Connections[storeKey]
+	if !ok {
+		return nil, fmt.Errorf("未找到Redis配置: %s", storeKey)
+	}
+	options, err := optionsFromConfig(redisConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		entry.failures++
+		entry.lastErr = err
+		entry.nextAttempt = time.Now().Add(m.backoff(entry.failures))
+		return nil, fmt.Errorf("连接Redis失败: %w", err)
+	}
+
+	// 关闭期间建立的连接直接释放
+	m.mu.Lock()
+	closed := m.closed
+	m.mu.Unlock()
+	if closed {
+		client.Close()
+		return nil, ErrManagerClosed
+	}
+
+	entry.client = client
+	entry.failures = 0
+	entry.lastErr = nil
+	entry.nextAttempt = time.Time{}
+	return client, nil
+}
+
+// backoff 计算第 failures 次失败后的等待时间
+func (m *Manager) backoff(failures int) time.Duration {
+	wait := m.backoffBase
+	for i := 1; i < failures && wait < m.backoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, m.backoffMax)
+}
+
+// Health 对已建立的连接执行 Ping，返回全部命名连接的状态，按名称排序
+func (m *Manager) Health(ctx context.Context) []ConnectionStatus {
+	return m.status(ctx, true)
+}
+
+// Stats 返回全部命名连接的状态与连接池统计，不访问 Redis
+func (m *Manager) Stats() []ConnectionStatus {
+	return m.status(context.Background(), false)
+}
+
+func (m *Manager) status(ctx context.Context, ping bool) []ConnectionStatus {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.entries))
+	entries := make(map[string]*managedClient, len(m.entries))
+	for name, entry := range m.entries {
+		names = append(names, name)
+		entries[name] = entry
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+
+	result := make([]ConnectionStatus, 0, len(names))
+	for _, name := range names {
+		entry := entries[name]
+		entry.mu.Lock()
+		status := ConnectionStatus{
+			Name:      name,
+			Connected: entry.client != nil,
+			Healthy:   entry.client != nil,
+			Failures:  entry.failures,
+			NextRetry: entry.nextAttempt,
+		}
+		if entry.lastErr != nil {
+			status.Error = entry.lastErr.Error()
+		}
+		client := entry.client
+		entry.mu.Unlock()
+
+		if client != nil {
+			status.Pool = client.PoolStats()
+			if ping {
+				start := time.Now()
+				err := client.Ping(ctx).Err()
+				status.Latency = time.Since(start)
+				if err != nil {
+					status.Healthy = false
+					status.Error = err.Error()
+				}
+			}
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// Close 关闭全部客户端，关闭后 Client 返回 ErrManagerClosed
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	entries := m.entries
+	m.entries = make(map[string]*managedClient)
+	m.mu.Unlock()
+
+	var errs []error
+	for name, entry := range entries {
+		entry.mu.Lock()
+		if entry.client != nil {
+			if err := entry.client.Close(); err != nil && !errors.Is(err, redis.ErrClosed) {
+				errs = append(errs, fmt.Errorf("关闭Redis连接 %s 失败: %w", name, err))
+			}
+			entry.client = nil
+		}
+		entry.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
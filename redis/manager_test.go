@@ -0,0 +1,147 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/wuwuseo/cmf/config"
+	cmfredis "github.com/wuwuseo/cmf/redis"
+)
+
+// TestManager_ClientReturnsSameInstance 测试管理器按名称复用客户端
+func TestManager_ClientReturnsSameInstance(t *testing.T) {
+	requireRedis(t)
+	ctx := context.Background()
+	manager := cmfredis.NewManager(newTestConfig("test-manager"))
+	defer manager.Close()
+
+	client1, err := manager.Client(ctx)
+	if err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+	client2, err := manager.Client(ctx, "test-manager")
+	if err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+	if client1 != client2 {
+		t.Error("相同名称应返回同一实例")
+	}
+}
+
+// TestManager_BackoffAfterFailure 测试连接失败后在退避期内不再重复拨号
+func TestManager_BackoffAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.Redis.Default = "unreachable"
+	cfg.Redis.Connections = map[string]config.Redis{
+		"unreachable": {Addr: "127.0.0.1:1", DialTimeout: 1},
+	}
+	manager := cmfredis.NewManager(cfg, cmfredis.WithBackoff(200*time.Millisecond, time.Second))
+	defer manager.Close()
+
+	if _, err := manager.Client(ctx); err == nil {
+		t.Fatal("连接不可达地址应返回错误")
+	}
+
+	start := time.Now()
+	_, err := manager.Client(ctx)
+	if err == nil || !strings.Contains(err.Error(), "退避") {
+		t.Fatalf("退避期内应直接返回错误，得到 %v", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("退避期内不应重新拨号")
+	}
+
+	stats := manager.Stats()
+	if len(stats) != 1 || stats[0].Failures != 1 || stats[0].Connected {
+		t.Errorf("统计信息不正确: %+v", stats)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	manager.Client(ctx)
+	if stats := manager.Stats(); stats[0].Failures != 2 {
+		t.Errorf("退避结束后应重新尝试连接，失败次数 %d", stats[0].Failures)
+	}
+}
+
+// TestManager_HealthAndStats 测试健康检查与连接池统计
+func TestManager_HealthAndStats(t *testing.T) {
+	requireRedis(t)
+	ctx := context.Background()
+	manager := cmfredis.NewManager(newTestConfig("test-manager-health"))
+	defer manager.Close()
+
+	if len(manager.Health(ctx)) != 0 {
+		t.Error("未使用的连接不应提前建立")
+	}
+	if _, err := manager.Client(ctx); err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+
+	health := manager.Health(ctx)
+	if len(health) != 1 || !health[0].Healthy || health[0].Pool == nil {
+		t.Fatalf("健康状态不正确: %+v", health)
+	}
+	if health[0].Name != "test-manager-health" {
+		t.Errorf("连接名称不正确: %s", health[0].Name)
+	}
+}
+
+// TestManager_Close 测试关闭后释放全部客户端
+func TestManager_Close(t *testing.T) {
+	requireRedis(t)
+	ctx := context.Background()
+	manager := cmfredis.NewManager(newTestConfig("test-manager-close"))
+
+	client, err := manager.Client(ctx)
+	if err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("关闭管理器失败: %v", err)
+	}
+	if err := client.Ping(ctx).Err(); !errors.Is(err, goredis.ErrClosed) {
+		t.Errorf("关闭后客户端应不可用，得到 %v", err)
+	}
+	if _, err := manager.Client(ctx); !errors.Is(err, cmfredis.ErrManagerClosed) {
+		t.Errorf("关闭后获取客户端应返回 ErrManagerClosed，得到 %v", err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Errorf("重复关闭不应返回错误: %v", err)
+	}
+}
+
+// TestSetDefaultManager 测试替换默认管理器后 NewClientFromConfig 使用新的实例
+func TestSetDefaultManager(t *testing.T) {
+	requireRedis(t)
+	ctx := context.Background()
+	old := cmfredis.DefaultManager()
+	defer cmfredis.SetDefaultManager(old)
+
+	storeName := "test-default-manager"
+	cfg := newTestConfig(storeName)
+
+	first := cmfredis.NewManager(nil)
+	cmfredis.SetDefaultManager(first)
+	client1, err := cmfredis.NewClientFromConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig 失败: %v", err)
+	}
+	first.Close()
+
+	second := cmfredis.NewManager(nil)
+	defer second.Close()
+	cmfredis.SetDefaultManager(second)
+	client2, err := cmfredis.NewClientFromConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewClientFromConfig 失败: %v", err)
+	}
+	if client1 == client2 {
+		t.Error("替换默认管理器后应创建新的客户端")
+	}
+}
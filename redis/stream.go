@@ -0,0 +1,415 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	cmflog "github.com/wuwuseo/cmf/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// streamPayloadField 消息体在 Stream 条目中的字段名
+	streamPayloadField = "payload"
+
+	defaultStreamWorkers       = 1
+	defaultStreamMaxDeliveries = 5
+	defaultStreamClaimMinIdle  = 30 * time.Second
+	defaultStreamBlockTimeout  = 2 * time.Second
+	defaultDeadLetterSuffix    = ":dead"
+)
+
+// ErrConsumerStarted 消费者已启动
+var ErrConsumerStarted = errors.New("stream consumer already started")
+
+// Producer Redis Stream 消息生产者
+type Producer struct {
+	client redis.UniversalClient
+	maxLen int64
+}
+
+// ProducerOption 函数式可选参数
+type ProducerOption func(*Producer)
+
+// WithStreamMaxLen 设置 Stream 的近似最大长度，超出后裁剪最早的消息
+func WithStreamMaxLen(n int64) ProducerOption {
+	return func(p *Producer) { p.maxLen = n }
+}
+
+// NewProducer 创建 Stream 消息生产者
+func NewProducer(client redis.UniversalClient, opts ...ProducerOption) *Producer {
+	p := &Producer{client: client}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish 发布消息，返回消息 ID
+// payload 为 []byte 或 string 时原样写入，其他类型编码为 JSON
+func (p *Producer) Publish(ctx context.Context, stream string, payload any) (string, error) {
+	data, err := encodePayload(payload)
+	if err != nil {
+		return "", err
+	}
+	args := &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]any{streamPayloadField: data},
+	}
+	if p.maxLen > 0 {
+		args.MaxLen = p.maxLen
+		args.Approx = true
+	}
+	return p.client.XAdd(ctx, args).Result()
+}
+
+func encodePayload(payload any) ([]byte, error) {
+	switch v := payload.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// StreamMessage 消费到的 Stream 消息
+type StreamMessage struct {
+	ID         string // 消息 ID
+	Stream     string // 所属 Stream
+	Payload    []byte // 消息体
+	Deliveries int64  // 已投递次数，首次投递为 1
+}
+
+// Decode 将 JSON 消息体解码到 v
+func (m *StreamMessage) Decode(v any) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// StreamHandler 消息处理函数，返回 nil 时确认消息，返回错误时等待重新投递
+type StreamHandler func(ctx context.Context, msg *StreamMessage) error
+
+// ConsumerConfig 消费者组配置
+type ConsumerConfig struct {
+	Stream   string        // Stream 名称（必填）
+	Group    string        // 消费者组名称（必填）
+	Consumer string        // 消费者名称，默认 主机名-进程号
+	Handler  StreamHandler // 消息处理函数（必填）
+	Workers  int           // 并发处理的 worker 数量，默认 1
+
+	MaxDeliveries    int64         // 最大投递次数，超过后转入死信 Stream，默认 5
+	ClaimMinIdle     time.Duration // 待确认消息空闲超过该时间后被重新认领，应大于单条消息的处理耗时，默认 30 秒
+	ClaimInterval    time.Duration // 扫描待确认消息的间隔，默认与 ClaimMinIdle 相同
+	BlockTimeout     time.Duration // XREADGROUP 阻塞等待时间，也决定 Close 的最长等待，默认 2 秒
+	DeadLetterStream string        // 死信 Stream 名称，默认 Stream + ":dead"
+
+	Logger cmflog.Logger // 日志，默认使用全局日志
+}
+
+// Consumer Redis Stream 消费者组
+// 新消息由 XREADGROUP 读取并分发给 worker，处理成功后 XACK；
+// 处理失败的消息保留在待确认列表中，空闲超过 ClaimMinIdle 后通过 XCLAIM 重新投递，
+// 投递次数达到 MaxDeliveries 后写入死信 Stream 并确认
+type Consumer struct {
+	client redis.UniversalClient
+	config ConsumerConfig
+	logger cmflog.Logger
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewConsumer 创建 Stream 消费者组
+func NewConsumer(client redis.UniversalClient, config ConsumerConfig) (*Consumer, error) {
+	if config.Stream == "" || config.Group == "" || config.Handler == nil {
+		return nil, errors.New("stream consumer 需要配置 Stream、Group 和 Handler")
+	}
+	if config.Consumer == "" {
+		hostname, _ := os.Hostname()
+		config.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultStreamWorkers
+	}
+	if config.MaxDeliveries <= 0 {
+		config.MaxDeliveries = defaultStreamMaxDeliveries
+	}
+	if config.ClaimMinIdle <= 0 {
+		config.ClaimMinIdle = defaultStreamClaimMinIdle
+	}
+	if config.ClaimInterval <= 0 {
+		config.ClaimInterval = config.ClaimMinIdle
+	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = defaultStreamBlockTimeout
+	}
+	if config.DeadLetterStream == "" {
+		config.DeadLetterStream = config.Stream + defaultDeadLetterSuffix
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = cmflog.GetDefault()
+	}
+	return &Consumer{
+		client: client,
+		config: config,
+		logger: logger.With(
+			zap.String("stream", config.Stream),
+			zap.String("group", config.Group),
+			zap.String("consumer", config.Consumer),
+		),
+	}, nil
+}
+
+// Start 创建消费者组（不存在时）并启动读取、认领和处理协程
+// 实现了 Start 的服务注册到 Bootstrap 后会随应用启动
+func (c *Consumer) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return ErrConsumerStarted
+	}
+
+	err := c.client.XGroupCreateMkStream(ctx, c.config.Stream, c.config.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("创建消费者组失败: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.cancel = cancel
+	c.started = true
+
+	jobs := make(chan *StreamMessage)
+	// 处理函数使用不随 Close 取消的上下文，保证进行中的消息正常完成
+	handlerCtx := context.WithoutCancel(ctx)
+	for range c.config.Workers {
+		c.wg.Add(1)
+		go c.work(handlerCtx, jobs)
+	}
+
+	var producers sync.WaitGroup
+	producers.Add(2)
+	go func() {
+		defer producers.Done()
+		c.read(runCtx, jobs)
+	}()
+	go func() {
+		defer producers.Done()
+		c.claim(runCtx, jobs)
+	}()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		producers.Wait()
+		close(jobs)
+	}()
+
+	c.logger.Info("Stream消费者已启动", zap.Int("workers", c.config.Workers))
+	return nil
+}
+
+// Close 停止读取新消息，并等待进行中的消息处理完成
+// 注册到 Bootstrap 后会在应用关闭时调用
+func (c *Consumer) Close() error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return nil
+	}
+	c.started = false
+	c.cancel()
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	c.logger.Info("Stream消费者已停止")
+	return nil
+}
+
+// read 读取分配给当前消费者的新消息
+func (c *Consumer) read(ctx context.Context, jobs chan<- *StreamMessage) {
+	for ctx.Err() == nil {
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.config.Group,
+			Consumer: c.config.Consumer,
+			Streams:  []string{c.config.Stream, ">"},
+			Count:    int64(c.config.Workers),
+			Block:    c.config.BlockTimeout,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			c.logger.Error("读取Stream消息失败", zap.Error(err))
+			sleepContext(ctx, c.config.BlockTimeout)
+			continue
+		}
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				if !dispatch(ctx, jobs, c.newMessage(message, 1)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// claim 定期认领空闲过久的待确认消息，超过最大投递次数的消息转入死信 Stream
+func (c *Consumer) claim(ctx context.Context, jobs chan<- *StreamMessage) {
+	ticker := time.NewTicker(c.config.ClaimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: c.config.Stream,
+			Group:  c.config.Group,
+			Idle:   c.config.ClaimMinIdle,
+			Start:  "-",
+			End:    "+",
+			Count:  int64(c.config.Workers) * 10,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Error("查询待确认消息失败", zap.Error(err))
+			}
+			continue
+		}
+
+		for _, entry := range pending {
+			messages, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+				Stream:   c.config.Stream,
+				Group:    c.config.Group,
+				Consumer: c.config.Consumer,
+				MinIdle:  c.config.ClaimMinIdle,
+				Messages: []string{entry.ID},
+			}).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					c.logger.Error("认领待确认消息失败", zap.String("id", entry.ID), zap.Error(err))
+				}
+				continue
+			}
+			// 已被其他消费者认领或消息已被删除
+			if len(messages) == 0 {
+				continue
+			}
+
+			// 处理过程中进程退出等原因导致未能转入死信的消息，在此补充处理
+			if entry.RetryCount >= c.config.MaxDeliveries {
+				c.deadLetter(ctx, c.newMessage(messages[0], entry.RetryCount), "超过最大投递次数")
+				continue
+			}
+			if !dispatch(ctx, jobs, c.newMessage(messages[0], entry.RetryCount+1)) {
+				return
+			}
+		}
+	}
+}
+
+// work 处理消息，成功后确认，失败且达到最大投递次数时转入死信 Stream
+func (c *Consumer) work(ctx context.Context, jobs <-chan *StreamMessage) {
+	defer c.wg.Done()
+	for msg := range jobs {
+		err := c.handle(ctx, msg)
+		if err == nil {
+			if err := c.client.XAck(ctx, c.config.Stream, c.config.Group, msg.ID).Err(); err != nil {
+				c.logger.Error("确认Stream消息失败", zap.String("id", msg.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		if msg.Deliveries >= c.config.MaxDeliveries {
+			c.deadLetter(ctx, msg, err.Error())
+			continue
+		}
+		c.logger.Warn("处理Stream消息失败，等待重新投递",
+			zap.String("id", msg.ID),
+			zap.Int64("deliveries", msg.Deliveries),
+			zap.Error(err),
+		)
+	}
+}
+
+// handle 调用处理函数，panic 视为处理失败
+func (c *Consumer) handle(ctx context.Context, msg *StreamMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.config.Handler(ctx, msg)
+}
+
+// deadLetter 将消息写入死信 Stream 并确认原消息
+func (c *Consumer) deadLetter(ctx context.Context, msg *StreamMessage, reason string) {
+	err := c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.config.DeadLetterStream,
+		Values: map[string]any{
+			streamPayloadField: msg.Payload,
+			"stream":           msg.Stream,
+			"group":            c.config.Group,
+			"id":               msg.ID,
+			"deliveries":       msg.Deliveries,
+			"error":            reason,
+		},
+	}).Err()
+	if err != nil {
+		c.logger.Error("写入死信Stream失败", zap.String("id", msg.ID), zap.Error(err))
+		return
+	}
+	if err := c.client.XAck(ctx, c.config.Stream, c.config.Group, msg.ID).Err(); err != nil {
+		c.logger.Error("确认死信消息失败", zap.String("id", msg.ID), zap.Error(err))
+		return
+	}
+	c.logger.Warn("Stream消息已转入死信",
+		zap.String("id", msg.ID),
+		zap.Int64("deliveries", msg.Deliveries),
+		zap.String("dead_letter_stream", c.config.DeadLetterStream),
+		zap.String("reason", reason),
+	)
+}
+
+func (c *Consumer) newMessage(message redis.XMessage, deliveries int64) *StreamMessage {
+	msg := &StreamMessage{ID: message.ID, Stream: c.config.Stream, Deliveries: deliveries}
+	switch v := message.Values[streamPayloadField].(type) {
+	case string:
+		msg.Payload = []byte(v)
+	case []byte:
+		msg.Payload = v
+	case nil:
+	default:
+		msg.Payload = []byte(fmt.Sprint(v))
+	}
+	return msg
+}
+
+// dispatch 将消息交给 worker，ctx 结束时返回 false
+func dispatch(ctx context.Context, jobs chan<- *StreamMessage, msg *StreamMessage) bool {
+	select {
+	case jobs <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
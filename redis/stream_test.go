@@ -0,0 +1,199 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	cmfredis "github.com/wuwuseo/cmf/redis"
+)
+
+// newStreamClient 创建 Stream 测试使用的客户端，并清理测试 Stream
+func newStreamClient(t *testing.T, streams ...string) goredis.UniversalClient {
+	t.Helper()
+	requireRedis(t)
+	client := cmfredis.NewClient(&cmfredis.Options{Addr: testRedisAddr})
+	t.Cleanup(func() {
+		client.Del(context.Background(), streams...)
+		client.Close()
+	})
+	client.Del(context.Background(), streams...)
+	return client
+}
+
+// waitFor 轮询等待条件成立
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestConsumer_AcksHandledMessages(t *testing.T) {
+	ctx := context.Background()
+	stream := "test-stream-ack"
+	client := newStreamClient(t, stream)
+
+	var mu sync.Mutex
+	var received []string
+	consumer, err := cmfredis.NewConsumer(client, cmfredis.ConsumerConfig{
+		Stream:       stream,
+		Group:        "workers",
+		Workers:      2,
+		BlockTimeout: 100 * time.Millisecond,
+		Handler: func(ctx context.Context, msg *cmfredis.StreamMessage) error {
+			var payload struct{ Name string }
+			if err := msg.Decode(&payload); err != nil {
+				return err
+			}
+			mu.Lock()
+			received = append(received, payload.Name)
+			mu.Unlock()
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建消费者失败: %v", err)
+	}
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatalf("启动消费者失败: %v", err)
+	}
+	defer consumer.Close()
+
+	producer := cmfredis.NewProducer(client)
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := producer.Publish(ctx, stream, map[string]string{"Name": name}); err != nil {
+			t.Fatalf("发布消息失败: %v", err)
+		}
+	}
+
+	ok := waitFor(t, 3*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	})
+	if !ok {
+		t.Fatalf("应收到 3 条消息，实际 %v", received)
+	}
+	waitFor(t, time.Second, func() bool {
+		pending, _ := client.XPending(ctx, stream, "workers").Result()
+		return pending != nil && pending.Count == 0
+	})
+	if pending, _ := client.XPending(ctx, stream, "workers").Result(); pending == nil || pending.Count != 0 {
+		t.Errorf("处理成功的消息应被确认，待确认数量 %+v", pending)
+	}
+}
+
+func TestConsumer_RetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	stream := "test-stream-retry"
+	client := newStreamClient(t, stream, stream+":dead")
+
+	var attempts atomic.Int64
+	var lastDeliveries atomic.Int64
+	consumer, err := cmfredis.NewConsumer(client, cmfredis.ConsumerConfig{
+		Stream:        stream,
+		Group:         "workers",
+		MaxDeliveries: 3,
+		ClaimMinIdle:  50 * time.Millisecond,
+		ClaimInterval: 50 * time.Millisecond,
+		BlockTimeout:  100 * time.Millisecond,
+		Handler: func(ctx context.Context, msg *cmfredis.StreamMessage) error {
+			attempts.Add(1)
+			lastDeliveries.Store(msg.Deliveries)
+			return errors.New("处理失败")
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建消费者失败: %v", err)
+	}
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatalf("启动消费者失败: %v", err)
+	}
+	defer consumer.Close()
+
+	id, err := cmfredis.NewProducer(client).Publish(ctx, stream, "boom")
+	if err != nil {
+		t.Fatalf("发布消息失败: %v", err)
+	}
+
+	ok := waitFor(t, 5*time.Second, func() bool {
+		n, _ := client.XLen(ctx, stream+":dead").Result()
+		return n == 1
+	})
+	if !ok {
+		t.Fatalf("超过最大投递次数后应转入死信 Stream，已处理 %d 次", attempts.Load())
+	}
+	if attempts.Load() != 3 || lastDeliveries.Load() != 3 {
+		t.Errorf("应投递 3 次，实际处理 %d 次，最后投递次数 %d", attempts.Load(), lastDeliveries.Load())
+	}
+
+	dead, err := client.XRange(ctx, stream+":dead", "-", "+").Result()
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("读取死信失败: %v", err)
+	}
+	if dead[0].Values["payload"] != "boom" || dead[0].Values["id"] != id {
+		t.Errorf("死信内容不正确: %v", dead[0].Values)
+	}
+	if pending, _ := client.XPending(ctx, stream, "workers").Result(); pending == nil || pending.Count != 0 {
+		t.Errorf("转入死信后原消息应被确认，待确认数量 %+v", pending)
+	}
+}
+
+func TestConsumer_CloseWaitsForInflight(t *testing.T) {
+	ctx := context.Background()
+	stream := "test-stream-close"
+	client := newStreamClient(t, stream)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	consumer, err := cmfredis.NewConsumer(client, cmfredis.ConsumerConfig{
+		Stream:       stream,
+		Group:        "workers",
+		BlockTimeout: 100 * time.Millisecond,
+		Handler: func(ctx context.Context, msg *cmfredis.StreamMessage) error {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			finished.Store(true)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建消费者失败: %v", err)
+	}
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatalf("启动消费者失败: %v", err)
+	}
+	if err := consumer.Start(ctx); !errors.Is(err, cmfredis.ErrConsumerStarted) {
+		t.Errorf("重复启动应返回 ErrConsumerStarted，得到 %v", err)
+	}
+
+	cmfredis.NewProducer(client).Publish(ctx, stream, "slow")
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("消息未被处理")
+	}
+	if err := consumer.Close(); err != nil {
+		t.Fatalf("关闭消费者失败: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Close 应等待进行中的消息处理完成")
+	}
+}
+
+func TestNewConsumer_RequiresHandler(t *testing.T) {
+	if _, err := cmfredis.NewConsumer(nil, cmfredis.ConsumerConfig{Stream: "s", Group: "g"}); err == nil {
+		t.Error("缺少 Handler 时应返回错误")
+	}
+}
@@ -0,0 +1,232 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	cmflog "github.com/wuwuseo/cmf/log"
+	"go.uber.org/zap"
+)
+
+// TLSOptions Redis TLS 连接选项
+type TLSOptions struct {
+	CAFile             string // CA 证书文件路径，为空时使用系统根证书
+	CertFile           string // 客户端证书文件路径
+	KeyFile            string // 客户端私钥文件路径
+	ServerName         string // 校验服务端证书使用的主机名，为空时使用连接地址中的主机名
+	InsecureSkipVerify bool   // 跳过服务端证书校验，仅用于开发环境
+}
+
+// NewTLSConfig 根据选项创建 TLS 配置
+// 创建时校验 CA 证书、客户端证书与私钥是否匹配以及证书是否在有效期内；
+// 证书文件在握手时按修改时间检测变更并重新加载，轮换证书无需重启。
+// 重新加载失败时继续使用旧证书并记录告警日志。
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("Redis TLS客户端证书和私钥需要同时配置")
+	}
+
+	reloader := &certReloader{caFile: opts.CAFile, certFile: opts.CertFile, keyFile: opts.KeyFile, serverName: opts.ServerName}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CertFile != "" {
+		tlsConfig.GetClientCertificate = reloader.getClientCertificate
+	}
+	// 自定义 CA 需要支持轮换，因此关闭内置校验，改为在 VerifyConnection 中使用最新的证书池校验
+	if opts.CAFile != "" && !opts.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = reloader.verifyConnection
+	}
+	return tlsConfig, nil
+}
+
+// certReloader 按文件修改时间重新加载证书
+type certReloader struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string // 配置的服务端主机名，为空时使用握手时的主机名
+
+	mu      sync.RWMutex
+	pool    *x509.CertPool
+	cert    *tls.Certificate
+	modTime map[string]time.Time
+}
+
+// load 读取并校验全部证书文件
+func (r *certReloader) load() error {
+	modTime := make(map[string]time.Time)
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		var err error
+		if pool, err = loadCAPool(r.caFile); err != nil {
+			return err
+		}
+	}
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		var err error
+		if cert, err = loadClientCertificate(r.certFile, r.keyFile); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{r.caFile, r.certFile, r.keyFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("读取Redis TLS证书文件失败: %w", err)
+		}
+		modTime[name] = info.ModTime()
+	}
+
+	r.mu.Lock()
+	r.pool = pool
+	r.cert = cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// maybeReload 证书文件有变更时重新加载
+func (r *certReloader) maybeReload() {
+	r.mu.RLock()
+	changed := false
+	for name, last := range r.modTime {
+		info, err := os.Stat(name)
+		if err == nil && !info.ModTime().Equal(last) {
+			changed = true
+			break
+		}
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		cmflog.Warn("重新加载Redis TLS证书失败，继续使用旧证书", zap.Error(err))
+		// 记录本次修改时间，避免每次握手重复加载同一份无效文件
+		r.mu.Lock()
+		for name := range r.modTime {
+			if info, err := os.Stat(name); err == nil {
+				r.modTime[name] = info.ModTime()
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// verifyConnection 使用当前 CA 证书池校验服务端证书链和主机名
+// 主机名为 IP 地址时握手不发送 SNI，state.ServerName 为空，必须使用配置或拨号地址中的主机名校验 IP SAN
+func (r *certReloader) verifyConnection(state tls.ConnectionState) error {
+	r.maybeReload()
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("Redis服务端未提供证书")
+	}
+	serverName := r.serverName
+	if serverName == "" {
+		serverName = state.ServerName
+	}
+	if serverName == "" {
+		return errors.New("Redis TLS无法确定校验服务端证书的主机名，请配置tls_server_name")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// dialTLS 返回建立 TLS 连接的拨号函数，每次连接克隆 TLS 配置
+// 未配置 ServerName 时使用拨号地址中的主机名，并在 VerifyConnection 中补全为该主机名，
+// 使连接 IP 地址时也按 IP SAN 校验服务端证书
+func dialTLS(tlsConfig *tls.Config, timeout time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		cfg := tlsConfig.Clone()
+		if cfg.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			cfg.ServerName = host
+		}
+		if verify := cfg.VerifyConnection; verify != nil {
+			serverName := cfg.ServerName
+			cfg.VerifyConnection = func(state tls.ConnectionState) error {
+				if state.ServerName == "" {
+					state.ServerName = serverName
+				}
+				return verify(state)
+			}
+		}
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: timeout, KeepAlive: 5 * time.Minute},
+			Config:    cfg,
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// loadCAPool 读取 CA 证书文件，文件中至少需要包含一个有效证书
+func loadCAPool(name string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("读取Redis TLS CA证书失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("Redis TLS CA证书无效: %s", name)
+	}
+	return pool, nil
+}
+
+// loadClientCertificate 读取客户端证书与私钥，并校验证书有效期
+func loadClientCertificate(certFile string, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载Redis TLS客户端证书失败: %w", err)
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("解析Redis TLS客户端证书失败: %w", err)
+		}
+	}
+	now := time.Now()
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("Redis TLS客户端证书不在有效期内: %s ~ %s",
+			leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	}
+	return &cert, nil
+}
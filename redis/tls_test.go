@@ -0,0 +1,312 @@
+package redis_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cmfredis "github.com/wuwuseo/cmf/redis"
+)
+
+// testCA 测试用 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue 签发证书，name 为 IP 地址时写入 IP SAN，否则写入 DNS SAN，返回 PEM 编码的证书和私钥
+func (ca *testCA) issue(t *testing.T, name string, notAfter time.Time, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func writeFile(t *testing.T, dir string, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// handshake 使用给定客户端配置与服务端完成一次 TLS 握手
+func handshake(t *testing.T, clientConfig *tls.Config, serverCert tls.Certificate) error {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tls.Server(conn, &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequestClientCert,
+		}).Handshake()
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+
+	cfg := clientConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = "redis.test"
+	}
+	return tls.Client(clientConn, cfg).Handshake()
+}
+
+func TestNewTLSConfig_Validation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem())
+	certPEM, keyPEM := ca.issue(t, "client", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client.key", keyPEM)
+	expiredPEM, expiredKey := ca.issue(t, "client", time.Now().Add(-time.Hour), x509.ExtKeyUsageClientAuth)
+	expiredFile := writeFile(t, dir, "expired.pem", expiredPEM)
+	expiredKeyFile := writeFile(t, dir, "expired.key", expiredKey)
+	invalidFile := writeFile(t, dir, "invalid.pem", []byte("not a certificate"))
+
+	if _, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}); err != nil {
+		t.Fatalf("有效证书应创建成功: %v", err)
+	}
+
+	cases := map[string]cmfredis.TLSOptions{
+		"CA文件不存在":  {CAFile: filepath.Join(dir, "missing.pem")},
+		"CA证书无效":   {CAFile: invalidFile},
+		"缺少私钥":     {CertFile: certFile},
+		"证书与私钥不匹配": {CertFile: certFile, KeyFile: expiredKeyFile},
+		"证书已过期":    {CertFile: expiredFile, KeyFile: expiredKeyFile},
+	}
+	for name, opts := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := cmfredis.NewTLSConfig(opts); err == nil {
+				t.Error("应返回错误")
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig_VerifiesWithCustomCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverPEM, serverKey := ca.issue(t, "redis.test", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair(serverPEM, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: writeFile(t, dir, "ca.pem", ca.pem())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, cfg, serverCert); err != nil {
+		t.Errorf("CA 签发的服务端证书应校验通过: %v", err)
+	}
+
+	wrongName := cfg.Clone()
+	wrongName.ServerName = "other.test"
+	if err := handshake(t, wrongName, serverCert); err == nil {
+		t.Error("主机名不匹配时应校验失败")
+	}
+
+	other := newTestCA(t)
+	otherCfg, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: writeFile(t, dir, "other.pem", other.pem())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, otherCfg, serverCert); err == nil {
+		t.Error("非受信 CA 签发的证书应校验失败")
+	}
+
+	insecure, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: filepath.Join(dir, "other.pem"), InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, insecure, serverCert); err != nil {
+		t.Errorf("跳过校验时应握手成功: %v", err)
+	}
+}
+
+func TestNewTLSConfig_ReloadsRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "client-a", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client.key", keyPEM)
+
+	cfg, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := cfg.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 写入新证书并推后修改时间，模拟证书轮换
+	rotatedPEM, rotatedKey := ca.issue(t, "client-b", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "client.pem", rotatedPEM)
+	writeFile(t, dir, "client.key", rotatedKey)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+
+	second, err := cfg.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Certificate[0]) == string(second.Certificate[0]) {
+		t.Error("证书文件变更后应重新加载")
+	}
+
+	// 写入无效内容时继续使用当前证书
+	writeFile(t, dir, "client.pem", []byte("broken"))
+	later := future.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	third, err := cfg.GetClientCertificate(nil)
+	if err != nil || string(third.Certificate[0]) != string(second.Certificate[0]) {
+		t.Error("重新加载失败时应保留旧证书")
+	}
+}
+
+// tlsServer 启动只完成 TLS 握手的服务端，返回监听地址与握手结果
+func tlsServer(t *testing.T, serverCert tls.Certificate) (string, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	result := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		result <- tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{serverCert}}).Handshake()
+	}()
+	return listener.Addr().String(), result
+}
+
+func TestNewTLSConfig_VerifiesIPAddress(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem())
+	cfg, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务端证书由同一 CA 签发但不包含 127.0.0.1，连接 IP 地址时应校验失败
+	otherPEM, otherKey := ca.issue(t, "redis.test", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	otherCert, err := tls.X509KeyPair(otherPEM, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, result := tlsServer(t, otherCert)
+	client := cmfredis.NewClient(&cmfredis.Options{Addr: addr, TLSConfig: cfg, DialTimeout: time.Second})
+	defer client.Close()
+	if err := client.Ping(context.Background()).Err(); err == nil {
+		t.Error("证书不包含连接的 IP 地址时应校验失败")
+	}
+	if err := <-result; err == nil {
+		t.Error("客户端应拒绝不包含连接 IP 地址的证书")
+	}
+
+	// 配置的 ServerName 为 IP 地址时同样校验 IP SAN
+	ipCfg, err := cmfredis.NewTLSConfig(cmfredis.TLSOptions{CAFile: caFile, ServerName: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, ipCfg, otherCert); err == nil {
+		t.Error("ServerName 为 IP 地址时应校验证书的 IP SAN")
+	}
+
+	// 包含 127.0.0.1 的证书握手成功
+	ipPEM, ipKey := ca.issue(t, "127.0.0.1", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	ipCert, err := tls.X509KeyPair(ipPEM, ipKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(t, ipCfg, ipCert); err != nil {
+		t.Errorf("包含 IP SAN 的证书应校验通过: %v", err)
+	}
+	addr, result = tlsServer(t, ipCert)
+	ipClient := cmfredis.NewClient(&cmfredis.Options{Addr: addr, TLSConfig: cfg, DialTimeout: time.Second})
+	defer ipClient.Close()
+	ipClient.Ping(context.Background())
+	if err := <-result; err != nil {
+		t.Errorf("包含 IP SAN 的证书应握手成功: %v", err)
+	}
+}
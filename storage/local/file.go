@@ -0,0 +1,366 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wuwuseo/cmf/storage"
+)
+
+// metaSuffix 元数据文件的后缀
+const metaSuffix = ".meta"
+
+// notFound 返回包含键的 ErrNotFound
+func notFound(key string) error {
+	return fmt.Errorf("%w: %s", storage.ErrNotFound, key)
+}
+
+// regularFile 返回键对应的未过期普通文件路径与信息
+func (s *Storage) regularFile(key string) (string, os.FileInfo, error) {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, notFound(key)
+		}
+		return "", nil, err
+	}
+	if info.IsDir() || expired(filePath) {
+		return "", nil, notFound(key)
+	}
+	return filePath, info, nil
+}
+
+// contentType 优先根据扩展名判断 MIME 类型，未知时根据内容头部嗅探
+func contentType(key string, head []byte) string {
+	if typ := mime.TypeByExtension(filepath.Ext(key)); typ != "" {
+		return typ
+	}
+	if head == nil {
+		return ""
+	}
+	return http.DetectContentType(head)
+}
+
+// ExistsWithContext 判断给定键的文件是否存在（带上下文），已过期的文件视为不存在
+func (s *Storage) ExistsWithContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if _, _, err := s.regularFile(key); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Exists 判断给定键的文件是否存在（不使用上下文）
+func (s *Storage) Exists(key string) (bool, error) {
+	return s.ExistsWithContext(context.Background(), key)
+}
+
+// StatWithContext 读取文件信息（带上下文）
+// 优先使用写入时记录在元数据中的类型与校验值；元数据缺失或与数据文件不一致时读取完整文件计算 MD5
+func (s *Storage) StatWithContext(ctx context.Context, key string) (*storage.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath, info, err := s.regularFile(key)
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := readMeta(filePath)
+	if meta == nil {
+		meta = &Meta{}
+	}
+	if meta.Checksum != "" && meta.Size == info.Size() && metaFresh(filePath, info) {
+		return &storage.FileInfo{
+			Key:         key,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			ContentType: meta.ContentType,
+			Checksum:    meta.Checksum,
+			Metadata:    meta.Metadata,
+		}, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	hash.Write(head)
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+
+	return &storage.FileInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		ContentType: contentType(key, head),
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		Metadata:    meta.Metadata,
+	}, nil
+}
+
+// metaFresh 判断元数据是否在数据文件之后写入，数据文件被外部修改时元数据中的校验值不再可信
+func metaFresh(filePath string, info os.FileInfo) bool {
+	metaInfo, err := os.Stat(metaPath(filePath))
+	return err == nil && !metaInfo.ModTime().Before(info.ModTime())
+}
+
+// Stat 读取文件信息（不使用上下文）
+func (s *Storage) Stat(key string) (*storage.FileInfo, error) {
+	return s.StatWithContext(context.Background(), key)
+}
+
+// SizeWithContext 读取文件大小（带上下文）
+func (s *Storage) SizeWithContext(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	_, info, err := s.regularFile(key)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Size 读取文件大小（不使用上下文）
+func (s *Storage) Size(key string) (int64, error) {
+	return s.SizeWithContext(context.Background(), key)
+}
+
+// ListWithContext 列举前缀下的文件（带上下文），结果按键排序
+// 前缀按字符串匹配，与对象存储一致；recursive 为 false 时子目录以 IsDir 返回且不展开
+// 列举结果不包含校验值，ContentType 只根据扩展名判断
+func (s *Storage) ListWithContext(ctx context.Context, prefix string, recursive bool) ([]storage.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prefix = strings.TrimLeft(strings.ReplaceAll(prefix, `\`, "/"), "/")
+	if slices.Contains(strings.Split(prefix, "/"), "..") {
+		return nil, &KeyError{Key: prefix, Reason: "escapes base path"}
+	}
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+	root := filepath.Join(s.BasePath, filepath.FromSlash(dir))
+	if err := s.checkSymlinks(prefix, root); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var files []storage.FileInfo
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(s.BasePath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			dirKey := key + "/"
+			if !strings.HasPrefix(dirKey, prefix) {
+				// 前缀落在目录内部时继续进入，否则跳过整个目录
+				if strings.HasPrefix(prefix, dirKey) {
+					return nil
+				}
+				return filepath.SkipDir
+			}
+			if !recursive {
+				files = append(files, storage.FileInfo{Key: dirKey, IsDir: true, ModTime: modTime(d)})
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// 跳过元数据文件与符号链接，链接目标可能位于基础目录之外
+		if strings.HasSuffix(key, metaSuffix) || d.Type()&fs.ModeSymlink != 0 || !strings.HasPrefix(key, prefix) || expired(p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, storage.FileInfo{
+			Key:         key,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			ContentType: contentType(key, nil),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(files, func(a, b storage.FileInfo) int { return strings.Compare(a.Key, b.Key) })
+	return files, nil
+}
+
+// List 列举前缀下的文件（不使用上下文）
+func (s *Storage) List(prefix string, recursive bool) ([]storage.FileInfo, error) {
+	return s.ListWithContext(context.Background(), prefix, recursive)
+}
+
+// modTime 返回目录项的修改时间，读取失败时为零值
+func modTime(d fs.DirEntry) (t time.Time) {
+	if info, err := d.Info(); err == nil {
+		t = info.ModTime()
+	}
+	return t
+}
+
+// GetReaderWithContext 以流的方式读取文件（带上下文），调用方负责关闭
+func (s *Storage) GetReaderWithContext(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath, _, err := s.regularFile(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, notFound(key)
+	}
+	return file, err
+}
+
+// GetReader 以流的方式读取文件（不使用上下文）
+func (s *Storage) GetReader(key string) (io.ReadCloser, error) {
+	return s.GetReaderWithContext(context.Background(), key)
+}
+
+// CopyWithContext 复制文件（带上下文），过期时间随文件一起复制
+func (s *Storage) CopyWithContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	srcPath, _, err := s.regularFile(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := s.resolve(dst)
+	if err != nil {
+		return err
+	}
+	if srcPath == dstPath {
+		return nil
+	}
+	if err := s.copyFile(srcPath, dstPath); err != nil {
+		return err
+	}
+	return copyMeta(srcPath, dstPath)
+}
+
+// Copy 复制文件（不使用上下文）
+func (s *Storage) Copy(src, dst string) error {
+	return s.CopyWithContext(context.Background(), src, dst)
+}
+
+// MoveWithContext 移动文件（带上下文），跨设备无法重命名时回退为复制后删除
+func (s *Storage) MoveWithContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	srcPath, _, err := s.regularFile(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := s.resolve(dst)
+	if err != nil {
+		return err
+	}
+	if srcPath == dstPath {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		if err := s.CopyWithContext(ctx, src, dst); err != nil {
+			return err
+		}
+		return s.DeleteWithContext(ctx, src)
+	}
+	if err := copyMeta(srcPath, dstPath); err != nil {
+		return err
+	}
+	if err := os.Remove(metaPath(srcPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Move 移动文件（不使用上下文）
+func (s *Storage) Move(src, dst string) error {
+	return s.MoveWithContext(context.Background(), src, dst)
+}
+
+func (s *Storage) copyFile(srcPath, dstPath string) error {
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dstPath, in)
+}
+
+// copyMeta 将源文件的元数据复制到目标，源文件没有元数据时删除目标的旧元数据
+func copyMeta(srcPath, dstPath string) error {
+	metaData, err := os.ReadFile(metaPath(srcPath))
+	if os.IsNotExist(err) {
+		if err := os.Remove(metaPath(dstPath)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return writeFile(metaPath(dstPath), bytes.NewReader(metaData))
+}
@@ -0,0 +1,103 @@
+package local
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staleTempAge 临时文件超过该时间仍未重命名时视为写入中断的遗留文件
+const staleTempAge = time.Hour
+
+// SweepResult 一次清理的统计结果
+type SweepResult struct {
+	Expired int   // 删除的过期文件数
+	Orphans int   // 删除的没有数据文件的元数据文件数
+	Temps   int   // 删除的写入中断遗留的临时文件数
+	Bytes   int64 // 释放的数据文件字节数
+}
+
+// SweepWithContext 遍历基础目录，删除过期的数据文件及其元数据、孤立的元数据文件与遗留的临时文件
+// 上下文取消时停止遍历并返回已完成部分的统计
+func (s *Storage) SweepWithContext(ctx context.Context) (SweepResult, error) {
+	var result SweepResult
+	err := filepath.WalkDir(s.BasePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 遍历期间被并发删除的目录直接跳过
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		switch {
+		case strings.HasSuffix(p, tempSuffix):
+			if info, err := d.Info(); err == nil && time.Since(info.ModTime()) > staleTempAge {
+				if os.Remove(p) == nil {
+					result.Temps++
+				}
+			}
+		case strings.HasSuffix(p, metaSuffix):
+			if _, err := os.Lstat(strings.TrimSuffix(p, metaSuffix)); os.IsNotExist(err) {
+				if os.Remove(p) == nil {
+					result.Orphans++
+				}
+			}
+		default:
+			if size, ok := removeExpired(p); ok {
+				result.Expired++
+				result.Bytes += size
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Sweep 执行一次清理（不使用上下文）
+func (s *Storage) Sweep() (SweepResult, error) {
+	return s.SweepWithContext(context.Background())
+}
+
+// removeExpired 删除已过期的数据文件及其元数据，返回释放的字节数
+func removeExpired(filePath string) (int64, bool) {
+	info, ok := expiredFile(filePath)
+	if !ok {
+		return 0, false
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return 0, false
+	}
+	os.Remove(metaPath(filePath))
+	return info.Size(), true
+}
+
+// gc 按间隔执行清理，直到存储关闭；关闭时正在进行的清理随上下文取消
+func (s *Storage) gc(ctx context.Context, interval time.Duration, onSweep func(SweepResult, error)) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.SweepWithContext(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if onSweep != nil {
+				onSweep(result, err)
+			}
+		}
+	}
+}
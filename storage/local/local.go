@@ -1,11 +1,12 @@
 package local
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sync"
 	"time"
 )
 
@@ -13,11 +14,19 @@ import (
 // 实现了 Storage 接口，用于在本地文件系统中直接存储原始文件数据
 type Storage struct {
 	BasePath string // 存储文件的基础目录
+
+	cancel context.CancelFunc // 停止过期清理
+	wg     sync.WaitGroup
 }
 
 // Config 本地存储配置参数
 type Config struct {
 	BasePath string // 存储文件的基础目录
+
+	// GCInterval 后台清理过期文件的间隔，0 表示不启动后台清理，过期文件仅在读取时删除
+	GCInterval time.Duration
+	// OnSweep 每次后台清理完成后调用，可用于记录删除数量
+	OnSweep func(result SweepResult, err error)
 }
 
 // configOrDefault 获取配置参数，如果未提供则使用默认值
@@ -40,19 +49,36 @@ func New(cfg ...Config) *Storage {
 		// 记录错误但继续执行
 	}
 
-	return &Storage{
+	s := &Storage{
 		BasePath: config.BasePath,
 	}
+	if config.GCInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+		s.wg.Add(1)
+		go s.gc(ctx, config.GCInterval, config.OnSweep)
+	}
+	return s
 }
 
-// getFilePath 将键转换为文件路径
-func (s *Storage) getFilePath(key string) string {
-	return filepath.Join(s.BasePath, key)
+// expired 根据元数据文件判断数据文件是否已过期，元数据无法读取时视为未过期
+// 数据文件的修改时间晚于过期时间说明它在过期后被重新写入，而新元数据尚未写入（如两次重命名之间崩溃），视为未过期
+func expired(filePath string) bool {
+	_, ok := expiredFile(filePath)
+	return ok
 }
 
-// getMetaFilePath 将键转换为元数据文件路径
-func (s *Storage) getMetaFilePath(key string) string {
-	return filepath.Join(s.BasePath, key+".meta")
+// expiredFile 判断数据文件是否已过期，过期时返回数据文件信息
+func expiredFile(filePath string) (os.FileInfo, bool) {
+	meta, err := readMeta(filePath)
+	if err != nil || meta == nil || !meta.Expired() {
+		return nil, false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || info.ModTime().Unix() > meta.ExpiresAt {
+		return nil, false
+	}
+	return info, true
 }
 
 // GetWithContext 获取给定键的原始文件数据（带上下文）
@@ -65,29 +91,19 @@ func (s *Storage) GetWithContext(ctx context.Context, key string) ([]byte, error
 	default:
 	}
 
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查元数据文件，确认是否过期
-	metaPath := s.getMetaFilePath(key)
-	metaData, err := os.ReadFile(metaPath)
-	if err == nil {
-		// 解析过期时间
-		expTimeStr := string(metaData)
-		if expTimeStr != "" {
-			expTimeUnix, parseErr := strconv.ParseInt(expTimeStr, 10, 64)
-			if parseErr == nil && expTimeUnix > 0 {
-				// 检查是否过期
-				if time.Now().Unix() > expTimeUnix {
-					// 已过期，删除数据文件和元数据文件
-					go func() {
-						s.DeleteWithContext(ctx, key)
-					}()
-					return nil, nil
-				}
-			}
-		}
+	if expired(filePath) {
+		// 已过期，顺带删除数据文件和元数据文件，删除失败时留给后台清理
+		removeExpired(filePath)
+		return nil, nil
 	}
 
 	// 读取原始数据文件
-	filePath := s.getFilePath(key)
 	fileData, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -120,24 +136,8 @@ func (s *Storage) SetWithContext(ctx context.Context, key string, val []byte, ex
 		return nil // 忽略空键或空值
 	}
 
-	// 写入原始数据文件
-	filePath := s.getFilePath(key)
-	// 确保父目录存在
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		return err
-	}
-	if err := os.WriteFile(filePath, val, 0644); err != nil {
-		return err
-	}
-
-	// 处理过期时间
-	metaPath := s.getMetaFilePath(key)
-	if exp > 0 {
-		// 设置过期时间（Unix时间戳）
-		expTime := time.Now().Add(exp).Unix()
-		return os.WriteFile(metaPath, []byte(strconv.FormatInt(expTime, 10)), 0644)
-	}
-	return nil
+	// 原子写入数据文件与元数据，未设置过期时间时覆盖旧的过期时间
+	return s.write(ctx, key, bytes.NewReader(val), exp, nil)
 }
 
 // Set 存储给定键的原始文件数据（不使用上下文）
@@ -157,13 +157,16 @@ func (s *Storage) DeleteWithContext(ctx context.Context, key string) error {
 	default:
 	}
 
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+
 	// 删除数据文件
-	filePath := s.getFilePath(key)
 	err1 := os.Remove(filePath)
 
 	// 删除元数据文件
-	metaPath := s.getMetaFilePath(key)
-	err2 := os.Remove(metaPath)
+	err2 := os.Remove(metaPath(filePath))
 
 	// 只有当两个删除操作都失败且都不是因为文件不存在时才返回错误
 	if err1 != nil && !os.IsNotExist(err1) {
@@ -197,13 +200,10 @@ func (s *Storage) ResetWithContext(ctx context.Context) error {
 		return err
 	}
 
-	// 删除所有文件（包括数据文件和元数据文件）
+	// 删除所有文件与子目录（包括数据文件、元数据文件与写入中断遗留的临时文件）
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			filePath := filepath.Join(s.BasePath, entry.Name())
-			if err := os.Remove(filePath); err != nil {
-				return err
-			}
+		if err := os.RemoveAll(filepath.Join(s.BasePath, entry.Name())); err != nil {
+			return err
 		}
 	}
 
@@ -215,15 +215,17 @@ func (s *Storage) Reset() error {
 	return s.ResetWithContext(context.Background())
 }
 
-// Close 关闭存储，停止任何运行的垃圾收集器和打开的连接
-// 对于本地文件存储，此方法实际上不需要执行任何操作
+// Close 关闭存储，停止后台清理并等待正在进行的清理退出，可以重复调用
 func (s *Storage) Close() error {
-	// 本地文件存储不需要特别的关闭操作
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
 	return nil
 }
 
 // SetReaderWithContext 流式存储给定键的文件数据（带上下文）
-// 直接从 io.Reader 读取数据并写入文件，避免全量数据加载到内存
+// 直接从 io.Reader 读取数据并写入临时文件，完成后重命名，避免全量数据加载到内存
 func (s *Storage) SetReaderWithContext(ctx context.Context, key string, reader io.Reader, exp time.Duration) error {
 	select {
 	case <-ctx.Done():
@@ -235,27 +237,7 @@ func (s *Storage) SetReaderWithContext(ctx context.Context, key string, reader i
 		return nil
 	}
 
-	filePath := s.getFilePath(key)
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		return err
-	}
-
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err := io.Copy(file, reader); err != nil {
-		return err
-	}
-
-	metaPath := s.getMetaFilePath(key)
-	if exp > 0 {
-		expTime := time.Now().Add(exp).Unix()
-		return os.WriteFile(metaPath, []byte(strconv.FormatInt(expTime, 10)), 0644)
-	}
-	return nil
+	return s.write(ctx, key, reader, exp, nil)
 }
 
 // SetReader 流式存储给定键的文件数据（不使用上下文）
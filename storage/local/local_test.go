@@ -3,11 +3,19 @@ package local_test
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/wuwuseo/cmf/storage"
 	"github.com/wuwuseo/cmf/storage/local"
 )
 
@@ -533,3 +541,526 @@ func TestSetReaderWithExpiration(t *testing.T) {
 		t.Fatalf("过期后应返回 nil, 得到 %v", got)
 	}
 }
+
+// =============================================================================
+// Exists / Stat / List / Copy / Move / GetReader 测试
+// =============================================================================
+
+func TestExistsStat(t *testing.T) {
+	store := newTempStorage(t)
+	data := []byte("hello stat")
+	if err := store.Set("docs/readme.txt", data, 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	ok, err := store.Exists("docs/readme.txt")
+	if err != nil || !ok {
+		t.Fatalf("文件应存在: %v, %v", ok, err)
+	}
+	if ok, _ := store.Exists("docs"); ok {
+		t.Fatal("目录不应视为文件")
+	}
+
+	info, err := store.Stat("docs/readme.txt")
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	sum := md5.Sum(data)
+	if info.Size != int64(len(data)) || info.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("文件信息不正确: %+v", info)
+	}
+	if !strings.HasPrefix(info.ContentType, "text/plain") || info.ModTime.IsZero() {
+		t.Fatalf("ContentType 或 ModTime 不正确: %+v", info)
+	}
+
+	// 无扩展名时根据内容嗅探
+	if err := store.Set("image", []byte("\x89PNG\r\n\x1a\n0000"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	if info, _ := store.Stat("image"); info == nil || info.ContentType != "image/png" {
+		t.Fatalf("期望嗅探为 image/png: %+v", info)
+	}
+
+	if _, err := store.Stat("missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("不存在的文件应返回 ErrNotFound: %v", err)
+	}
+	if size, err := store.Size("docs/readme.txt"); err != nil || size != int64(len(data)) {
+		t.Fatalf("Size 不正确: %d, %v", size, err)
+	}
+}
+
+func TestStat_Expired(t *testing.T) {
+	store := newTempStorage(t)
+	if err := store.Set("expiring", []byte("data"), time.Second); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	// 直接写入过去的过期时间，数据文件的修改时间早于过期时间
+	if err := os.WriteFile(filepath.Join(store.BasePath, "expiring.meta"), []byte("2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(store.BasePath, "expiring"), time.Unix(1, 0), time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Exists("expiring"); ok {
+		t.Fatal("过期的文件应视为不存在")
+	}
+	if _, err := store.GetReader("expiring"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("过期的文件应返回 ErrNotFound: %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	store := newTempStorage(t)
+	for _, key := range []string{"a.txt", "img/1.png", "img/2.png", "img/thumb/1.png", "imgx.txt"} {
+		if err := store.Set(key, []byte(key), 0); err != nil {
+			t.Fatalf("Set 失败: %v", err)
+		}
+	}
+	// 带过期时间的文件不应列出元数据文件
+	if err := store.Set("img/tmp.png", []byte("tmp"), time.Hour); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	keys := func(files []storage.FileInfo) string {
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Key)
+		}
+		return strings.Join(out, ",")
+	}
+
+	files, err := store.List("img/", false)
+	if err != nil {
+		t.Fatalf("List 失败: %v", err)
+	}
+	if got := keys(files); got != "img/1.png,img/2.png,img/thumb/,img/tmp.png" {
+		t.Fatalf("非递归列举结果不正确: %s", got)
+	}
+	if !files[2].IsDir {
+		t.Fatal("子目录应标记为 IsDir")
+	}
+
+	files, _ = store.List("img", true)
+	if got := keys(files); got != "img/1.png,img/2.png,img/thumb/1.png,img/tmp.png,imgx.txt" {
+		t.Fatalf("递归列举结果不正确: %s", got)
+	}
+
+	files, _ = store.List("", false)
+	if got := keys(files); got != "a.txt,img/,imgx.txt" {
+		t.Fatalf("根目录列举结果不正确: %s", got)
+	}
+
+	if files, err := store.List("missing/", true); err != nil || len(files) != 0 {
+		t.Fatalf("不存在的前缀应返回空结果: %v, %v", files, err)
+	}
+}
+
+func TestCopyMove(t *testing.T) {
+	store := newTempStorage(t)
+	data := []byte("copy me")
+	if err := store.Set("src.txt", data, time.Hour); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	if err := store.Copy("src.txt", "backup/copy.txt"); err != nil {
+		t.Fatalf("Copy 失败: %v", err)
+	}
+	if got, _ := store.Get("backup/copy.txt"); !bytes.Equal(got, data) {
+		t.Fatalf("复制的内容不正确: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(store.BasePath, "backup", "copy.txt.meta")); err != nil {
+		t.Fatalf("过期时间应随文件复制: %v", err)
+	}
+
+	if err := store.Move("src.txt", "moved/dst.txt"); err != nil {
+		t.Fatalf("Move 失败: %v", err)
+	}
+	if ok, _ := store.Exists("src.txt"); ok {
+		t.Fatal("移动后源文件不应存在")
+	}
+	if _, err := os.Stat(filepath.Join(store.BasePath, "src.txt.meta")); !os.IsNotExist(err) {
+		t.Fatal("移动后源文件的元数据应被删除")
+	}
+	reader, err := store.GetReader("moved/dst.txt")
+	if err != nil {
+		t.Fatalf("GetReader 失败: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, data) {
+		t.Fatalf("移动后的内容不正确: %q", got)
+	}
+
+	if err := store.Copy("missing", "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("复制不存在的文件应返回 ErrNotFound: %v", err)
+	}
+	if err := store.Move("missing", "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("移动不存在的文件应返回 ErrNotFound: %v", err)
+	}
+}
+
+func TestCleanKey(t *testing.T) {
+	valid := map[string]string{
+		"a/b.txt":       "a/b.txt",
+		"/a//b.txt":     "a/b.txt",
+		`a\b.txt`:       "a/b.txt",
+		"a/./c/../b":    "a/b",
+		"meta":          "meta",
+		"x.meta.txt":    "x.meta.txt",
+		"dir/.metadata": "dir/.metadata",
+	}
+	for key, want := range valid {
+		got, err := local.CleanKey(key)
+		if err != nil || got != want {
+			t.Errorf("CleanKey(%q) = %q, %v，期望 %q", key, got, err, want)
+		}
+	}
+
+	for _, key := range []string{"", "/", "..", "../etc/passwd", "a/../../b", `..\..\x`, "a.meta", "a.meta/b", "a\x00b"} {
+		_, err := local.CleanKey(key)
+		var keyErr *local.KeyError
+		if !errors.As(err, &keyErr) || !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("CleanKey(%q) 应返回 *KeyError: %v", key, err)
+		}
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	parent := t.TempDir()
+	store := local.New(local.Config{BasePath: filepath.Join(parent, "root")})
+
+	for _, key := range []string{"../escape.txt", "a/../../escape.txt", `..\escape.txt`} {
+		if err := store.Set(key, []byte("x"), time.Hour); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Fatalf("Set(%q) 应返回 ErrInvalidKey: %v", key, err)
+		}
+		if err := store.SetReader(key, strings.NewReader("x"), 0); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Fatalf("SetReader(%q) 应返回 ErrInvalidKey: %v", key, err)
+		}
+		if _, err := store.Get(key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Fatalf("Get(%q) 应返回 ErrInvalidKey: %v", key, err)
+		}
+		if err := store.Delete(key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Fatalf("Delete(%q) 应返回 ErrInvalidKey: %v", key, err)
+		}
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+		t.Fatalf("基础目录之外不应写入文件: %v", entries)
+	}
+
+	// 开头的 / 视为相对基础目录
+	if err := store.Set("/abs.txt", []byte("x"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "root", "abs.txt")); err != nil {
+		t.Fatalf("文件应写入基础目录: %v", err)
+	}
+
+	if err := store.Set("a.meta", []byte("x"), 0); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("保留后缀应返回 ErrInvalidKey: %v", err)
+	}
+	if err := store.Copy("abs.txt", "../copy.txt"); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("Copy 到基础目录之外应返回 ErrInvalidKey: %v", err)
+	}
+	if err := store.Move("abs.txt", "../moved.txt"); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("Move 到基础目录之外应返回 ErrInvalidKey: %v", err)
+	}
+	if _, err := store.List("../", true); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("List 基础目录之外应返回 ErrInvalidKey: %v", err)
+	}
+}
+
+func TestSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := newTempStorage(t)
+	if err := os.Symlink(outside, filepath.Join(store.BasePath, "link")); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(store.BasePath, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("link/secret.txt"); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("通过目录链接读取应返回 ErrInvalidKey: %v", err)
+	}
+	if err := store.Set("link/new.txt", []byte("x"), 0); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("通过目录链接写入应返回 ErrInvalidKey: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); !os.IsNotExist(err) {
+		t.Fatal("不应在链接目标中创建文件")
+	}
+	if _, err := store.GetReader("file.txt"); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Fatalf("读取文件链接应返回 ErrInvalidKey: %v", err)
+	}
+	files, err := store.List("", true)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("列举不应包含符号链接: %v, %v", files, err)
+	}
+
+	// 基础目录内的链接允许访问
+	if err := store.Set("real/a.txt", []byte("ok"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(store.BasePath, "real"), filepath.Join(store.BasePath, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get("alias/a.txt"); err != nil || string(got) != "ok" {
+		t.Fatalf("基础目录内的链接应允许访问: %q, %v", got, err)
+	}
+}
+
+// failingReader 读取部分数据后返回错误，模拟写入中断
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestSetReader_Atomic(t *testing.T) {
+	store := newTempStorage(t)
+	if err := store.Set("doc.txt", []byte("old content"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+
+	if err := store.SetReader("doc.txt", &failingReader{data: []byte("new")}, 0); err == nil {
+		t.Fatal("读取失败时应返回错误")
+	}
+	if got, _ := store.Get("doc.txt"); string(got) != "old content" {
+		t.Fatalf("写入失败不应破坏原文件: %q", got)
+	}
+	entries, _ := os.ReadDir(store.BasePath)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "doc.txt,doc.txt.meta" {
+		t.Fatalf("不应遗留临时文件: %v", names)
+	}
+}
+
+func TestMetaSidecar(t *testing.T) {
+	store := newTempStorage(t)
+	data := []byte("<html><body>hi</body></html>")
+	if err := store.SetReaderWithMeta("page", bytes.NewReader(data), time.Hour, map[string]string{"owner": "alice"}); err != nil {
+		t.Fatalf("SetReaderWithMeta 失败: %v", err)
+	}
+	sum := md5.Sum(data)
+
+	meta, err := store.ReadMeta("page")
+	if err != nil {
+		t.Fatalf("ReadMeta 失败: %v", err)
+	}
+	if meta.Size != int64(len(data)) || meta.Checksum != hex.EncodeToString(sum[:]) ||
+		meta.ContentType != "text/html; charset=utf-8" || meta.ExpiresAt == 0 || meta.Metadata["owner"] != "alice" {
+		t.Fatalf("元数据不正确: %+v", meta)
+	}
+	raw, _ := os.ReadFile(filepath.Join(store.BasePath, "page.meta"))
+	if !bytes.HasPrefix(raw, []byte("{")) {
+		t.Fatalf("元数据应为 JSON: %s", raw)
+	}
+
+	info, err := store.Stat("page")
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	if info.Checksum != meta.Checksum || info.Metadata["owner"] != "alice" {
+		t.Fatalf("Stat 应返回元数据中的信息: %+v", info)
+	}
+
+	// 不带过期时间重新写入时清除旧的过期时间与自定义元数据
+	if err := store.Set("page", []byte("v2"), 0); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	meta, _ = store.ReadMeta("page")
+	if meta.ExpiresAt != 0 || meta.Metadata != nil || meta.Size != 2 {
+		t.Fatalf("重新写入后元数据应更新: %+v", meta)
+	}
+
+	// 兼容旧格式：仅包含过期时间戳
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	if err := os.WriteFile(filepath.Join(store.BasePath, "page.meta"), []byte(future), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta, err = store.ReadMeta("page")
+	if err != nil || meta.ExpiresAt == 0 {
+		t.Fatalf("旧格式的元数据应能读取: %+v, %v", meta, err)
+	}
+	info, err = store.Stat("page")
+	if sum := md5.Sum([]byte("v2")); err != nil || info.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("旧格式的元数据应重新计算校验值: %+v, %v", info, err)
+	}
+}
+
+func TestReset_NestedDirectories(t *testing.T) {
+	store := newTempStorage(t)
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt"} {
+		if err := store.Set(key, []byte(key), time.Hour); err != nil {
+			t.Fatalf("Set 失败: %v", err)
+		}
+	}
+	if err := store.Reset(); err != nil {
+		t.Fatalf("Reset 失败: %v", err)
+	}
+	if entries, _ := os.ReadDir(store.BasePath); len(entries) != 0 {
+		t.Fatalf("Reset 后基础目录应为空: %v", entries)
+	}
+	if _, err := os.Stat(store.BasePath); err != nil {
+		t.Fatalf("Reset 不应删除基础目录: %v", err)
+	}
+}
+
+// expire 将键的过期时间改为过去，数据文件的修改时间同步提前
+func expire(t *testing.T, store *local.Storage, key string) {
+	t.Helper()
+	past := time.Now().Add(-2 * time.Hour)
+	meta := fmt.Sprintf(`{"expires_at":%d,"size":1}`, past.Add(time.Minute).Unix())
+	filePath := filepath.Join(store.BasePath, filepath.FromSlash(key))
+	if err := os.WriteFile(filePath+".meta", []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filePath, past, past); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	store := newTempStorage(t)
+	for _, key := range []string{"old.txt", "dir/old.txt", "keep.txt", "forever.txt"} {
+		if err := store.Set(key, []byte("x"), time.Hour); err != nil {
+			t.Fatalf("Set 失败: %v", err)
+		}
+	}
+	store.Set("forever.txt", []byte("x"), 0)
+	expire(t, store, "old.txt")
+	expire(t, store, "dir/old.txt")
+
+	// 孤立的元数据与遗留的临时文件
+	os.WriteFile(filepath.Join(store.BasePath, "gone.txt.meta"), []byte("1"), 0644)
+	temp := filepath.Join(store.BasePath, ".keep.txt.123.tmp.meta")
+	os.WriteFile(temp, []byte("partial"), 0644)
+	past := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(temp, past, past)
+	os.WriteFile(filepath.Join(store.BasePath, ".keep.txt.456.tmp.meta"), []byte("writing"), 0644)
+
+	result, err := store.Sweep()
+	if err != nil {
+		t.Fatalf("Sweep 失败: %v", err)
+	}
+	if result.Expired != 2 || result.Bytes != 2 || result.Orphans != 1 || result.Temps != 1 {
+		t.Fatalf("清理统计不正确: %+v", result)
+	}
+	for _, name := range []string{"old.txt", "old.txt.meta", "dir/old.txt", "dir/old.txt.meta", "gone.txt.meta", ".keep.txt.123.tmp.meta"} {
+		if _, err := os.Stat(filepath.Join(store.BasePath, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s 应已删除", name)
+		}
+	}
+	for _, key := range []string{"keep.txt", "forever.txt"} {
+		if ok, _ := store.Exists(key); !ok {
+			t.Fatalf("%s 不应被删除", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(store.BasePath, ".keep.txt.456.tmp.meta")); err != nil {
+		t.Fatal("正在写入的临时文件不应被删除")
+	}
+}
+
+func TestSweep_RewrittenAfterExpiry(t *testing.T) {
+	store := newTempStorage(t)
+	store.Set("a.txt", []byte("x"), time.Hour)
+	expire(t, store, "a.txt")
+	// 数据文件已重新写入但元数据尚未更新
+	now := time.Now()
+	os.Chtimes(filepath.Join(store.BasePath, "a.txt"), now, now)
+
+	result, err := store.Sweep()
+	if err != nil || result.Expired != 0 {
+		t.Fatalf("重新写入的文件不应被删除: %+v, %v", result, err)
+	}
+}
+
+func TestGet_RemovesExpired(t *testing.T) {
+	store := newTempStorage(t)
+	store.Set("a.txt", []byte("x"), time.Hour)
+	expire(t, store, "a.txt")
+
+	if got, err := store.Get("a.txt"); err != nil || got != nil {
+		t.Fatalf("过期的键应返回 nil: %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(store.BasePath, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("读取过期的键后应删除数据文件")
+	}
+}
+
+func TestGCLoop(t *testing.T) {
+	sweeps := make(chan local.SweepResult, 10)
+	store := local.New(local.Config{
+		BasePath:   t.TempDir(),
+		GCInterval: 20 * time.Millisecond,
+		OnSweep: func(result local.SweepResult, err error) {
+			if err == nil {
+				sweeps <- result
+			}
+		},
+	})
+	store.Set("a.txt", []byte("x"), time.Hour)
+	expire(t, store, "a.txt")
+
+	deadline := time.After(2 * time.Second)
+	for removed := 0; removed == 0; {
+		select {
+		case result := <-sweeps:
+			removed = result.Expired
+		case <-deadline:
+			t.Fatal("后台清理未删除过期文件")
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+	// 关闭后不再清理
+	for len(sweeps) > 0 {
+		<-sweeps
+	}
+	time.Sleep(60 * time.Millisecond)
+	if len(sweeps) != 0 {
+		t.Fatal("Close 后不应继续清理")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("重复 Close 失败: %v", err)
+	}
+}
+
+func TestStaleExpiredSidecar(t *testing.T) {
+	store := newTempStorage(t)
+	if err := store.Set("a.txt", []byte("old"), time.Hour); err != nil {
+		t.Fatalf("Set 失败: %v", err)
+	}
+	expire(t, store, "a.txt")
+	// 模拟重新写入时在数据文件重命名之后、元数据写入之前崩溃：新数据文件旁是旧的过期元数据
+	if err := os.WriteFile(filepath.Join(store.BasePath, "a.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := store.Get("a.txt"); err != nil || string(got) != "new" {
+		t.Fatalf("新写入的数据不应被旧的过期元数据隐藏: %q, %v", got, err)
+	}
+	if ok, _ := store.Exists("a.txt"); !ok {
+		t.Fatal("新写入的数据应存在")
+	}
+	if result, err := store.Sweep(); err != nil || result.Expired != 0 {
+		t.Fatalf("清理不应删除新写入的数据: %+v, %v", result, err)
+	}
+	if info, err := store.Stat("a.txt"); err != nil || info.Size != 3 {
+		t.Fatalf("Stat 应重新计算: %+v, %v", info, err)
+	}
+}
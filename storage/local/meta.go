@@ -0,0 +1,208 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// tempSuffix 写入过程中临时文件的后缀，以 .meta 结尾保证不会与合法的键冲突，也不会被 List 列出
+const tempSuffix = ".tmp" + metaSuffix
+
+// Meta 与数据文件一同写入的 JSON 元数据
+// 旧版本的元数据文件只包含过期时间的 Unix 时间戳，读取时兼容
+type Meta struct {
+	ExpiresAt   int64             `json:"expires_at,omitempty"`   // 过期时间（Unix 秒），0 表示永不过期
+	ContentType string            `json:"content_type,omitempty"` // 写入时根据扩展名或内容嗅探的 MIME 类型
+	Size        int64             `json:"size"`                   // 数据文件大小
+	Checksum    string            `json:"checksum,omitempty"`     // 数据文件的 MD5 十六进制
+	Metadata    map[string]string `json:"metadata,omitempty"`     // 自定义元数据
+}
+
+// Expired 判断是否已过期
+func (m *Meta) Expired() bool {
+	return m.ExpiresAt > 0 && time.Now().Unix() > m.ExpiresAt
+}
+
+// readMeta 读取数据文件对应的元数据，元数据文件不存在时返回 nil, nil
+func readMeta(filePath string) (*Meta, error) {
+	data, err := os.ReadFile(metaPath(filePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	meta := &Meta{}
+	if len(data) == 0 {
+		return meta, nil
+	}
+	if data[0] != '{' {
+		// 旧格式：仅过期时间戳
+		meta.ExpiresAt, err = strconv.ParseInt(string(data), 10, 64)
+		return meta, err
+	}
+	return meta, json.Unmarshal(data, meta)
+}
+
+// writeMeta 原子写入元数据文件
+func writeMeta(filePath string, meta *Meta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFile(metaPath(filePath), bytes.NewReader(data))
+}
+
+// writeFile 先写入同目录下的临时文件并同步到磁盘，再重命名为目标文件
+// 进程崩溃或断电时目标文件要么是旧内容，要么是完整的新内容
+func writeFile(filePath string, reader io.Reader) (err error) {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, reader); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir 同步目录项，使重命名持久化；不支持对目录调用 fsync 的平台忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// sniffWriter 保留写入内容的前 512 字节用于类型嗅探
+type sniffWriter struct {
+	head []byte
+}
+
+func (w *sniffWriter) Write(p []byte) (int, error) {
+	if n := 512 - len(w.head); n > 0 {
+		w.head = append(w.head, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// write 原子写入数据文件，随后写入包含大小、校验值、类型与过期时间的元数据
+// 数据文件先于元数据重命名，两者之间崩溃时旧元数据的大小或修改时间与数据文件不一致：
+// Stat 会重新计算校验值，expired 忽略早于数据文件修改时间的过期时间，新数据不会被旧元数据隐藏
+func (s *Storage) write(ctx context.Context, key string, reader io.Reader, exp time.Duration, metadata map[string]string) error {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	hash := md5.New()
+	sniff := &sniffWriter{}
+	counter := &countWriter{}
+	if err := writeFile(filePath, io.TeeReader(contextReader{ctx, reader}, io.MultiWriter(hash, sniff, counter))); err != nil {
+		return err
+	}
+
+	meta := &Meta{
+		ContentType: contentType(key, sniff.head),
+		Size:        counter.n,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		Metadata:    metadata,
+	}
+	if exp > 0 {
+		meta.ExpiresAt = time.Now().Add(exp).Unix()
+	}
+	return writeMeta(filePath, meta)
+}
+
+// countWriter 统计写入的字节数
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// contextReader 在上下文取消后中断读取
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// SetReaderWithMetaContext 流式存储文件并附带自定义元数据（带上下文）
+func (s *Storage) SetReaderWithMetaContext(ctx context.Context, key string, reader io.Reader, exp time.Duration, metadata map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if key == "" || reader == nil {
+		return nil
+	}
+	return s.write(ctx, key, reader, exp, metadata)
+}
+
+// SetReaderWithMeta 流式存储文件并附带自定义元数据（不使用上下文）
+func (s *Storage) SetReaderWithMeta(key string, reader io.Reader, exp time.Duration, metadata map[string]string) error {
+	return s.SetReaderWithMetaContext(context.Background(), key, reader, exp, metadata)
+}
+
+// ReadMetaWithContext 读取文件的元数据（带上下文），文件不存在或已过期时返回 ErrNotFound
+// 旧版本写入的文件没有大小、校验值等字段
+func (s *Storage) ReadMetaWithContext(ctx context.Context, key string) (*Meta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath, _, err := s.regularFile(key)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readMeta(filePath)
+	if meta == nil && err == nil {
+		meta = &Meta{}
+	}
+	return meta, err
+}
+
+// ReadMeta 读取文件的元数据（不使用上下文）
+func (s *Storage) ReadMeta(key string) (*Meta, error) {
+	return s.ReadMetaWithContext(context.Background(), key)
+}
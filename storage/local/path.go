@@ -0,0 +1,115 @@
+package local
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wuwuseo/cmf/storage"
+)
+
+// KeyError 键不合法的错误，可以用 errors.Is(err, storage.ErrInvalidKey) 判断
+type KeyError struct {
+	Key    string // 原始键
+	Reason string // 不合法的原因
+}
+
+func (e *KeyError) Error() string {
+	return "invalid storage key " + `"` + e.Key + `": ` + e.Reason
+}
+
+func (e *KeyError) Unwrap() error {
+	return storage.ErrInvalidKey
+}
+
+// CleanKey 规范化键：反斜杠视为分隔符，去掉开头的 /，折叠 . 与 ..
+// 越出根目录、包含 NUL 字符或任一段以保留的 .meta 结尾的键返回 *KeyError
+func CleanKey(key string) (string, error) {
+	if strings.ContainsRune(key, 0) {
+		return "", &KeyError{Key: key, Reason: "contains NUL character"}
+	}
+	cleaned := path.Clean(strings.TrimLeft(strings.ReplaceAll(key, `\`, "/"), "/"))
+	if cleaned == "." {
+		return "", &KeyError{Key: key, Reason: "empty key"}
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", &KeyError{Key: key, Reason: "escapes base path"}
+	}
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.HasSuffix(segment, metaSuffix) {
+			return "", &KeyError{Key: key, Reason: "reserved " + metaSuffix + " suffix"}
+		}
+	}
+	return cleaned, nil
+}
+
+// resolve 将键转换为基础目录下的文件路径，并确认路径经过符号链接解析后仍在基础目录内
+func (s *Storage) resolve(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(s.BasePath, filepath.FromSlash(cleaned))
+	if !withinRoot(s.BasePath, filePath) {
+		return "", &KeyError{Key: key, Reason: "escapes base path"}
+	}
+	if err := s.checkSymlinks(key, filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// metaPath 返回数据文件对应的元数据文件路径
+func metaPath(filePath string) string {
+	return filePath + metaSuffix
+}
+
+// checkSymlinks 解析路径中已存在部分的符号链接，链接指向基础目录之外时返回 *KeyError
+// 尚不存在的部分会在写入时创建为普通目录和文件，不需要检查
+func (s *Storage) checkSymlinks(key, filePath string) error {
+	root, err := filepath.EvalSymlinks(s.BasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	existing := filePath
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return nil
+		}
+		existing = parent
+	}
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		// 悬空的符号链接无法判断指向，按越界处理
+		return &KeyError{Key: key, Reason: "unresolvable symlink"}
+	}
+	if !withinRoot(root, real) {
+		return &KeyError{Key: key, Reason: "symlink escapes base path"}
+	}
+	return nil
+}
+
+// withinRoot 判断 target 是否位于 root 目录内（含 root 本身）
+func withinRoot(root, target string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absTarget)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
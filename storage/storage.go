@@ -0,0 +1,75 @@
+// Package storage 定义存储驱动共享的文件信息与可选能力接口
+//
+// 驱动除实现 gofiber storage.Storage 的键值接口外，可以按需实现这里的接口，
+// filesystem 包优先调用驱动的原生实现，未实现时回退到基于 Get/Set 的通用实现。
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNotFound 文件不存在或已过期
+	ErrNotFound = errors.New("file not found")
+	// ErrInvalidKey 键不合法，如越出存储根目录或使用了保留的后缀
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// FileInfo 文件信息
+type FileInfo struct {
+	Key         string            // 文件键，目录以 / 结尾
+	Size        int64             // 文件大小（字节）
+	ModTime     time.Time         // 最后修改时间，驱动无法提供时为零值
+	ContentType string            // MIME 类型
+	Checksum    string            // 内容校验值，本地存储为 MD5 十六进制，S3 为 ETag（分片上传的对象不是 MD5）
+	IsDir       bool              // 非递归列举时的子目录
+	Metadata    map[string]string // 自定义元数据，驱动不支持或列举结果中为空
+}
+
+// Existser 判断文件是否存在
+type Existser interface {
+	Exists(key string) (bool, error)
+}
+
+// Statter 读取文件信息，文件不存在时返回 ErrNotFound
+type Statter interface {
+	Stat(key string) (*FileInfo, error)
+}
+
+// Sizer 读取文件大小，文件不存在时返回 ErrNotFound
+type Sizer interface {
+	Size(key string) (int64, error)
+}
+
+// Lister 列举前缀下的文件，recursive 为 false 时只列举当前层级，子目录以 IsDir 返回
+type Lister interface {
+	List(prefix string, recursive bool) ([]FileInfo, error)
+}
+
+// Copier 复制文件，源文件不存在时返回 ErrNotFound
+type Copier interface {
+	Copy(src, dst string) error
+}
+
+// Mover 移动文件，源文件不存在时返回 ErrNotFound
+type Mover interface {
+	Move(src, dst string) error
+}
+
+// ReaderGetter 以流的方式读取文件，文件不存在时返回 ErrNotFound，调用方负责关闭
+type ReaderGetter interface {
+	GetReader(key string) (io.ReadCloser, error)
+}
+
+// URLOptions 临时链接选项
+type URLOptions struct {
+	Filename    string // 下载时保存的文件名，设置后以附件形式下载
+	ContentType string // 覆盖响应的 Content-Type
+}
+
+// TemporaryURLer 生成带有效期的文件访问链接，如 S3 预签名链接
+type TemporaryURLer interface {
+	TemporaryURL(key string, ttl time.Duration, opts URLOptions) (string, error)
+}